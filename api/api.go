@@ -0,0 +1,34 @@
+// Package api embeds the OpenAPI document of the server and the page that
+// renders it.
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/sallescosta/conduit-api/pkg/openapi"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed docs.html
+var docs []byte
+
+// Spec parses the embedded document.
+func Spec() (*openapi.Document, error) {
+	return openapi.Load(spec)
+}
+
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+// ServeDocs serves a self-contained page, so the documentation works
+// without reaching a CDN.
+func ServeDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'unsafe-inline'; script-src 'unsafe-inline'")
+	w.Write(docs)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sallescosta/conduit-api/internal/dto"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	notificationEntity "github.com/sallescosta/conduit-api/internal/entity/notification"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSchemasMatchEntities encodes the values handlers return and checks
+// them against the component schemas that document them.
+func TestSchemasMatchEntities(t *testing.T) {
+	spec, err := Spec()
+	require.NoError(t, err)
+
+	article, err := articleEntity.NewArticle("author", "Title", "", "# Heading\n\nBody", []string{"go"})
+	require.NoError(t, err)
+	user, err := userEntity.NewUser("jake", "jake@jake.jake", "jakejake")
+	require.NoError(t, err)
+	comment := entityComment.NewComment("Nice", "author", "article")
+	published := *article
+	published.CanonicalURL = "https://blog.example/title"
+	published.CoverImage = "https://cdn.example/cover.png"
+	published.License = "CC-BY-4.0"
+
+	cases := map[string]interface{}{
+		"Article":       article,
+		"ArticleList":   articleEntity.AllArticlesOutput{Articles: []articleEntity.Article{*article, published}, ArticlesCount: 2},
+		"User":          user,
+		"ArticleMeta":   published.Meta("https://conduit.example/article/title", "jake"),
+		"StoredComment": comment,
+		"CommentList": dto.AllCommentsOutput{Comments: []dto.Comment{{
+			ID: "1", Body: "Nice", Replies: []dto.Comment{{ID: "2", Depth: 1, Deleted: true, Body: entityComment.DeletedPlaceholder}},
+		}}, CommentsCount: 1},
+		"Profile":     dto.ProfileDTO{Profile: dto.Profile{UserName: "jake"}},
+		"Token":       dto.GetJWTOutput{AccessToken: "token"},
+		"PreviewHTML": dto.PreviewOutput{HTML: "<p>Hi</p>"},
+		"NotificationList": dto.NotificationsOutput{Notifications: []dto.NotificationOutput{
+			{ID: "1", Type: "follow", Actors: []dto.NotificationActor{{Username: "jake"}}, ActorsCount: 1, Count: 1},
+			{ID: "2", Type: "comment", Article: &dto.NotificationArticle{Slug: "title", Title: "Title"}, Actors: []dto.NotificationActor{}, Count: 2},
+		}, NotificationsCount: 2, UnreadCount: 2},
+		"Export": dto.ExportOutput{ID: "1", Status: "ready", Size: 2048, DownloadURL: "https://conduit.example/api/exports/t",
+			ExpiresAt: "2024-01-02T00:00:00Z", CreatedAt: "2024-01-01T00:00:00Z", UpdatedAt: "2024-01-01T00:00:00Z"},
+		"NotificationPreferences": dto.NotificationPreferences{Preferences: notificationEntity.DefaultPreferences()},
+		"Webhook":                 dto.WebhookOutput{ID: "1", URL: "https://example.com/hook", Events: []string{"article.published"}, Secret: "s"},
+		"WebhookList": dto.WebhooksOutput{Webhooks: []dto.WebhookOutput{
+			{ID: "1", URL: "https://example.com/hook", Events: []string{"user.followed"}, Global: true},
+		}, WebhooksCount: 1},
+		"DeliveryList": dto.DeliveriesOutput{Deliveries: []dto.DeliveryOutput{{
+			ID: "1", Event: "comment.created", Status: "failed", Attempts: 2, ResponseStatus: 500, Error: "status 500",
+			Payload: json.RawMessage(`{"id":"1","event":"comment.created","createdAt":"2024-01-01T00:00:00Z","data":{}}`),
+		}}, DeliveriesCount: 1},
+	}
+
+	for name, value := range cases {
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+
+		errs := spec.ValidateSchema(name, data)
+		assert.Empty(t, errs, name)
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+)
+
+const usage = `usage: conduitctl [--json] <command> [arguments]
+
+commands:
+  user create <username> <email> <password|-> [--admin]
+  user reset-password <email|username> <password|->
+  user grant-admin <email|username>
+  user revoke-admin <email|username>
+  user delete <email|username> [--content anonymize|delete]
+  article delete <slug>
+  comment delete <id>
+  tag rename <from> <to>
+  migrate
+  stats
+
+A password of - is read from the first line of stdin.
+`
+
+// errUsage is returned for malformed command lines.
+var errUsage = errors.New("invalid usage")
+
+// app runs one command against the repositories and prints its outcome,
+// as text or as JSON.
+type app struct {
+	users    database.UserInterface
+	articles database.ArticleInterface
+	comments database.CommentInterface
+	tags     database.TagsInterface
+	stats    database.StatsInterface
+	migrate  func() error
+
+	in   io.Reader
+	out  io.Writer
+	json bool
+}
+
+func (a *app) run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("conduitctl", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&a.json, "json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	args = fs.Args()
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+	if command == "migrate" || command == "stats" {
+		args, err := a.parse(flag.NewFlagSet(command, flag.ContinueOnError), args)
+		if err != nil {
+			return err
+		}
+		if len(args) != 0 {
+			return errUsage
+		}
+		if command == "migrate" {
+			return a.runMigrate()
+		}
+		return a.printStats(ctx)
+	}
+
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch command + " " + args[0] {
+	case "user create":
+		return a.createUser(ctx, args[1:])
+	case "user reset-password":
+		return a.resetPassword(ctx, args[1:])
+	case "user grant-admin":
+		return a.setRole(ctx, args[1:], userEntity.RoleAdmin)
+	case "user revoke-admin":
+		return a.setRole(ctx, args[1:], userEntity.RoleUser)
+	case "user delete":
+		return a.deleteUser(ctx, args[1:])
+	case "article delete":
+		return a.deleteArticle(ctx, args[1:])
+	case "comment delete":
+		return a.deleteComment(ctx, args[1:])
+	case "tag rename":
+		return a.renameTag(ctx, args[1:])
+	}
+	return errUsage
+}
+
+// parse parses the flags of fs, which may come before or after the
+// arguments, and returns the arguments. --json is accepted everywhere.
+func (a *app) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&a.json, "json", a.json, "print JSON")
+
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUsage, err)
+		}
+		if fs.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// print writes v as JSON, or the text.
+func (a *app) print(text string, v interface{}) error {
+	if a.json {
+		return json.NewEncoder(a.out).Encode(v)
+	}
+	_, err := fmt.Fprintln(a.out, text)
+	return err
+}
+
+type userOutput struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Banned   bool   `json:"banned"`
+}
+
+func toUserOutput(u *userEntity.User) userOutput {
+	return userOutput{ID: u.ID.String(), Username: u.UserName, Email: u.Email, Role: u.Role, Banned: u.Banned}
+}
+
+func (a *app) createUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	admin := fs.Bool("admin", false, "grant the admin role")
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 3 {
+		return errUsage
+	}
+	username, email := args[0], args[1]
+
+	if username == userEntity.DeletedUserName {
+		return fmt.Errorf("username %s is reserved", username)
+	}
+	existing, err := a.users.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%s is already registered", email)
+	}
+
+	password, err := a.password(args[2])
+	if err != nil {
+		return err
+	}
+
+	u, err := userEntity.NewUser(username, email, password)
+	if err != nil {
+		return err
+	}
+	if *admin {
+		u.Role = userEntity.RoleAdmin
+	}
+	if err := a.users.CreateUser(ctx, u); err != nil {
+		return err
+	}
+
+	return a.print(fmt.Sprintf("created %s %s (%s), id %s", u.Role, u.UserName, u.Email, u.ID), toUserOutput(u))
+}
+
+func (a *app) resetPassword(ctx context.Context, args []string) error {
+	args, err := a.parse(flag.NewFlagSet("user reset-password", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errUsage
+	}
+
+	u, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	password, err := a.password(args[1])
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.users.UpdateUserDb(ctx, u.ID.String(), "", password, "", ""); err != nil {
+		return err
+	}
+
+	return a.print("password reset for "+u.UserName, toUserOutput(u))
+}
+
+func (a *app) setRole(ctx context.Context, args []string, role string) error {
+	args, err := a.parse(flag.NewFlagSet("user role", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	u, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if err := a.users.SetRole(ctx, u.ID.String(), role); err != nil {
+		return err
+	}
+
+	u.Role = role
+	return a.print(fmt.Sprintf("%s is now %s", u.UserName, role), toUserOutput(u))
+}
+
+func (a *app) deleteUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("user delete", flag.ContinueOnError)
+	content := fs.String("content", userEntity.AnonymizeContent, "anonymize or delete the user's articles and comments")
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || !userEntity.ValidContentMode(*content) {
+		return errUsage
+	}
+
+	u, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if u.ID.String() == userEntity.DeletedUserID {
+		return errors.New("the deleted-user placeholder cannot be deleted")
+	}
+
+	if _, err := a.users.DeleteUser(ctx, u.ID.String(), *content); err != nil {
+		return err
+	}
+
+	return a.print(fmt.Sprintf("deleted %s, content: %s", u.UserName, *content), map[string]string{
+		"id": u.ID.String(), "username": u.UserName, "content": *content,
+	})
+}
+
+func (a *app) deleteArticle(ctx context.Context, args []string) error {
+	args, err := a.parse(flag.NewFlagSet("article delete", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	article, err := a.articles.GetArticleBySlug(ctx, args[0])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no article %s", args[0])
+		}
+		return err
+	}
+	if _, err := a.articles.DeleteArticleDB(ctx, article.Slug); err != nil {
+		return err
+	}
+
+	return a.print("deleted article "+article.Slug, map[string]string{"id": article.ID.String(), "slug": article.Slug})
+}
+
+// deleteComment removes the comment and its replies, as moderators do.
+func (a *app) deleteComment(ctx context.Context, args []string) error {
+	args, err := a.parse(flag.NewFlagSet("comment delete", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	if _, err := a.comments.PurgeCommentDb(ctx, args[0]); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no comment %s", args[0])
+		}
+		return err
+	}
+
+	return a.print("deleted comment "+args[0]+" and its replies", map[string]string{"id": args[0]})
+}
+
+func (a *app) renameTag(ctx context.Context, args []string) error {
+	args, err := a.parse(flag.NewFlagSet("tag rename", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 || args[1] == "" || args[0] == args[1] {
+		return errUsage
+	}
+
+	slugs, err := a.tags.RenameTag(ctx, args[0], args[1])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no tag %s", args[0])
+		}
+		return err
+	}
+
+	return a.print(fmt.Sprintf("renamed %s to %s in %d articles", args[0], args[1], len(slugs)),
+		map[string]interface{}{"from": args[0], "to": args[1], "articles": slugs})
+}
+
+func (a *app) runMigrate() error {
+	if err := a.migrate(); err != nil {
+		return err
+	}
+	return a.print("tables are up to date", map[string]string{"status": "ok"})
+}
+
+func (a *app) printStats(ctx context.Context) error {
+	stats, err := a.stats.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.print("", stats)
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	for _, row := range []struct {
+		name  string
+		value int
+	}{
+		{"users", stats.Users},
+		{"admins", stats.Admins},
+		{"banned users", stats.BannedUsers},
+		{"articles", stats.Articles},
+		{"comments", stats.Comments},
+		{"tags", stats.Tags},
+		{"favorites", stats.Favorites},
+		{"follows", stats.Follows},
+		{"notifications", stats.Notifications},
+		{"webhooks", stats.Webhooks},
+	} {
+		fmt.Fprintf(tw, "%s\t%d\n", row.name, row.value)
+	}
+	return tw.Flush()
+}
+
+// findUser looks the user up by email when ref has an @, by username
+// otherwise.
+func (a *app) findUser(ctx context.Context, ref string) (*userEntity.User, error) {
+	var (
+		u   *userEntity.User
+		err error
+	)
+	if strings.Contains(ref, "@") {
+		u, err = a.users.FindByEmail(ctx, ref)
+	} else {
+		var profile *database.ProfileWithId
+		profile, err = a.users.GetProfileDb(ctx, ref)
+		if err == nil {
+			u, err = a.users.FindById(ctx, profile.Profile.ID.String())
+		}
+	}
+
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && u == nil) {
+		return nil, fmt.Errorf("no user %s", ref)
+	}
+	return u, err
+}
+
+// password returns arg, or the first line of stdin when arg is -.
+func (a *app) password(arg string) (string, error) {
+	password := arg
+	if arg == "-" {
+		line, err := bufio.NewReader(a.in).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		return "", errors.New("the password is empty")
+	}
+	return password, nil
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeUsers struct {
+	database.UserInterface
+	users    []*userEntity.User
+	deleted  map[string]string
+	password map[string]string
+}
+
+func (f *fakeUsers) FindByEmail(ctx context.Context, email string) (*userEntity.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeUsers) FindById(ctx context.Context, id string) (*userEntity.User, error) {
+	for _, u := range f.users {
+		if u.ID.String() == id {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeUsers) GetProfileDb(ctx context.Context, username string) (*database.ProfileWithId, error) {
+	for _, u := range f.users {
+		if u.UserName == username {
+			var p database.ProfileWithId
+			p.Profile.ID = u.ID
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("profile not found: %w", sql.ErrNoRows)
+}
+
+func (f *fakeUsers) CreateUser(ctx context.Context, u *userEntity.User) error {
+	f.users = append(f.users, u)
+	return nil
+}
+
+func (f *fakeUsers) UpdateUserDb(ctx context.Context, id, username, password, image, bio string) (*userEntity.User, error) {
+	u, _ := f.FindById(ctx, id)
+	f.password[u.Email] = password
+	return u, nil
+}
+
+func (f *fakeUsers) SetRole(ctx context.Context, id string, role string) error {
+	u, _ := f.FindById(ctx, id)
+	u.Role = role
+	return nil
+}
+
+func (f *fakeUsers) DeleteUser(ctx context.Context, id string, mode string) (*database.DeletedAccount, error) {
+	f.deleted[id] = mode
+	return &database.DeletedAccount{}, nil
+}
+
+type fakeArticles struct {
+	database.ArticleInterface
+	articles map[string]*articleEntity.Article
+}
+
+func (f *fakeArticles) GetArticleBySlug(ctx context.Context, slug string) (*articleEntity.Article, error) {
+	if article, ok := f.articles[slug]; ok {
+		return article, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeArticles) DeleteArticleDB(ctx context.Context, slug string) ([]string, error) {
+	delete(f.articles, slug)
+	return nil, nil
+}
+
+type fakeComments struct {
+	database.CommentInterface
+	comments map[string]bool
+}
+
+func (f *fakeComments) PurgeCommentDb(ctx context.Context, id string) ([]string, error) {
+	if !f.comments[id] {
+		return nil, sql.ErrNoRows
+	}
+	delete(f.comments, id)
+	return []string{id}, nil
+}
+
+type fakeTags struct {
+	database.TagsInterface
+	renamed [2]string
+}
+
+func (f *fakeTags) RenameTag(ctx context.Context, from, to string) ([]string, error) {
+	if from != "golang" {
+		return nil, sql.ErrNoRows
+	}
+	f.renamed = [2]string{from, to}
+	return []string{"a", "b"}, nil
+}
+
+type fakeStats struct{}
+
+func (fakeStats) Stats(ctx context.Context) (*database.Stats, error) {
+	return &database.Stats{Users: 3, Admins: 1, Articles: 5, Tags: 2}, nil
+}
+
+type fixture struct {
+	app      *app
+	out      *bytes.Buffer
+	users    *fakeUsers
+	articles *fakeArticles
+	comments *fakeComments
+	tags     *fakeTags
+	jake     *userEntity.User
+	migrated bool
+}
+
+func newFixture(t *testing.T) *fixture {
+	jake, err := userEntity.NewUser("jake", "jake@jake.jake", "jakejake")
+	require.NoError(t, err)
+	article, err := articleEntity.NewArticle(jake.ID.String(), "Hello", "", "body", nil)
+	require.NoError(t, err)
+
+	f := &fixture{
+		out:      &bytes.Buffer{},
+		users:    &fakeUsers{users: []*userEntity.User{jake}, deleted: map[string]string{}, password: map[string]string{}},
+		articles: &fakeArticles{articles: map[string]*articleEntity.Article{"hello": article}},
+		comments: &fakeComments{comments: map[string]bool{"c1": true}},
+		tags:     &fakeTags{},
+		jake:     jake,
+	}
+	f.app = &app{
+		users:    f.users,
+		articles: f.articles,
+		comments: f.comments,
+		tags:     f.tags,
+		stats:    fakeStats{},
+		migrate:  func() error { f.migrated = true; return nil },
+		in:       strings.NewReader("from-stdin\n"),
+		out:      f.out,
+	}
+	return f
+}
+
+func (f *fixture) run(args ...string) error {
+	return f.app.run(context.Background(), args)
+}
+
+func TestUserCreate(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("user", "create", "anna", "anna@anna.anna", "annaanna", "--admin"))
+	require.Len(t, f.users.users, 2)
+	anna := f.users.users[1]
+	assert.Equal(t, userEntity.RoleAdmin, anna.Role)
+	assert.True(t, anna.ValidatePassword("annaanna"))
+	assert.Equal(t, "created admin anna (anna@anna.anna), id "+anna.ID.String()+"\n", f.out.String())
+
+	assert.ErrorContains(t, f.run("user", "create", "jake2", "jake@jake.jake", "x"), "already registered")
+	assert.ErrorContains(t, f.run("user", "create", userEntity.DeletedUserName, "d@d.d", "x"), "reserved")
+	assert.ErrorIs(t, f.run("user", "create", "bob"), errUsage)
+}
+
+func TestUserCreate_JSONAndPasswordFromStdin(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("--json", "user", "create", "bob", "bob@bob.bob", "-"))
+	bob := f.users.users[1]
+	assert.True(t, bob.ValidatePassword("from-stdin"))
+
+	var out userOutput
+	require.NoError(t, json.Unmarshal(f.out.Bytes(), &out))
+	assert.Equal(t, userOutput{ID: bob.ID.String(), Username: "bob", Email: "bob@bob.bob", Role: userEntity.RoleUser}, out)
+}
+
+func TestUserResetPassword(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("user", "reset-password", "jake", "newpass"))
+	assert.Equal(t, "newpass", f.users.password["jake@jake.jake"])
+	assert.Equal(t, "password reset for jake\n", f.out.String())
+
+	assert.ErrorContains(t, f.run("user", "reset-password", "nobody", "x"), "no user nobody")
+	assert.ErrorContains(t, f.run("user", "reset-password", "nobody@example.com", "x"), "no user nobody@example.com")
+}
+
+func TestUserGrantAndRevokeAdmin(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("user", "grant-admin", "jake@jake.jake", "--json"))
+	assert.True(t, f.jake.IsAdmin())
+	assert.JSONEq(t, `{"id":"`+f.jake.ID.String()+`","username":"jake","email":"jake@jake.jake","role":"admin","banned":false}`,
+		f.out.String())
+
+	f.out.Reset()
+	require.NoError(t, f.run("user", "revoke-admin", "jake"))
+	assert.False(t, f.jake.IsAdmin())
+	assert.Equal(t, "jake is now user\n", f.out.String())
+}
+
+func TestUserDelete(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("user", "delete", "jake", "--content", "delete"))
+	assert.Equal(t, userEntity.DeleteContent, f.users.deleted[f.jake.ID.String()])
+	assert.Equal(t, "deleted jake, content: delete\n", f.out.String())
+
+	require.NoError(t, f.run("user", "delete", "jake@jake.jake"))
+	assert.Equal(t, userEntity.AnonymizeContent, f.users.deleted[f.jake.ID.String()])
+
+	assert.ErrorIs(t, f.run("user", "delete", "jake", "--content", "shred"), errUsage)
+}
+
+func TestArticleDelete(t *testing.T) {
+	f := newFixture(t)
+	id := f.articles.articles["hello"].ID.String()
+
+	require.NoError(t, f.run("--json", "article", "delete", "hello"))
+	assert.Empty(t, f.articles.articles)
+	assert.JSONEq(t, `{"id":"`+id+`","slug":"hello"}`, f.out.String())
+
+	assert.ErrorContains(t, f.run("article", "delete", "hello"), "no article hello")
+}
+
+func TestCommentDelete(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("comment", "delete", "c1"))
+	assert.Empty(t, f.comments.comments)
+	assert.Equal(t, "deleted comment c1 and its replies\n", f.out.String())
+
+	assert.ErrorContains(t, f.run("comment", "delete", "c1"), "no comment c1")
+}
+
+func TestTagRename(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("tag", "rename", "golang", "go"))
+	assert.Equal(t, [2]string{"golang", "go"}, f.tags.renamed)
+	assert.Equal(t, "renamed golang to go in 2 articles\n", f.out.String())
+
+	f.out.Reset()
+	require.NoError(t, f.run("--json", "tag", "rename", "golang", "go"))
+	assert.JSONEq(t, `{"from":"golang","to":"go","articles":["a","b"]}`, f.out.String())
+
+	assert.ErrorContains(t, f.run("tag", "rename", "rust", "go"), "no tag rust")
+	assert.ErrorIs(t, f.run("tag", "rename", "go", "go"), errUsage)
+}
+
+func TestMigrate(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("migrate"))
+	assert.True(t, f.migrated)
+	assert.Equal(t, "tables are up to date\n", f.out.String())
+
+	f.app.migrate = func() error { return errors.New("connection refused") }
+	assert.EqualError(t, f.run("migrate"), "connection refused")
+}
+
+func TestStats(t *testing.T) {
+	f := newFixture(t)
+
+	require.NoError(t, f.run("stats"))
+	assert.Contains(t, f.out.String(), "users          3\n")
+	assert.Contains(t, f.out.String(), "articles       5\n")
+
+	f.out.Reset()
+	require.NoError(t, f.run("stats", "--json"))
+	var stats database.Stats
+	require.NoError(t, json.Unmarshal(f.out.Bytes(), &stats))
+	assert.Equal(t, database.Stats{Users: 3, Admins: 1, Articles: 5, Tags: 2}, stats)
+}
+
+func TestUsage(t *testing.T) {
+	f := newFixture(t)
+
+	for _, args := range [][]string{{}, {"user"}, {"user", "rename"}, {"stats", "now"}, {"--verbose", "stats"}} {
+		assert.ErrorIs(t, f.run(args...), errUsage, args)
+	}
+}
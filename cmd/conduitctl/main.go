@@ -0,0 +1,76 @@
+// Command conduitctl runs maintenance tasks against the database the server
+// uses, with the same settings. Run it from the repository root:
+//
+//	go run ./cmd/conduitctl user grant-admin jake@jake.jake
+//	go run ./cmd/conduitctl --json stats
+//
+// It writes to the database directly: servers may serve cached users and
+// articles until CACHE_TTL runs out, and no webhooks are sent. Deleting users
+// and articles also deletes their uploads from UPLOAD_DIR.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/sallescosta/conduit-api/configs"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/uploads"
+	"github.com/sallescosta/conduit-api/pkg/blob"
+)
+
+func main() {
+	// results go to stdout, logs to stderr
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	config, err := configs.LoadConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	connStr := fmt.Sprintf(
+		"user=%s password= %s dbname=%s sslmode=disable",
+		config.DBUser,
+		config.DBPassword,
+		config.DBName,
+	)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "conduitctl:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := database.WaitForDB(ctx, db, config.DBConnectTimeout); err != nil {
+		fmt.Fprintln(os.Stderr, "conduitctl:", err)
+		os.Exit(1)
+	}
+
+	store := blob.NewLocal(config.UploadDir)
+	a := &app{
+		users:    uploads.NewUserRepository(database.NewUser(db), store),
+		articles: uploads.NewArticleRepository(database.NewArticle(db), store),
+		comments: database.NewComment(db),
+		tags:     database.NewTag(db),
+		stats:    database.NewStats(db),
+		migrate:  func() error { return database.Migrate(db) },
+		in:       os.Stdin,
+		out:      os.Stdout,
+	}
+
+	if err := a.run(ctx, os.Args[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "conduitctl:", err)
+		os.Exit(1)
+	}
+}
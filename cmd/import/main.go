@@ -0,0 +1,102 @@
+// Command import creates articles from a directory of Markdown files with
+// YAML front matter, such as the articles of a data export:
+//
+//	---
+//	title: "How to train your dragon"
+//	description: "Ever wonder how?"
+//	tags: ["dragons", "training"]
+//	date: 2019-04-01T10:00:00Z
+//	slug: how-to-train-your-dragon
+//	author_email: jake@jake.jake
+//	---
+//
+//	It takes a Jacobian.
+//
+// Run it from the repository root, where the server reads its settings:
+//
+//	go run ./cmd/import -dir ./posts -author jake@jake.jake -dry-run
+//
+// Files whose slug exists already are skipped, so it can be run again. It
+// exits with status 1 when a file failed.
+//
+// With CACHE_DRIVER=redis it writes through the same cache as the server, so
+// the tag list is refreshed at once. The memory cache lives inside the
+// server process, which picks the new tags up after CACHE_TTL.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/sallescosta/conduit-api/configs"
+	"github.com/sallescosta/conduit-api/internal/infra/cache"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/imports"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory of .md files, read recursively")
+	author := flag.String("author", "", "email of the author of the files without author_email")
+	dryRun := flag.Bool("dry-run", false, "check the files without creating anything")
+	flag.Parse()
+
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "usage: import -dir <directory> [-author <email>] [-dry-run]")
+		os.Exit(2)
+	}
+
+	config, err := configs.LoadConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	connStr := fmt.Sprintf(
+		"user=%s password= %s dbname=%s sslmode=disable",
+		config.DBUser,
+		config.DBPassword,
+		config.DBName,
+	)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		slog.Error("Error opening database connection", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := database.WaitForDB(ctx, db, config.DBConnectTimeout); err != nil {
+		slog.Error("Error connecting to the database", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if err := database.Migrate(db); err != nil {
+		slog.Error("Error creating tables", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	var articleDB database.ArticleInterface = database.NewArticle(db)
+	var tagDB database.TagsInterface = database.NewTag(db)
+	if config.CacheDriver == "redis" {
+		store := cache.NewRedis(config.RedisAddr, 0)
+		defer store.Close()
+		articleDB = cache.NewArticleRepository(articleDB, store, config.CacheTTL)
+		tagDB = cache.NewTagRepository(tagDB, store, config.CacheTTL)
+	}
+
+	importer := imports.NewImporter(database.NewUser(db), articleDB, tagDB, *author, *dryRun)
+	report, err := importer.ImportDir(ctx, *dir)
+	if err != nil {
+		slog.Error("Error reading the directory", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	report.Write(os.Stdout)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
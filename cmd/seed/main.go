@@ -0,0 +1,122 @@
+// Command seed fills the database with generated users, articles, comments,
+// follows and favorites. The same -seed always generates the same content.
+// Run it from the repository root, where the server reads its settings:
+//
+//	go run ./cmd/seed -reset -users 500 -articles 5000 -copy
+//
+// Every user can sign in with their email, such as ana.silva@example.com,
+// and the -password. -reset empties every table first, so never point it at
+// data you want to keep.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/sallescosta/conduit-api/configs"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/seed"
+)
+
+func main() {
+	seedValue := flag.Int64("seed", 1, "random seed")
+	users := flag.Int("users", 50, "users to create")
+	articles := flag.Int("articles", 200, "articles to create")
+	comments := flag.Int("comments", 4, "average comments per article")
+	follows := flag.Int("follows", 5, "average follows per user")
+	favorites := flag.Int("favorites", 10, "average favorites per user")
+	password := flag.String("password", "password", "password of every user")
+	reset := flag.Bool("reset", false, "empty every table first")
+	bulk := flag.Bool("copy", false, "load with COPY instead of the repositories")
+	flag.Parse()
+
+	config, err := configs.LoadConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	connStr := fmt.Sprintf(
+		"user=%s password= %s dbname=%s sslmode=disable",
+		config.DBUser,
+		config.DBPassword,
+		config.DBName,
+	)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		slog.Error("Error opening database connection", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := database.WaitForDB(ctx, db, config.DBConnectTimeout); err != nil {
+		slog.Error("Error connecting to the database", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if err := database.Migrate(db); err != nil {
+		slog.Error("Error creating tables", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	seedDB := database.NewSeed(db)
+	if *reset {
+		if err := seedDB.Reset(ctx); err != nil {
+			slog.Error("Error resetting the database", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	hash, err := userEntity.DoHash(*password)
+	if err != nil {
+		slog.Error("Error hashing the password", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	data, err := seed.Generate(seed.Config{
+		Seed:         *seedValue,
+		Users:        *users,
+		Articles:     *articles,
+		Comments:     *comments,
+		Follows:      *follows,
+		Favorites:    *favorites,
+		Now:          start.UTC().Truncate(time.Hour),
+		PasswordHash: string(hash),
+	})
+	if err != nil {
+		slog.Error("Error generating data", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	if *bulk {
+		err = seedDB.CopySeedData(ctx, data)
+	} else {
+		repos := &seed.Repositories{
+			UserDB:    database.NewUser(db),
+			ArticleDB: database.NewArticle(db),
+			CommentDB: database.NewComment(db),
+			TagDB:     database.NewTag(db),
+		}
+		err = repos.Write(ctx, data)
+	}
+	if err != nil {
+		slog.Error("Error writing data", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	slog.Info("database seeded",
+		slog.Int64("seed", *seedValue),
+		slog.Int("users", len(data.Users)),
+		slog.Int("articles", len(data.Articles)),
+		slog.Int("comments", len(data.Comments)),
+		slog.Int("tags", len(data.Tags)),
+		slog.Duration("took", time.Since(start)))
+}
@@ -1,18 +1,29 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/exports"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
+	"github.com/sallescosta/conduit-api/internal/infra/webhooks"
+	"github.com/sallescosta/conduit-api/pkg/logger"
+	"github.com/sallescosta/conduit-api/pkg/tracing"
+	"github.com/sallescosta/conduit-api/pkg/webhook"
 
 	"github.com/go-chi/chi/v5"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sallescosta/conduit-api/cmd/server/router"
 	"github.com/sallescosta/conduit-api/configs"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
 )
 
 func main() {
@@ -22,6 +33,8 @@ func main() {
 		panic(err)
 	}
 
+	slog.SetDefault(logger.New(os.Stdout, config.LogFormat, config.LogLevel))
+
 	connStr := fmt.Sprintf(
 		"user=%s password= %s dbname=%s sslmode=disable",
 		config.DBUser,
@@ -29,34 +42,83 @@ func main() {
 		config.DBName,
 	)
 
-	db, err := sql.Open("postgres", connStr)
-
+	tracer, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    config.TracesExporter,
+		Endpoint:    config.OTLPEndpoint,
+		File:        config.TracesFile,
+		ServiceName: config.ServiceName,
+		SampleRatio: config.TraceSampleRatio,
+	})
 	if err != nil {
-		slog.Error("Error opening database connection", slog.String("error", err.Error()))
+		slog.Error("Error configuring tracing", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
 
-	err = db.Ping()
-	if err != nil {
-		slog.Error("Verify the docker. Open the docker and run `docker-compose up -d`")
+	var db *sql.DB
+	if tracer != nil {
+		slog.Info("Tracing enabled", slog.String("exporter", config.TracesExporter))
+		defer tracer.Shutdown(context.Background())
+
+		db, err = tracing.OpenDB("postgres", connStr, semconv.DBSystemNamePostgreSQL)
 	} else {
-		fmt.Println("Successfully connected to the database!")
-		database.CreateUsersTable(db)
-		database.CreateArticlesTable(db)
-		database.CreateCommentsTable(db)
-		database.CreateTagsTable(db)
-		database.CreateArticleTagsTable(db)
+		db, err = sql.Open("postgres", connStr)
 	}
 
+	if err != nil {
+		slog.Error("Error opening database connection", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 	defer db.Close()
 
-	if err != nil {
-		log.Fatal(err)
+	if err := database.WaitForDB(context.Background(), db, config.DBConnectTimeout); err != nil {
+		slog.Error("Verify the docker. Open the docker and run `docker-compose up -d`", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
+	slog.Info("Successfully connected to the database!")
+
+	if err := database.Migrate(db); err != nil {
+		slog.Error("Error creating tables", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	if len(config.AdminEmails) > 0 {
+		if err := database.NewUser(db).PromoteAdmins(context.Background(), config.AdminEmails); err != nil {
+			slog.Error("Error promoting admins", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	if config.MetricsAddr != "" {
+		go serveMetrics(config.MetricsAddr, reg)
+	}
+
+	webhookDB := instrument.NewWebhookRepository(database.NewWebhook(db), instrument.RepositoryTimer)
+	sender := webhook.NewSender(config.WebhookTimeout, config.WebhookAllowPrivate)
+	go webhooks.NewWorker(webhookDB, sender, config.WebhookPollInterval).Run(context.Background())
+
+	exportDB := instrument.NewExportRepository(database.NewExport(db), instrument.RepositoryTimer)
+	go exports.NewWorker(exportDB, config.ExportTTL, config.ExportPollInterval).Run(context.Background())
+
 	r := chi.NewRouter()
 
-	router.Init(r, config, db)
-	err = http.ListenAndServe(":8080", r)
+	router.Init(r, config, db, reg)
+	err = http.ListenAndServe(":"+config.WebServePort, r)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("Server stopped", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+// serveMetrics keeps /metrics off the public port when METRICS_ADDR is set.
+func serveMetrics(addr string, reg *prometheus.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	slog.Info("Serving metrics", slog.String("addr", addr))
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("Metrics server stopped", slog.String("error", err.Error()))
 	}
 }
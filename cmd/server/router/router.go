@@ -1,72 +1,262 @@
 package router
 
 import (
+	"context"
 	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/jwtauth"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sallescosta/conduit-api/api"
 	"github.com/sallescosta/conduit-api/configs"
+	"github.com/sallescosta/conduit-api/internal/infra/cache"
 	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
+	"github.com/sallescosta/conduit-api/internal/infra/uploads"
+	"github.com/sallescosta/conduit-api/internal/infra/webhooks"
+	"github.com/sallescosta/conduit-api/pkg/blob"
+	"github.com/sallescosta/conduit-api/pkg/conditional"
+	"github.com/sallescosta/conduit-api/pkg/health"
+	"github.com/sallescosta/conduit-api/pkg/logger"
+	"github.com/sallescosta/conduit-api/pkg/openapi"
+	"github.com/sallescosta/conduit-api/pkg/stream"
+	"github.com/sallescosta/conduit-api/pkg/tracing"
 
 	"github.com/sallescosta/conduit-api/internal/infra/webserver/handlers"
 )
 
-func Init(r *chi.Mux, config *configs.Conf, db *sql.DB) {
-	userHandler := handlers.NewUserHandler(database.NewUser(db))
-	articleHandler := handlers.NewArticleHandler(database.NewArticle(db), database.NewTag(db))
-	commentHandler := handlers.NewCommentHandler(database.NewComment(db))
-	tagHandler := handlers.NewTagHandler(database.NewTag(db))
+// Init registers every route on r and the application metrics on reg, which
+// also backs /metrics when it is served on the public port.
+func Init(r *chi.Mux, config *configs.Conf, db *sql.DB, reg *prometheus.Registry) {
+	if err := instrument.Register(reg, db, config.DBName); err != nil {
+		panic(err)
+	}
 
-	r.Use(middleware.Logger)
+	observe := instrument.Chain(instrument.RepositoryTimer, tracing.Observe)
+	var userDB database.UserInterface = instrument.NewUserRepository(database.NewUser(db), observe)
+	var articleDB database.ArticleInterface = instrument.NewArticleRepository(database.NewArticle(db), observe)
+	var commentDB database.CommentInterface = instrument.NewCommentRepository(database.NewComment(db), observe)
+	var tagDB database.TagsInterface = instrument.NewTagRepository(database.NewTag(db), observe)
+	var notificationDB database.NotificationInterface = instrument.NewNotificationRepository(database.NewNotification(db), observe)
+	var webhookDB database.WebhookInterface = instrument.NewWebhookRepository(database.NewWebhook(db), observe)
+	var sitemapDB database.SitemapInterface = instrument.NewSitemapRepository(database.NewSitemap(db), observe)
+	var exportDB database.ExportInterface = instrument.NewExportRepository(database.NewExport(db), observe)
+
+	uploadStore := blob.NewLocal(config.UploadDir)
+	userDB = uploads.NewUserRepository(userDB, uploadStore)
+	articleDB = uploads.NewArticleRepository(articleDB, uploadStore)
+
+	store := newCache(config)
+	if store != nil {
+		userDB = cache.NewUserRepository(userDB, store, config.CacheTTL)
+		articleDB = cache.NewArticleRepository(articleDB, store, config.CacheTTL)
+		tagDB = cache.NewTagRepository(tagDB, store, config.CacheTTL)
+	}
+
+	dispatcher := webhooks.NewDispatcher(webhookDB)
+	notifier := handlers.NewNotifier(notificationDB, articleDB, dispatcher)
+	broker := stream.NewMemory(config.SSEHistory, 1000)
+
+	userHandler := handlers.NewUserHandler(userDB, notifier, config.SiteURL)
+	articleHandler := handlers.NewArticleHandler(articleDB, tagDB, dispatcher)
+	commentHandler := handlers.NewCommentHandler(commentDB, articleDB, userDB, notifier, broker, config.CommentMaxDepth, config.SiteURL)
+	commentStreamHandler := handlers.NewCommentStreamHandler(articleDB, broker, config.SSEHeartbeat)
+	tagHandler := handlers.NewTagHandler(tagDB)
+	notificationHandler := handlers.NewNotificationHandler(notificationDB, config.SiteURL)
+	webhookHandler := handlers.NewWebhookHandler(webhookDB, userDB)
+	feedHandler := handlers.NewFeedHandler(articleDB, userDB, config.SiteURL, config.FeedItems)
+	seoHandler := handlers.NewSEOHandler(articleDB, userDB, sitemapDB, config.SiteURL)
+	exportHandler := handlers.NewExportHandler(exportDB, config.SiteURL)
+	uploadHandler := handlers.NewUploadHandler(userDB, articleDB, uploadStore, dispatcher,
+		config.SiteURL, config.UploadMaxBytes)
+	adminHandler := handlers.NewAdminHandler(userDB, articleDB, commentDB, broker, dispatcher, config.SiteURL)
+	auth := handlers.NewAuth(userDB)
+
+	r.Use(tracing.Middleware)
+	r.Use(logger.Middleware(slog.Default()))
+	r.Use(instrument.Middleware)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.WithValue("jwt", config.TokenAuth))
 	r.Use(middleware.WithValue("JwtExpiresIn", config.JwtExpiresIn))
 
+	spec, err := api.Spec()
+	if err != nil {
+		panic(err)
+	}
+	r.Use(openapi.Middleware(spec, config.OpenAPIValidation))
+
+	r.Get("/api/openapi.json", api.ServeSpec)
+	r.Get("/api/docs", api.ServeDocs)
+
+	checker := health.NewChecker(2 * time.Second)
+	checker.Add("database", db.PingContext)
+	checker.Add("migrations", func(ctx context.Context) error { return database.CheckMigrations(ctx, db) })
+	if pinger, ok := store.(interface{ Ping() error }); ok {
+		checker.Add("cache", func(ctx context.Context) error { return pinger.Ping() })
+	}
+
+	r.Get("/healthz", health.Live)
+	r.Get("/readyz", checker.Ready)
+
+	if config.MetricsAddr == "" {
+		r.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	}
+
+	r.Route("/feeds", func(r chi.Router) {
+		r.Use(conditional.Middleware)
+
+		r.Get("/articles.atom", feedHandler.GlobalFeed)
+		r.Get("/articles.rss", feedHandler.GlobalFeed)
+		r.Get("/authors/{username}", feedHandler.AuthorFeed)
+		r.Get("/tags/{tag}", feedHandler.TagFeed)
+	})
+
+	r.With(conditional.Middleware).Get("/sitemap.xml", seoHandler.Sitemap)
+	r.With(conditional.Middleware).Get("/sitemaps/{page}", seoHandler.SitemapPage)
+
 	r.Post("/api/users", userHandler.CreateUser)
 	r.Post("/api/users/login", userHandler.GetJWT)
+	r.Get("/api/exports/{token}", exportHandler.DownloadExport)
+	r.Get("/uploads/{kind}/{owner}/{name}", uploadHandler.ServeUpload)
 
 	r.Route("/api/user", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(config.TokenAuth))
 		r.Use(jwtauth.Authenticator)
+		r.Use(logger.CaptureUser)
+		r.Use(auth.RejectBanned)
 
 		r.Put("/", userHandler.UpdateUser)
 		r.Get("/", userHandler.GetCurrentUser)
-		r.Get("/all", userHandler.ListAllUsers)
+		r.Delete("/", userHandler.DeleteAccount)
+		r.Put("/avatar", uploadHandler.UploadAvatar)
+		r.Post("/export", exportHandler.RequestExport)
+		r.Get("/export/{id}", exportHandler.GetExport)
 	})
 
 	r.Route("/api/profiles", func(r chi.Router) {
-		r.Use(jwtauth.Verifier(config.TokenAuth))
-		r.Use(jwtauth.Authenticator)
+		r.With(conditional.Middleware).Get("/{username}/avatar.svg", userHandler.DefaultAvatar)
+
+		r.Group(func(r chi.Router) {
+			r.Use(jwtauth.Verifier(config.TokenAuth))
+			r.Use(jwtauth.Authenticator)
+			r.Use(logger.CaptureUser)
+			r.Use(auth.RejectBanned)
 
-		r.Get("/{username}", userHandler.GetProfileUser)
-		r.Post("/{username}/follow", userHandler.FollowUser)
-		r.Delete("/{username}/follow", userHandler.FollowUser)
+			r.With(conditional.Middleware).Get("/{username}", userHandler.GetProfileUser)
+			r.Post("/{username}/follow", userHandler.FollowUser)
+			r.Delete("/{username}/follow", userHandler.FollowUser)
+		})
 	})
 
 	r.Route("/api/articles", func(r chi.Router) {
+		// EventSource cannot send an Authorization header, and the comments
+		// it streams are public anyway
+		r.Get("/{slug}/comments/stream", commentStreamHandler.StreamComments)
+
+		r.Group(func(r chi.Router) {
+			r.Use(jwtauth.Verifier(config.TokenAuth))
+			r.Use(jwtauth.Authenticator)
+			r.Use(logger.CaptureUser)
+			r.Use(auth.RejectBanned)
+
+			r.Post("/", articleHandler.CreateArticle)
+			r.With(conditional.Middleware).Get("/", articleHandler.ListAllArticle)
+			r.Get("/feed", articleHandler.FeedArticles)
+			r.Post("/preview", articleHandler.PreviewArticle)
+
+			r.With(conditional.Middleware).Get("/{slug}", articleHandler.GetArticle)
+			r.Put("/{slug}", articleHandler.UpdateArticle)
+			r.Delete("/{slug}", articleHandler.DeleteArticle)
+			r.Put("/{slug}/cover", uploadHandler.UploadCover)
+			r.With(conditional.Middleware).Get("/{slug}/meta", seoHandler.ArticleMeta)
+
+			r.Post("/{slug}/favorite", userHandler.FavoriteArticle)
+			r.Delete("/{slug}/favorite", userHandler.FavoriteArticle)
+
+			r.Post("/{slug}/comments", commentHandler.CreateComment)
+			r.Get("/{slug}/comments", commentHandler.GetComments)
+			r.Put("/{slug}/comments/{id}", commentHandler.UpdateComment)
+			r.Delete("/{slug}/comments/{id}", commentHandler.DeleteComment)
+		})
+	})
+
+	r.Route("/api/tags", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(config.TokenAuth))
 		r.Use(jwtauth.Authenticator)
+		r.Use(logger.CaptureUser)
+		r.Use(auth.RejectBanned)
+		r.With(conditional.Middleware).Get("/", tagHandler.ListTags)
+	})
 
-		r.Post("/", articleHandler.CreateArticle)
-		r.Get("/", articleHandler.ListAllArticle)
-		r.Get("/feed", articleHandler.FeedArticles)
+	r.Route("/api/notifications", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(logger.CaptureUser)
+		r.Use(auth.RejectBanned)
 
-		r.Get("/{slug}", articleHandler.GetArticle)
-		r.Put("/{slug}", articleHandler.UpdateArticle)
-		r.Delete("/{slug}", articleHandler.DeleteArticle)
+		r.Get("/", notificationHandler.ListNotifications)
+		r.Post("/read", notificationHandler.MarkAllRead)
+		r.Post("/{id}/read", notificationHandler.MarkRead)
+		r.Get("/preferences", notificationHandler.GetPreferences)
+		r.Put("/preferences", notificationHandler.UpdatePreferences)
+	})
 
-		r.Post("/{slug}/favorite", userHandler.FavoriteArticle)
-		r.Delete("/{slug}/favorite", userHandler.FavoriteArticle)
+	r.Route("/api/webhooks", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(logger.CaptureUser)
+		r.Use(auth.RejectBanned)
 
-		r.Post("/{slug}/comments", commentHandler.CreateComment)
-		r.Get("/{slug}/comments", commentHandler.GetComments)
-		r.Delete("/{slug}/comments/{id}", commentHandler.DeleteComment)
+		r.Post("/", webhookHandler.CreateWebhook)
+		r.Get("/", webhookHandler.ListWebhooks)
+		r.Delete("/{id}", webhookHandler.DeleteWebhook)
+		r.Get("/{id}/deliveries", webhookHandler.ListDeliveries)
+		r.Post("/{id}/deliveries/{deliveryId}/redeliver", webhookHandler.Redeliver)
 	})
 
-	r.Route("/api/tags", func(r chi.Router) {
+	r.Route("/api/admin", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(config.TokenAuth))
 		r.Use(jwtauth.Authenticator)
-		r.Get("/", tagHandler.ListTags)
+		r.Use(logger.CaptureUser)
+		r.Use(auth.RejectBanned)
+		r.Use(auth.RequireAdmin)
+
+		r.Get("/users", adminHandler.ListUsers)
+		r.Post("/users/{username}/ban", adminHandler.BanUser)
+		r.Delete("/users/{username}/ban", adminHandler.BanUser)
+		r.Post("/users/{username}/admin", adminHandler.PromoteUser)
+		r.Delete("/users/{username}/admin", adminHandler.PromoteUser)
+		r.Delete("/articles/{slug}", adminHandler.DeleteArticle)
+		r.Delete("/comments/{id}", adminHandler.DeleteComment)
 	})
+
+	if store != nil {
+		cacheHandler := handlers.NewCacheHandler(store)
+
+		r.Route("/api/cache", func(r chi.Router) {
+			r.Use(jwtauth.Verifier(config.TokenAuth))
+			r.Use(jwtauth.Authenticator)
+			r.Use(logger.CaptureUser)
+			r.Use(auth.RejectBanned)
+			r.Use(auth.RequireAdmin)
+			r.Get("/stats", cacheHandler.Stats)
+		})
+	}
+}
+
+// newCache picks the cache backend from CACHE_DRIVER; repositories are used
+// directly when it is empty.
+func newCache(config *configs.Conf) cache.Cache {
+	switch config.CacheDriver {
+	case "memory":
+		return cache.NewMemory(config.CacheSize)
+	case "redis":
+		return cache.NewRedis(config.RedisAddr, 0)
+	}
+	return nil
 }
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sallescosta/conduit-api/api"
+	"github.com/sallescosta/conduit-api/configs"
+	"github.com/sallescosta/conduit-api/pkg/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoutesMatchOpenAPI fails when a route is registered without being
+// documented, or documented without being registered.
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	// sql.Open does not connect, so no database is needed to build the router
+	db, err := sql.Open("postgres", "sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	config := &configs.Conf{
+		TokenAuth:       jwtauth.New("HS256", []byte("secret"), nil),
+		CommentMaxDepth: 5,
+		CacheDriver:     "memory",
+		CacheSize:       10,
+		CacheTTL:        time.Minute,
+	}
+
+	r := chi.NewRouter()
+	Init(r, config, db, prometheus.NewRegistry())
+
+	var routes []string
+	err = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+openapi.NormalizePath(route))
+		return nil
+	})
+	require.NoError(t, err)
+	sort.Strings(routes)
+
+	spec, err := api.Spec()
+	require.NoError(t, err)
+
+	assert.Equal(t, spec.Operations(), routes)
+}
+
+// TestInit_Twice builds two routers in one process, as tests and embedders
+// do; each gets its own metrics registry.
+func TestInit_Twice(t *testing.T) {
+	db, err := sql.Open("postgres", "sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	config := &configs.Conf{
+		TokenAuth:       jwtauth.New("HS256", []byte("secret"), nil),
+		CommentMaxDepth: 5,
+	}
+
+	assert.NotPanics(t, func() {
+		Init(chi.NewRouter(), config, db, prometheus.NewRegistry())
+		Init(chi.NewRouter(), config, db, prometheus.NewRegistry())
+	})
+}
+
+// TestCommentStream_Public reaches the comment stream without a token: the
+// unknown article answers 404 rather than 401.
+func TestCommentStream_Public(t *testing.T) {
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	config := &configs.Conf{
+		TokenAuth:       jwtauth.New("HS256", []byte("secret"), nil),
+		CommentMaxDepth: 5,
+	}
+
+	r := chi.NewRouter()
+	Init(r, config, db, prometheus.NewRegistry())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/articles/missing/comments/stream", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/articles/missing/comments", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
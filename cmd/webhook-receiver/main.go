@@ -0,0 +1,36 @@
+// Command webhook-receiver is a local endpoint to try webhooks with: it
+// checks the signature of every delivery and prints its payload.
+//
+//	go run ./cmd/webhook-receiver -secret <secret returned when the webhook was created>
+//
+// Register http://localhost:9000 as the webhook URL, and start the server
+// with WEBHOOK_ALLOW_PRIVATE=true so it may deliver to localhost. That turns
+// off the guard against private addresses: never set it outside local
+// development.
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/sallescosta/conduit-api/pkg/webhook"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	secret := flag.String("secret", os.Getenv("WEBHOOK_SECRET"), "webhook secret, defaults to $WEBHOOK_SECRET")
+	flag.Parse()
+
+	if *secret == "" {
+		slog.Error("a secret is required: pass -secret or set WEBHOOK_SECRET")
+		os.Exit(2)
+	}
+
+	slog.Info("waiting for webhooks", slog.String("addr", *addr))
+	if err := http.ListenAndServe(*addr, webhook.Receiver(*secret, os.Stdout)); err != nil {
+		slog.Error("receiver stopped", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
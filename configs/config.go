@@ -3,6 +3,8 @@ package configs
 import (
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/jwtauth"
 	"github.com/joho/godotenv"
@@ -11,14 +13,54 @@ import (
 )
 
 type Conf struct {
-	DBHost       string `mapstructure:"DB_HOST"`
-	DBPort       string `mapstructure:"DB_PORT"`
-	DBUser       string `mapstructure:"DB_USER"`
-	DBPassword   string `mapstructure:"DB_PASSWORD"`
-	DBName       string `mapstructure:"DB_NAME"`
-	WebServePort string `mapstructure:"WEB_SERVER_PORT"`
-	JwtExpiresIn int    `mapstructure:"JWT_EXPIRESIN"`
-	TokenAuth    *jwtauth.JWTAuth
+	DBHost     string `mapstructure:"DB_HOST"`
+	DBPort     string `mapstructure:"DB_PORT"`
+	DBUser     string `mapstructure:"DB_USER"`
+	DBPassword string `mapstructure:"DB_PASSWORD"`
+	DBName     string `mapstructure:"DB_NAME"`
+
+	DBConnectTimeout time.Duration `mapstructure:"DB_CONNECT_TIMEOUT"`
+	WebServePort     string        `mapstructure:"WEB_SERVER_PORT"`
+	JwtExpiresIn     int           `mapstructure:"JWT_EXPIRESIN"`
+	TokenAuth        *jwtauth.JWTAuth
+
+	CommentMaxDepth int `mapstructure:"COMMENT_MAX_DEPTH"`
+
+	CacheDriver string        `mapstructure:"CACHE_DRIVER"`
+	CacheSize   int           `mapstructure:"CACHE_SIZE"`
+	CacheTTL    time.Duration `mapstructure:"CACHE_TTL"`
+	RedisAddr   string        `mapstructure:"REDIS_ADDR"`
+
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+
+	MetricsAddr string `mapstructure:"METRICS_ADDR"`
+
+	OpenAPIValidation string `mapstructure:"OPENAPI_VALIDATION"`
+
+	AdminEmails []string `mapstructure:"ADMIN_EMAILS"`
+
+	SSEHeartbeat time.Duration `mapstructure:"SSE_HEARTBEAT"`
+	SSEHistory   int           `mapstructure:"SSE_HISTORY"`
+
+	WebhookTimeout      time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookPollInterval time.Duration `mapstructure:"WEBHOOK_POLL_INTERVAL"`
+	WebhookAllowPrivate bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE"`
+
+	SiteURL   string `mapstructure:"SITE_URL"`
+	FeedItems int    `mapstructure:"FEED_ITEMS"`
+
+	ExportTTL          time.Duration `mapstructure:"EXPORT_TTL"`
+	ExportPollInterval time.Duration `mapstructure:"EXPORT_POLL_INTERVAL"`
+
+	UploadDir      string `mapstructure:"UPLOAD_DIR"`
+	UploadMaxBytes int64  `mapstructure:"UPLOAD_MAX_MB"`
+
+	ServiceName      string  `mapstructure:"OTEL_SERVICE_NAME"`
+	TracesExporter   string  `mapstructure:"OTEL_TRACES_EXPORTER"`
+	OTLPEndpoint     string  `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracesFile       string  `mapstructure:"OTEL_TRACES_FILE"`
+	TraceSampleRatio float64 `mapstructure:"OTEL_TRACES_SAMPLER_ARG"`
 
 	DBDriver string `mapstructure:"DB_DRIVER"`
 }
@@ -38,16 +80,158 @@ func LoadConfig() (*Conf, error) {
 		log.Fatalf("Erro ao converter JWT_EXPIRESIN para inteiro: %v", err)
 	}
 
+	dbConnectTimeout, err := strconv.Atoi(os.Getenv("DB_CONNECT_TIMEOUT"))
+	if err != nil || dbConnectTimeout <= 0 {
+		dbConnectTimeout = 30
+	}
+
+	commentMaxDepth, err := strconv.Atoi(os.Getenv("COMMENT_MAX_DEPTH"))
+	if err != nil || commentMaxDepth < 0 {
+		commentMaxDepth = 5
+	}
+
+	cacheSize, err := strconv.Atoi(os.Getenv("CACHE_SIZE"))
+	if err != nil {
+		cacheSize = 1000
+	}
+
+	cacheTTL, err := strconv.Atoi(os.Getenv("CACHE_TTL"))
+	if err != nil {
+		cacheTTL = 60
+	}
+
+	sseHeartbeat, err := strconv.Atoi(os.Getenv("SSE_HEARTBEAT"))
+	if err != nil || sseHeartbeat <= 0 {
+		sseHeartbeat = 15
+	}
+
+	sseHistory, err := strconv.Atoi(os.Getenv("SSE_HISTORY"))
+	if err != nil || sseHistory < 0 {
+		sseHistory = 100
+	}
+
+	webhookTimeout, err := strconv.Atoi(os.Getenv("WEBHOOK_TIMEOUT"))
+	if err != nil || webhookTimeout <= 0 {
+		webhookTimeout = 10
+	}
+
+	webhookPollInterval, err := strconv.Atoi(os.Getenv("WEBHOOK_POLL_INTERVAL"))
+	if err != nil || webhookPollInterval <= 0 {
+		webhookPollInterval = 2
+	}
+
+	webhookAllowPrivate, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE"))
+
+	webServerPort := os.Getenv("WEB_SERVER_PORT")
+	if webServerPort == "" {
+		webServerPort = "8080"
+	}
+
+	siteURL := os.Getenv("SITE_URL")
+	if siteURL == "" {
+		siteURL = "http://localhost:" + webServerPort
+	}
+
+	feedItems, err := strconv.Atoi(os.Getenv("FEED_ITEMS"))
+	if err != nil || feedItems <= 0 {
+		feedItems = 20
+	}
+	if feedItems > 100 {
+		feedItems = 100
+	}
+
+	exportTTL, err := strconv.Atoi(os.Getenv("EXPORT_TTL"))
+	if err != nil || exportTTL <= 0 {
+		exportTTL = 24
+	}
+
+	exportPollInterval, err := strconv.Atoi(os.Getenv("EXPORT_POLL_INTERVAL"))
+	if err != nil || exportPollInterval <= 0 {
+		exportPollInterval = 5
+	}
+
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "uploads"
+	}
+
+	uploadMaxMB, err := strconv.Atoi(os.Getenv("UPLOAD_MAX_MB"))
+	if err != nil || uploadMaxMB <= 0 {
+		uploadMaxMB = 5
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "conduit-api"
+	}
+
+	otlpEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if otlpEndpoint == "" {
+		otlpEndpoint = "http://localhost:4318"
+	}
+
+	traceSampleRatio, err := strconv.ParseFloat(os.Getenv("OTEL_TRACES_SAMPLER_ARG"), 64)
+	if err != nil {
+		traceSampleRatio = 1
+	}
+
+	var adminEmails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			adminEmails = append(adminEmails, email)
+		}
+	}
+
 	tokenAuth := jwtauth.New("HS256", []byte(os.Getenv("JWT_SECRET")), nil)
 
 	return &Conf{
-		DBHost:       os.Getenv("DB_HOST"),
-		DBPort:       port,
-		DBUser:       os.Getenv("DB_USER"),
-		DBPassword:   os.Getenv("DB_PASSWORD"),
-		DBName:       os.Getenv("DB_NAME"),
-		WebServePort: os.Getenv("WEB_SERVER_PORT"),
-		JwtExpiresIn: jwtExpiresIn,
-		TokenAuth:    tokenAuth,
-	}, err
+		DBHost:     os.Getenv("DB_HOST"),
+		DBPort:     port,
+		DBUser:     os.Getenv("DB_USER"),
+		DBPassword: os.Getenv("DB_PASSWORD"),
+		DBName:     os.Getenv("DB_NAME"),
+
+		DBConnectTimeout: time.Duration(dbConnectTimeout) * time.Second,
+		WebServePort:     webServerPort,
+		JwtExpiresIn:     jwtExpiresIn,
+		TokenAuth:        tokenAuth,
+
+		CommentMaxDepth: commentMaxDepth,
+
+		CacheDriver: os.Getenv("CACHE_DRIVER"),
+		CacheSize:   cacheSize,
+		CacheTTL:    time.Duration(cacheTTL) * time.Second,
+		RedisAddr:   os.Getenv("REDIS_ADDR"),
+
+		LogFormat: os.Getenv("LOG_FORMAT"),
+		LogLevel:  os.Getenv("LOG_LEVEL"),
+
+		MetricsAddr: os.Getenv("METRICS_ADDR"),
+
+		OpenAPIValidation: os.Getenv("OPENAPI_VALIDATION"),
+
+		AdminEmails: adminEmails,
+
+		SSEHeartbeat: time.Duration(sseHeartbeat) * time.Second,
+		SSEHistory:   sseHistory,
+
+		WebhookTimeout:      time.Duration(webhookTimeout) * time.Second,
+		WebhookPollInterval: time.Duration(webhookPollInterval) * time.Second,
+		WebhookAllowPrivate: webhookAllowPrivate,
+
+		SiteURL:   siteURL,
+		FeedItems: feedItems,
+
+		ExportTTL:          time.Duration(exportTTL) * time.Hour,
+		ExportPollInterval: time.Duration(exportPollInterval) * time.Second,
+
+		UploadDir:      uploadDir,
+		UploadMaxBytes: int64(uploadMaxMB) << 20,
+
+		ServiceName:      serviceName,
+		TracesExporter:   os.Getenv("OTEL_TRACES_EXPORTER"),
+		OTLPEndpoint:     otlpEndpoint,
+		TracesFile:       os.Getenv("OTEL_TRACES_FILE"),
+		TraceSampleRatio: traceSampleRatio,
+	}, nil
 }
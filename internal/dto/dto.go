@@ -1,5 +1,7 @@
 package dto
 
+import "encoding/json"
+
 // ##############  inputs ##############
 
 type AuthenticationInput struct {
@@ -30,9 +32,20 @@ type AddCommentInput struct {
 	Comment struct {
 		Body      string `json:"body"`
 		ArticleID string `json:"article_id"`
+		ParentID  string `json:"parentId"`
+	} `json:"comment"`
+}
+
+type UpdateCommentInput struct {
+	Comment struct {
+		Body string `json:"body"`
 	} `json:"comment"`
 }
 
+type PreviewInput struct {
+	Body string `json:"body"`
+}
+
 type AuthenticationOutput struct {
 	Email    string `json:"email"`
 	Token    string `json:"token"`
@@ -58,6 +71,11 @@ type ArticleInput struct {
 		Description string   `json:"description"`
 		Body        string   `json:"body"`
 		TagList     []string `json:"tag_list"`
+
+		CanonicalURL   string `json:"canonical_url"`
+		CoverImage     string `json:"cover_image"`
+		License        string `json:"license"`
+		SEODescription string `json:"seo_description"`
 	} `json:"article"`
 }
 
@@ -66,6 +84,11 @@ type ArticleUpdateInput struct {
 		Title       string `json:"title"`
 		Description string `json:"description"`
 		Body        string `json:"body"`
+
+		CanonicalURL   string `json:"canonical_url"`
+		CoverImage     string `json:"cover_image"`
+		License        string `json:"license"`
+		SEODescription string `json:"seo_description"`
 	} `json:"article"`
 }
 
@@ -90,21 +113,140 @@ type AuthorOutput struct {
 }
 
 type Comment struct {
-	ID        int          `json:"id"`
+	ID        string       `json:"id"`
+	ParentID  string       `json:"parentId,omitempty"`
+	Depth     int          `json:"depth"`
+	Deleted   bool         `json:"deleted"`
 	CreatedAt string       `json:"createdAt"`
 	UpdatedAt string       `json:"updatedAt"`
 	Body      string       `json:"body"`
 	Author    AuthorOutput `json:"author"`
+	Replies   []Comment    `json:"replies,omitempty"`
 }
 
 type AllCommentsOutput struct {
-	Comments []Comment `json:"comments"`
+	Comments      []Comment `json:"comments"`
+	CommentsCount int       `json:"commentsCount"`
+	NextCursor    string    `json:"nextCursor,omitempty"`
 }
 
 type AllTagsOutput struct {
 	Tags []string `json:"tags"`
 }
 
+type PreviewOutput struct {
+	HTML string `json:"html"`
+}
+
 type GetJWTOutput struct {
 	AccessToken string `json:"access_token"`
 }
+
+type AdminUserOutput struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Bio      string `json:"bio"`
+	Image    string `json:"image"`
+	Role     string `json:"role"`
+	Banned   bool   `json:"banned"`
+}
+
+type AdminUsersOutput struct {
+	Users      []AdminUserOutput `json:"users"`
+	UsersCount int               `json:"usersCount"`
+}
+
+type NotificationActor struct {
+	Username string `json:"username"`
+	Image    string `json:"image"`
+}
+
+type NotificationArticle struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+}
+
+type NotificationOutput struct {
+	ID          string               `json:"id"`
+	Type        string               `json:"type"`
+	Article     *NotificationArticle `json:"article"`
+	Actors      []NotificationActor  `json:"actors"`
+	ActorsCount int                  `json:"actorsCount"`
+	Count       int                  `json:"count"`
+	Read        bool                 `json:"read"`
+	CreatedAt   string               `json:"createdAt"`
+	UpdatedAt   string               `json:"updatedAt"`
+}
+
+type NotificationsOutput struct {
+	Notifications      []NotificationOutput `json:"notifications"`
+	NotificationsCount int                  `json:"notificationsCount"`
+	UnreadCount        int                  `json:"unreadCount"`
+}
+
+type NotificationPreferences struct {
+	Preferences map[string]bool `json:"preferences"`
+}
+
+type WebhookInput struct {
+	Webhook struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Global bool     `json:"global"`
+	} `json:"webhook"`
+}
+
+type WebhookOutput struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Global    bool     `json:"global"`
+	Secret    string   `json:"secret,omitempty"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+type WebhooksOutput struct {
+	Webhooks      []WebhookOutput `json:"webhooks"`
+	WebhooksCount int             `json:"webhooksCount"`
+}
+
+type DeliveryOutput struct {
+	ID             string          `json:"id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"responseStatus"`
+	Error          string          `json:"error"`
+	NextAttemptAt  string          `json:"nextAttemptAt"`
+	CreatedAt      string          `json:"createdAt"`
+	UpdatedAt      string          `json:"updatedAt"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+type DeliveriesOutput struct {
+	Deliveries      []DeliveryOutput `json:"deliveries"`
+	DeliveriesCount int              `json:"deliveriesCount"`
+}
+
+type ExportOutput struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	Size        int    `json:"size"`
+	Error       string `json:"error,omitempty"`
+	DownloadURL string `json:"downloadUrl,omitempty"`
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+type DeleteAccountInput struct {
+	User struct {
+		Password string `json:"password"`
+		Content  string `json:"content"`
+	} `json:"user"`
+}
+
+type ImageUploadOutput struct {
+	URL      string            `json:"url"`
+	Variants map[string]string `json:"variants"`
+}
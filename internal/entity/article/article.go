@@ -1,29 +1,54 @@
 package entity
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gosimple/slug"
 	"github.com/sallescosta/conduit-api/pkg/entity"
+	"github.com/sallescosta/conduit-api/pkg/markdown"
 )
 
 type Article struct {
-	ID             entity.ID `json:"id"`
-	Slug           string    `json:"slug"`
-	Title          string    `json:"title"`
-	Description    string    `json:"description"`
-	Body           string    `json:"body,omitempty"`
-	TagList        []string  `json:"tag_list"`
-	Favorited      bool      `json:"favorited"`
-	FavoritesCount int       `json:"favorites_count"`
-	CreatedAt      string    `json:"created_at"`
-	UpdatedAt      string    `json:"updated_at"`
-	AuthorID       string    `json:"author_id"`
+	ID          entity.ID `json:"id"`
+	Slug        string    `json:"slug"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Body        string    `json:"body,omitempty"`
+	BodyHTML    string    `json:"bodyHtml,omitempty"`
+
+	WordCount       int                `json:"wordCount"`
+	ReadingMinutes  int                `json:"readingMinutes"`
+	Excerpt         string             `json:"excerpt"`
+	TableOfContents []markdown.Heading `json:"tableOfContents"`
+
+	// Optional publishing metadata used for SEO tags, see Meta.
+	CanonicalURL   string `json:"canonical_url,omitempty"`
+	CoverImage     string `json:"cover_image,omitempty"`
+	License        string `json:"license,omitempty"`
+	SEODescription string `json:"seo_description,omitempty"`
+
+	TagList        []string `json:"tag_list"`
+	Favorited      bool     `json:"favorited"`
+	FavoritesCount int      `json:"favorites_count"`
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
+	AuthorID       string   `json:"author_id"`
+
+	// Version goes up with every change to the stored row, so it tells
+	// apart edits made within the same second of UpdatedAt.
+	Version int `json:"version"`
 }
 
+const (
+	wordsPerMinute   = 200
+	excerptMaxLength = 200
+)
+
 type AllArticlesOutput struct {
 	Articles      []Article `json:"articles"`
 	ArticlesCount int       `json:"articlesCount"`
+	NextCursor    string    `json:"nextCursor,omitempty"`
 }
 
 func NewArticle(
@@ -36,7 +61,7 @@ func NewArticle(
 
 	slug := slug.Make(title)
 
-	return &Article{
+	article := &Article{
 		ID:             entity.NewID(),
 		Slug:           slug,
 		Title:          title,
@@ -48,5 +73,58 @@ func NewArticle(
 		AuthorID:       authorId,
 		CreatedAt:      time.Now().Format(time.RFC3339),
 		UpdatedAt:      time.Now().Format(time.RFC3339),
-	}, nil
+		Version:        1,
+	}
+
+	if err := article.RenderBody(); err != nil {
+		return nil, err
+	}
+	article.ComputeReadingMetadata()
+
+	return article, nil
+}
+
+// RenderBody refreshes the sanitized HTML kept next to the Markdown body.
+func (a *Article) RenderBody() error {
+	html, err := markdown.Render(a.Body)
+	if err != nil {
+		return err
+	}
+
+	a.BodyHTML = html
+	return nil
+}
+
+// ComputeReadingMetadata derives the word count, reading time, excerpt and
+// table of contents from the body. It must run again whenever Body or
+// Description change.
+func (a *Article) ComputeReadingMetadata() {
+	plain := markdown.PlainText(a.Body)
+	words := strings.Fields(plain)
+
+	a.WordCount = len(words)
+	a.ReadingMinutes = (a.WordCount + wordsPerMinute - 1) / wordsPerMinute
+	a.TableOfContents = markdown.Outline(a.Body)
+
+	if a.Description != "" {
+		a.Excerpt = a.Description
+		return
+	}
+	a.Excerpt = excerpt(words, excerptMaxLength)
+}
+
+func excerpt(words []string, maxLength int) string {
+	var sb strings.Builder
+
+	for _, word := range words {
+		if sb.Len()+len(word)+1 > maxLength {
+			return sb.String() + "…"
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(word)
+	}
+
+	return sb.String()
 }
@@ -1,8 +1,10 @@
 package entity
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/sallescosta/conduit-api/pkg/markdown"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,10 +25,106 @@ func TestNewArticle(t *testing.T) {
 	assert.Equal(t, "My title", article.Title)
 	assert.Equal(t, "My description", article.Description)
 	assert.Equal(t, "Body, body, body...", article.Body)
+	assert.Equal(t, "<p>Body, body, body...</p>\n", article.BodyHTML)
 	assert.Equal(t, []string{"Go", "Tailwind", "Templ", "HTMX"}, article.TagList)
 	assert.Equal(t, "my-title", article.Slug)
 	assert.Equal(t, false, article.Favorited)
 	assert.Equal(t, 0, article.FavoritesCount)
 	assert.NotEmpty(t, article.CreatedAt)
 	assert.NotEmpty(t, article.UpdatedAt)
+	assert.Equal(t, 1, article.Version)
+}
+
+func TestArticle_RenderBody(t *testing.T) {
+	article, err := NewArticle("author123", "My title", "My description", "old", tags)
+	assert.Nil(t, err)
+
+	article.Body = "## New *body*"
+	assert.Nil(t, article.RenderBody())
+	assert.Equal(t, "<h2 id=\"new-body\">New <em>body</em></h2>\n", article.BodyHTML)
+}
+
+func TestArticle_ComputeReadingMetadata(t *testing.T) {
+	body := "# Intro\n\n" + strings.Repeat("word ", 250) + "\n\n## Next steps\n"
+	article, err := NewArticle("author123", "My title", "", body, tags)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 253, article.WordCount)
+	assert.Equal(t, 2, article.ReadingMinutes)
+	assert.True(t, strings.HasPrefix(article.Excerpt, "Intro word word"))
+	assert.True(t, strings.HasSuffix(article.Excerpt, "…"))
+	assert.LessOrEqual(t, len(article.Excerpt), 200+len("…"))
+	assert.Equal(t, []markdown.Heading{
+		{Level: 1, Text: "Intro", Anchor: "intro"},
+		{Level: 2, Text: "Next steps", Anchor: "next-steps"},
+	}, article.TableOfContents)
+
+	article.Description = "Short description"
+	article.ComputeReadingMetadata()
+	assert.Equal(t, "Short description", article.Excerpt)
+}
+
+func TestArticle_ValidateMetadata(t *testing.T) {
+	article, err := NewArticle("author123", "My title", "", "Body", tags)
+	assert.Nil(t, err)
+	assert.Nil(t, article.ValidateMetadata())
+
+	article.CanonicalURL = "https://blog.example/my-title"
+	article.CoverImage = "http://cdn.example/cover.png"
+	assert.Nil(t, article.ValidateMetadata())
+
+	article.CoverImage = "/cover.png"
+	assert.Equal(t, ErrInvalidURL, article.ValidateMetadata())
+
+	article.CoverImage = ""
+	article.CanonicalURL = "javascript:alert(1)"
+	assert.Equal(t, ErrInvalidURL, article.ValidateMetadata())
+
+	article.CanonicalURL = ""
+	article.SEODescription = strings.Repeat("é", 301)
+	assert.Equal(t, ErrSEODescriptionTooLong, article.ValidateMetadata())
+}
+
+func TestArticle_Meta(t *testing.T) {
+	article, err := NewArticle("author123", "My title", "", "Some body text", []string{"go"})
+	assert.Nil(t, err)
+
+	meta := article.Meta("https://conduit.example/article/my-title", "jake")
+	assert.Equal(t, "https://conduit.example/article/my-title", meta.Canonical)
+	assert.Equal(t, "Some body text", meta.Description)
+	assert.Contains(t, meta.Tags, MetaTag{Property: "og:url", Content: "https://conduit.example/article/my-title"})
+	assert.Contains(t, meta.Tags, MetaTag{Property: "article:author", Content: "jake"})
+	assert.Contains(t, meta.Tags, MetaTag{Property: "article:tag", Content: "go"})
+	assert.Contains(t, meta.Tags, MetaTag{Name: "twitter:card", Content: "summary"})
+	assert.NotContains(t, meta.Tags, MetaTag{Property: "og:image"})
+
+	article.CanonicalURL = "https://blog.example/my-title"
+	article.CoverImage = "https://cdn.example/cover.png"
+	article.SEODescription = "Read this"
+	article.License = "CC-BY-4.0"
+
+	meta = article.Meta("https://conduit.example/article/my-title", "jake")
+	assert.Equal(t, "https://blog.example/my-title", meta.Canonical)
+	assert.Equal(t, "Read this", meta.Description)
+	assert.Equal(t, "CC-BY-4.0", meta.License)
+	assert.Contains(t, meta.Tags, MetaTag{Property: "og:image", Content: "https://cdn.example/cover.png"})
+	assert.Contains(t, meta.Tags, MetaTag{Name: "twitter:card", Content: "summary_large_image"})
+	assert.Contains(t, meta.Tags, MetaTag{Name: "twitter:description", Content: "Read this"})
+}
+
+func TestRevision(t *testing.T) {
+	article, err := NewArticle("author123", "My title", "My description", "First body", tags)
+	assert.Nil(t, err)
+
+	revision := NewRevision(article)
+	assert.Equal(t, article.ID.String(), revision.ArticleID)
+	assert.Equal(t, "First body", revision.Body)
+	assert.Equal(t, article.UpdatedAt, revision.CreatedAt)
+	assert.NotEmpty(t, revision.ReplacedAt)
+
+	article.CoverImage = "https://example.com/cover.jpg"
+	assert.False(t, revision.Replaced(article))
+
+	article.Body = "Second body"
+	assert.True(t, revision.Replaced(article))
 }
@@ -0,0 +1,108 @@
+package entity
+
+import (
+	"errors"
+	"net/url"
+	"unicode/utf8"
+)
+
+const (
+	SiteName = "Conduit"
+
+	seoDescriptionMaxLength = 300
+)
+
+var (
+	ErrInvalidURL            = errors.New("canonical url and cover image must be absolute http or https urls")
+	ErrSEODescriptionTooLong = errors.New("seo description is limited to 300 characters")
+)
+
+// MetaTag is one <meta> element: Open Graph tags use Property and Twitter
+// cards use Name.
+type MetaTag struct {
+	Property string `json:"property,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Meta is what a page showing the article puts in its <head>.
+type Meta struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Canonical   string    `json:"canonical"`
+	Image       string    `json:"image,omitempty"`
+	License     string    `json:"license,omitempty"`
+	Tags        []MetaTag `json:"tags"`
+}
+
+// ValidateMetadata checks the optional publishing fields.
+func (a *Article) ValidateMetadata() error {
+	for _, u := range []string{a.CanonicalURL, a.CoverImage} {
+		if u != "" && !absoluteURL(u) {
+			return ErrInvalidURL
+		}
+	}
+	if utf8.RuneCountInString(a.SEODescription) > seoDescriptionMaxLength {
+		return ErrSEODescriptionTooLong
+	}
+	return nil
+}
+
+// Meta computes the Open Graph and Twitter card tags of the article.
+// pageURL is where the site shows it, used unless a canonical URL was set;
+// the description falls back from the SEO description to the excerpt.
+func (a *Article) Meta(pageURL, author string) Meta {
+	meta := Meta{
+		Title:       a.Title,
+		Description: a.SEODescription,
+		Canonical:   a.CanonicalURL,
+		Image:       a.CoverImage,
+		License:     a.License,
+	}
+	if meta.Description == "" {
+		meta.Description = a.Excerpt
+	}
+	if meta.Canonical == "" {
+		meta.Canonical = pageURL
+	}
+
+	og := func(property, content string) {
+		if content != "" {
+			meta.Tags = append(meta.Tags, MetaTag{Property: property, Content: content})
+		}
+	}
+	twitter := func(name, content string) {
+		if content != "" {
+			meta.Tags = append(meta.Tags, MetaTag{Name: name, Content: content})
+		}
+	}
+
+	og("og:type", "article")
+	og("og:site_name", SiteName)
+	og("og:title", meta.Title)
+	og("og:description", meta.Description)
+	og("og:url", meta.Canonical)
+	og("og:image", meta.Image)
+	og("article:published_time", a.CreatedAt)
+	og("article:modified_time", a.UpdatedAt)
+	og("article:author", author)
+	for _, tag := range a.TagList {
+		og("article:tag", tag)
+	}
+
+	card := "summary"
+	if meta.Image != "" {
+		card = "summary_large_image"
+	}
+	twitter("twitter:card", card)
+	twitter("twitter:title", meta.Title)
+	twitter("twitter:description", meta.Description)
+	twitter("twitter:image", meta.Image)
+
+	return meta
+}
+
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
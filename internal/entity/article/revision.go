@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+// Revision is an earlier version of an article's content, saved when an edit
+// replaces it. CreatedAt is when that version was written and ReplacedAt when
+// the edit superseded it.
+type Revision struct {
+	ID          entity.ID `json:"id"`
+	ArticleID   string    `json:"article_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Body        string    `json:"body"`
+	CreatedAt   string    `json:"created_at"`
+	ReplacedAt  string    `json:"replaced_at"`
+}
+
+// NewRevision captures the current content of a, before it is edited.
+func NewRevision(a *Article) *Revision {
+	return &Revision{
+		ID:          entity.NewID(),
+		ArticleID:   a.ID.String(),
+		Title:       a.Title,
+		Description: a.Description,
+		Body:        a.Body,
+		CreatedAt:   a.UpdatedAt,
+		ReplacedAt:  time.Now().Format(time.RFC3339),
+	}
+}
+
+// Replaced reports whether the content of a differs from the revision, so
+// edits that only touch metadata such as the cover image do not add one.
+func (r *Revision) Replaced(a *Article) bool {
+	return r.Title != a.Title || r.Description != a.Description || r.Body != a.Body
+}
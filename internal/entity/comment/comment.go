@@ -1,18 +1,30 @@
 package entity
 
 import (
+	"errors"
 	"time"
 
 	"github.com/sallescosta/conduit-api/pkg/entity"
 )
 
+const DeletedPlaceholder = "[deleted]"
+
+var (
+	ErrMaxDepthReached = errors.New("max reply depth reached")
+	ErrCommentDeleted  = errors.New("comment was deleted")
+)
+
 type Comment struct {
-	ID        entity.ID `json:"id"`
-	Body      string    `json:"body"`
-	AuthorID  string    `json:"author_id"`
-	ArticleID string    `json:"article_id"`
-	CreatedAt string    `json:"created_at,omitempty"`
-	UpdatedAt string    `json:"updated_at,omitempty"`
+	ID        entity.ID  `json:"id"`
+	Body      string     `json:"body"`
+	AuthorID  string     `json:"author_id"`
+	ArticleID string     `json:"article_id"`
+	ParentID  string     `json:"parent_id,omitempty"`
+	Depth     int        `json:"depth"`
+	Deleted   bool       `json:"deleted"`
+	CreatedAt string     `json:"created_at,omitempty"`
+	UpdatedAt string     `json:"updated_at,omitempty"`
+	Replies   []*Comment `json:"replies,omitempty"`
 }
 
 type AllCommentsFromAnArticle struct {
@@ -30,3 +42,77 @@ func NewComment(body, authorId, articleId string) *Comment {
 		UpdatedAt: time.Now().Format(time.RFC3339),
 	}
 }
+
+// NewReply creates a comment nested under parent, refusing to go deeper than maxDepth.
+func NewReply(body, authorId string, parent *Comment, maxDepth int) (*Comment, error) {
+	if parent.Deleted {
+		return nil, ErrCommentDeleted
+	}
+
+	if parent.Depth+1 > maxDepth {
+		return nil, ErrMaxDepthReached
+	}
+
+	reply := NewComment(body, authorId, parent.ArticleID)
+	reply.ParentID = parent.ID.String()
+	reply.Depth = parent.Depth + 1
+
+	return reply, nil
+}
+
+func (c *Comment) Edit(body string) error {
+	if c.Deleted {
+		return ErrCommentDeleted
+	}
+
+	c.Body = body
+	c.UpdatedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+// MarkDeleted keeps the comment in place so its replies still have a parent.
+func (c *Comment) MarkDeleted() {
+	c.Body = DeletedPlaceholder
+	c.Deleted = true
+	c.UpdatedAt = time.Now().Format(time.RFC3339)
+}
+
+// BuildTree nests the comments under their parents. Comments whose parent is
+// missing from the list are kept at the root.
+func BuildTree(comments []Comment) []*Comment {
+	byID := make(map[string]*Comment, len(comments))
+	for i := range comments {
+		comments[i].Replies = nil
+		byID[comments[i].ID.String()] = &comments[i]
+	}
+
+	roots := []*Comment{}
+	for i := range comments {
+		c := &comments[i]
+		if parent, ok := byID[c.ParentID]; ok && c.ParentID != "" {
+			parent.Replies = append(parent.Replies, c)
+			continue
+		}
+		roots = append(roots, c)
+	}
+
+	return roots
+}
+
+// Flatten walks the tree depth first, so each reply comes right after its parent.
+func Flatten(tree []*Comment) []Comment {
+	flat := []Comment{}
+
+	var walk func(nodes []*Comment)
+	walk = func(nodes []*Comment) {
+		for _, c := range nodes {
+			item := *c
+			item.Replies = nil
+			flat = append(flat, item)
+			walk(c.Replies)
+		}
+	}
+	walk(tree)
+
+	return flat
+}
@@ -21,3 +21,55 @@ func TestNewComment(t *testing.T) {
 	assert.NotEmpty(t, comment.CreatedAt)
 	assert.NotEmpty(t, comment.UpdatedAt)
 }
+
+func TestNewReply(t *testing.T) {
+	parent := NewComment("parent", "author123", "article123")
+
+	reply, err := NewReply("reply", "author456", parent, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, parent.ID.String(), reply.ParentID)
+	assert.Equal(t, "article123", reply.ArticleID)
+	assert.Equal(t, 1, reply.Depth)
+
+	nested, err := NewReply("nested", "author123", reply, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, nested.Depth)
+
+	_, err = NewReply("too deep", "author123", nested, 2)
+	assert.ErrorIs(t, err, ErrMaxDepthReached)
+
+	parent.MarkDeleted()
+	_, err = NewReply("on deleted", "author123", parent, 2)
+	assert.ErrorIs(t, err, ErrCommentDeleted)
+}
+
+func TestComment_Edit(t *testing.T) {
+	comment := NewComment("body body", "author123", "article123")
+
+	assert.Nil(t, comment.Edit("new body"))
+	assert.Equal(t, "new body", comment.Body)
+
+	comment.MarkDeleted()
+	assert.Equal(t, DeletedPlaceholder, comment.Body)
+	assert.ErrorIs(t, comment.Edit("again"), ErrCommentDeleted)
+}
+
+func TestBuildTreeAndFlatten(t *testing.T) {
+	root := NewComment("root", "author123", "article123")
+	reply, _ := NewReply("reply", "author456", root, 5)
+	nested, _ := NewReply("nested", "author123", reply, 5)
+	other := NewComment("other", "author456", "article123")
+
+	tree := BuildTree([]Comment{*root, *other, *reply, *nested})
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "root", tree[0].Body)
+	assert.Len(t, tree[0].Replies, 1)
+	assert.Equal(t, "nested", tree[0].Replies[0].Replies[0].Body)
+
+	flat := Flatten(tree)
+	assert.Len(t, flat, 4)
+	assert.Equal(t, []string{"root", "reply", "nested", "other"},
+		[]string{flat[0].Body, flat[1].Body, flat[2].Body, flat[3].Body})
+	assert.Equal(t, 2, flat[2].Depth)
+	assert.Nil(t, flat[0].Replies)
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+// Export statuses. An export is pending until a worker builds its archive,
+// then ready until it expires, or failed.
+const (
+	StatusPending = "pending"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+	StatusExpired = "expired"
+)
+
+// Export is a copy of everything a user has stored, built in the background
+// as a ZIP archive. The archive is downloaded with Token, without a session,
+// until ExpiresAt.
+type Export struct {
+	ID        entity.ID `json:"id"`
+	UserID    string    `json:"user_id"`
+	Status    string    `json:"status"`
+	Token     string    `json:"-"`
+	Size      int       `json:"size"`
+	Error     string    `json:"error"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewExport(userID string) (*Export, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	return &Export{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		Status:    StatusPending,
+		Token:     hex.EncodeToString(token),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// MarkReady records an archive of size bytes built at now, downloadable for ttl.
+func (e *Export) MarkReady(size int, now time.Time, ttl time.Duration) {
+	e.Status = StatusReady
+	e.Size = size
+	e.Error = ""
+	e.ExpiresAt = now.Add(ttl)
+	e.UpdatedAt = now
+}
+
+func (e *Export) MarkFailed(err error, now time.Time) {
+	e.Status = StatusFailed
+	e.Error = err.Error()
+	e.UpdatedAt = now
+}
+
+// Expired reports whether a ready export can no longer be downloaded.
+func (e *Export) Expired(now time.Time) bool {
+	return e.Status == StatusExpired || (e.Status == StatusReady && !now.Before(e.ExpiresAt))
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExport(t *testing.T) {
+	e, err := NewExport("user-1")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "user-1", e.UserID)
+	assert.Equal(t, StatusPending, e.Status)
+	assert.Len(t, e.Token, 64)
+	assert.False(t, e.Expired(time.Now()))
+
+	other, err := NewExport("user-1")
+	assert.Nil(t, err)
+	assert.NotEqual(t, e.Token, other.Token)
+}
+
+func TestExport_MarkReady(t *testing.T) {
+	e, _ := NewExport("user-1")
+	now := time.Now()
+
+	e.MarkReady(1024, now, time.Hour)
+
+	assert.Equal(t, StatusReady, e.Status)
+	assert.Equal(t, 1024, e.Size)
+	assert.Equal(t, now.Add(time.Hour), e.ExpiresAt)
+	assert.False(t, e.Expired(now.Add(59*time.Minute)))
+	assert.True(t, e.Expired(now.Add(time.Hour)))
+}
+
+func TestExport_MarkFailed(t *testing.T) {
+	e, _ := NewExport("user-1")
+
+	e.MarkFailed(errors.New("connection reset"), time.Now())
+
+	assert.Equal(t, StatusFailed, e.Status)
+	assert.Equal(t, "connection reset", e.Error)
+	assert.False(t, e.Expired(time.Now()))
+}
@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+// Notification types. Each one can be switched off in the preferences of the
+// recipient.
+const (
+	TypeFollow   = "follow"
+	TypeComment  = "comment"
+	TypeFavorite = "favorite"
+)
+
+var Types = []string{TypeFollow, TypeComment, TypeFavorite}
+
+var (
+	ErrInvalidType      = errors.New("invalid notification type")
+	ErrSelfNotification = errors.New("users are not notified of their own actions")
+)
+
+// Notification groups every unread event of the same type about the same
+// subject: following a user has no subject, comments and favorites have the
+// article. ActorIDs holds each actor once, the most recent first, and Count
+// the number of events.
+type Notification struct {
+	ID          entity.ID `json:"id"`
+	RecipientID string    `json:"recipient_id"`
+	Type        string    `json:"type"`
+	ArticleID   string    `json:"article_id,omitempty"`
+	ActorIDs    []string  `json:"actor_ids"`
+	Count       int       `json:"count"`
+	Read        bool      `json:"read"`
+	CreatedAt   string    `json:"created_at"`
+	UpdatedAt   string    `json:"updated_at"`
+}
+
+func NewNotification(recipientID, notificationType, actorID, articleID string) (*Notification, error) {
+	if !ValidType(notificationType) {
+		return nil, ErrInvalidType
+	}
+
+	if recipientID == actorID {
+		return nil, ErrSelfNotification
+	}
+
+	now := time.Now().Format(time.RFC3339)
+
+	return &Notification{
+		ID:          entity.NewID(),
+		RecipientID: recipientID,
+		Type:        notificationType,
+		ArticleID:   articleID,
+		ActorIDs:    []string{actorID},
+		Count:       1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+func ValidType(notificationType string) bool {
+	for _, t := range Types {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// Preferences tells, for each type, whether the user wants to receive it.
+type Preferences map[string]bool
+
+// DefaultPreferences turns every type on.
+func DefaultPreferences() Preferences {
+	prefs := Preferences{}
+	for _, t := range Types {
+		prefs[t] = true
+	}
+	return prefs
+}
+
+// Merge applies changes on top of p, refusing unknown types.
+func (p Preferences) Merge(changes Preferences) error {
+	for t := range changes {
+		if !ValidType(t) {
+			return ErrInvalidType
+		}
+	}
+	for t, enabled := range changes {
+		p[t] = enabled
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNotification(t *testing.T) {
+	n, err := NewNotification("author", TypeComment, "reader", "article")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, n.ID)
+	assert.Equal(t, "author", n.RecipientID)
+	assert.Equal(t, TypeComment, n.Type)
+	assert.Equal(t, "article", n.ArticleID)
+	assert.Equal(t, []string{"reader"}, n.ActorIDs)
+	assert.Equal(t, 1, n.Count)
+	assert.False(t, n.Read)
+}
+
+func TestNewNotification_Invalid(t *testing.T) {
+	_, err := NewNotification("author", "mention", "reader", "")
+	assert.ErrorIs(t, err, ErrInvalidType)
+
+	_, err = NewNotification("author", TypeFollow, "author", "")
+	assert.ErrorIs(t, err, ErrSelfNotification)
+}
+
+func TestPreferences_Merge(t *testing.T) {
+	prefs := DefaultPreferences()
+	assert.Equal(t, Preferences{TypeFollow: true, TypeComment: true, TypeFavorite: true}, prefs)
+
+	assert.Nil(t, prefs.Merge(Preferences{TypeFavorite: false}))
+	assert.False(t, prefs[TypeFavorite])
+	assert.True(t, prefs[TypeComment])
+
+	assert.ErrorIs(t, prefs.Merge(Preferences{TypeFollow: false, "mention": true}), ErrInvalidType)
+	assert.True(t, prefs[TypeFollow])
+}
@@ -1,10 +1,32 @@
 package entity
 
 import (
+	"net/url"
+	"strings"
+
 	"github.com/sallescosta/conduit-api/pkg/entity"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// What happens to the articles and comments of a deleted account: they are
+// deleted with it, or kept and credited to the deleted-user placeholder.
+const (
+	DeleteContent    = "delete"
+	AnonymizeContent = "anonymize"
+)
+
+// The placeholder account anonymized content belongs to. It has no email or
+// password and is banned, so nobody can sign in as it.
+const (
+	DeletedUserID   = "00000000-0000-0000-0000-000000000000"
+	DeletedUserName = "deleted-user"
+)
+
 type User struct {
 	ID        entity.ID   `json:"id"`
 	UserName  string      `json:"username"`
@@ -14,6 +36,8 @@ type User struct {
 	Image     string      `json:"image"`
 	Following []entity.ID `json:"following"`
 	Favorites []entity.ID `json:"favorites"`
+	Role      string      `json:"role"`
+	Banned    bool        `json:"banned"`
 }
 
 func DoHash(password string) (hash []byte, err error) {
@@ -39,6 +63,7 @@ func NewUser(username, email, password string) (*User, error) {
 		Image:     "",
 		Following: []entity.ID{},
 		Favorites: []entity.ID{},
+		Role:      RoleUser,
 	}, nil
 }
 
@@ -46,3 +71,26 @@ func (u *User) ValidatePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// ValidRole reports whether role is one the API knows about.
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// ValidContentMode reports whether mode is DeleteContent or AnonymizeContent.
+func ValidContentMode(mode string) bool {
+	return mode == DeleteContent || mode == AnonymizeContent
+}
+
+// AvatarURL is the image clients should show for a user: image when they
+// set one, or else the identicon served for their username on siteURL.
+func AvatarURL(siteURL, username, image string) string {
+	if image != "" {
+		return image
+	}
+	return strings.TrimRight(siteURL, "/") + "/api/profiles/" + url.PathEscape(username) + "/avatar.svg"
+}
@@ -14,6 +14,9 @@ func TestNewUser(t *testing.T) {
 	assert.NotEmpty(t, user.Password)
 	assert.Equal(t, "User Test", user.UserName)
 	assert.Equal(t, "user@test.com", user.Email)
+	assert.Equal(t, RoleUser, user.Role)
+	assert.False(t, user.IsAdmin())
+	assert.False(t, user.Banned)
 }
 
 func TestUser_ValidatePassword(t *testing.T) {
@@ -23,3 +26,21 @@ func TestUser_ValidatePassword(t *testing.T) {
 	assert.False(t, user.ValidatePassword("1234567"))
 	assert.NotEqual(t, "123456", user.Password)
 }
+
+func TestValidRole(t *testing.T) {
+	assert.True(t, ValidRole(RoleUser))
+	assert.True(t, ValidRole(RoleAdmin))
+	assert.False(t, ValidRole("root"))
+}
+
+func TestValidContentMode(t *testing.T) {
+	assert.True(t, ValidContentMode(DeleteContent))
+	assert.True(t, ValidContentMode(AnonymizeContent))
+	assert.False(t, ValidContentMode(""))
+}
+
+func TestAvatarURL(t *testing.T) {
+	assert.Equal(t, "https://cdn.example/me.png", AvatarURL("http://localhost:8000", "ana", "https://cdn.example/me.png"))
+	assert.Equal(t, "http://localhost:8000/api/profiles/ana/avatar.svg", AvatarURL("http://localhost:8000/", "ana", ""))
+	assert.Equal(t, "http://localhost:8000/api/profiles/ana%20s/avatar.svg", AvatarURL("http://localhost:8000", "ana s", ""))
+}
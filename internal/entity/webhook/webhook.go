@@ -0,0 +1,168 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+// Events a webhook can subscribe to.
+const (
+	EventArticlePublished = "article.published"
+	EventArticleUpdated   = "article.updated"
+	EventArticleDeleted   = "article.deleted"
+	EventCommentCreated   = "comment.created"
+	EventUserFollowed     = "user.followed"
+)
+
+var Events = []string{EventArticlePublished, EventArticleUpdated, EventArticleDeleted, EventCommentCreated, EventUserFollowed}
+
+var (
+	ErrInvalidURL   = errors.New("webhook url must be an absolute http or https url")
+	ErrNoEvents     = errors.New("webhook must subscribe to at least one event")
+	ErrInvalidEvent = errors.New("unknown webhook event")
+)
+
+// Webhook receives the events that concern its owner: their articles,
+// comments on their articles and their new followers. Global webhooks,
+// which only admins can register, receive every event.
+type Webhook struct {
+	ID        entity.ID `json:"id"`
+	OwnerID   string    `json:"owner_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Global    bool      `json:"global"`
+	CreatedAt string    `json:"created_at"`
+}
+
+func NewWebhook(ownerID, rawURL string, events []string, global bool) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	if len(events) == 0 {
+		return nil, ErrNoEvents
+	}
+	for _, event := range events {
+		if !ValidEvent(event) {
+			return nil, ErrInvalidEvent
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &Webhook{
+		ID:        entity.NewID(),
+		OwnerID:   ownerID,
+		URL:       u.String(),
+		Secret:    hex.EncodeToString(secret),
+		Events:    events,
+		Global:    global,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// MaxAttempts is how many times a delivery is tried before it is given up.
+const MaxAttempts = 8
+
+const (
+	firstBackoff = 10 * time.Second
+	maxBackoff   = time.Hour
+)
+
+// Delivery is one attempt history of sending a payload to a webhook. The
+// payload is stored as sent, so a redelivery is signed over the same bytes.
+type Delivery struct {
+	ID             entity.ID `json:"id"`
+	WebhookID      string    `json:"webhook_id"`
+	Event          string    `json:"event"`
+	Payload        []byte    `json:"payload"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	ResponseStatus int       `json:"response_status"`
+	Error          string    `json:"error"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func NewDelivery(webhookID, event string, payload []byte) *Delivery {
+	now := time.Now().UTC()
+
+	return &Delivery{
+		ID:            entity.NewID(),
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       payload,
+		Status:        DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Backoff is the wait after the given failed attempt: ten seconds after the
+// first one, doubling up to an hour.
+func Backoff(attempt int) time.Duration {
+	backoff := firstBackoff
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// RecordAttempt stores the outcome of an attempt made at now. A 2xx status
+// without error succeeds; anything else is retried after Backoff until
+// MaxAttempts is reached.
+func (d *Delivery) RecordAttempt(status int, err error, now time.Time) {
+	d.Attempts++
+	d.ResponseStatus = status
+	d.UpdatedAt = now
+
+	if err == nil && status >= 200 && status < 300 {
+		d.Status = DeliverySucceeded
+		d.Error = ""
+		return
+	}
+
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	if d.Attempts >= MaxAttempts {
+		d.Status = DeliveryFailed
+		return
+	}
+	d.NextAttemptAt = now.Add(Backoff(d.Attempts))
+}
+
+// Redeliver queues the same payload again as a new delivery, leaving this
+// one in the log as it is.
+func (d *Delivery) Redeliver() *Delivery {
+	return NewDelivery(d.WebhookID, d.Event, d.Payload)
+}
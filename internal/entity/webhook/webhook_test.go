@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWebhook(t *testing.T) {
+	hook, err := NewWebhook("owner", "https://chat.example.com/hooks/1", []string{EventArticlePublished}, false)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, hook.ID)
+	assert.Equal(t, "owner", hook.OwnerID)
+	assert.Len(t, hook.Secret, 64)
+	assert.False(t, hook.Global)
+
+	other, _ := NewWebhook("owner", "http://localhost:9000", Events, true)
+	assert.NotEqual(t, hook.Secret, other.Secret)
+}
+
+func TestNewWebhook_Invalid(t *testing.T) {
+	_, err := NewWebhook("owner", "ftp://example.com", Events, false)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+
+	_, err = NewWebhook("owner", "/relative", Events, false)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+
+	_, err = NewWebhook("owner", "https://example.com", nil, false)
+	assert.ErrorIs(t, err, ErrNoEvents)
+
+	_, err = NewWebhook("owner", "https://example.com", []string{"article.liked"}, false)
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, Backoff(1))
+	assert.Equal(t, 20*time.Second, Backoff(2))
+	assert.Equal(t, 80*time.Second, Backoff(4))
+	assert.Equal(t, time.Hour, Backoff(20))
+}
+
+func TestDelivery_RecordAttempt(t *testing.T) {
+	now := time.Now()
+	d := NewDelivery("hook", EventCommentCreated, []byte(`{}`))
+	assert.Equal(t, DeliveryPending, d.Status)
+
+	d.RecordAttempt(0, errors.New("connection refused"), now)
+	assert.Equal(t, DeliveryPending, d.Status)
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, "connection refused", d.Error)
+	assert.Equal(t, now.Add(10*time.Second), d.NextAttemptAt)
+
+	d.RecordAttempt(500, nil, now)
+	assert.Equal(t, now.Add(20*time.Second), d.NextAttemptAt)
+	assert.Equal(t, 500, d.ResponseStatus)
+
+	d.RecordAttempt(204, nil, now)
+	assert.Equal(t, DeliverySucceeded, d.Status)
+	assert.Empty(t, d.Error)
+}
+
+func TestDelivery_GivesUp(t *testing.T) {
+	d := NewDelivery("hook", EventUserFollowed, []byte(`{}`))
+	for i := 0; i < MaxAttempts; i++ {
+		d.RecordAttempt(503, nil, time.Now())
+	}
+	assert.Equal(t, DeliveryFailed, d.Status)
+	assert.Equal(t, MaxAttempts, d.Attempts)
+
+	again := d.Redeliver()
+	assert.NotEqual(t, d.ID, again.ID)
+	assert.Equal(t, DeliveryPending, again.Status)
+	assert.Equal(t, 0, again.Attempts)
+	assert.Equal(t, d.Payload, again.Payload)
+}
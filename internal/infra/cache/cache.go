@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Cache stores serialized values under string keys. Implementations must be
+// safe for concurrent use.
+type Cache interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(keys ...string) error
+	Stats() Stats
+}
+
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Sets      uint64 `json:"sets"`
+	Deletes   uint64 `json:"deletes"`
+	Evictions uint64 `json:"evictions"`
+	Errors    uint64 `json:"errors"`
+	Size      int    `json:"size"`
+}
+
+type counters struct {
+	hits, misses, sets, deletes, evictions, errors atomic.Uint64
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Sets:      c.sets.Load(),
+		Deletes:   c.deletes.Load(),
+		Evictions: c.evictions.Load(),
+		Errors:    c.errors.Load(),
+	}
+}
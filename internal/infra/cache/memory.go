@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// Memory is an in-process LRU cache whose entries also expire after their TTL.
+type Memory struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+	counters
+}
+
+func NewMemory(capacity int) *Memory {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+
+	return &Memory{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		m.misses.Add(1)
+		return nil, false, nil
+	}
+
+	e := el.Value.(*entry)
+	if !e.expiresAt.IsZero() && m.now().After(e.expiresAt) {
+		m.removeElement(el)
+		m.misses.Add(1)
+		return nil, false, nil
+	}
+
+	m.order.MoveToFront(el)
+	m.hits.Add(1)
+	return e.value, true, nil
+}
+
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = m.now().Add(ttl)
+	}
+
+	m.sets.Add(1)
+
+	if el, ok := m.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value = value
+		e.expiresAt = expiresAt
+		m.order.MoveToFront(el)
+		return nil
+	}
+
+	m.items[key] = m.order.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+
+	for m.order.Len() > m.capacity {
+		m.removeElement(m.order.Back())
+		m.evictions.Add(1)
+	}
+
+	return nil
+}
+
+func (m *Memory) Delete(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := m.items[key]; ok {
+			m.removeElement(el)
+			m.deletes.Add(1)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.snapshot()
+	stats.Size = m.order.Len()
+	return stats
+}
+
+func (m *Memory) removeElement(el *list.Element) {
+	m.order.Remove(el)
+	delete(m.items, el.Value.(*entry).key)
+}
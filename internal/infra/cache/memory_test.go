@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory_GetSetDelete(t *testing.T) {
+	m := NewMemory(10)
+
+	_, ok, err := m.Get("a")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, m.Set("a", []byte("1"), 0))
+	value, ok, _ := m.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	assert.Nil(t, m.Delete("a"))
+	_, ok, _ = m.Get("a")
+	assert.False(t, ok)
+
+	stats := m.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Deletes)
+	assert.Equal(t, 0, stats.Size)
+}
+
+func TestMemory_EvictsLeastRecentlyUsed(t *testing.T) {
+	m := NewMemory(2)
+
+	m.Set("a", []byte("1"), 0)
+	m.Set("b", []byte("2"), 0)
+	m.Get("a")
+	m.Set("c", []byte("3"), 0)
+
+	_, ok, _ := m.Get("b")
+	assert.False(t, ok)
+	_, ok, _ = m.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), m.Stats().Evictions)
+}
+
+func TestMemory_Expires(t *testing.T) {
+	now := time.Now()
+	m := NewMemory(2)
+	m.now = func() time.Time { return now }
+
+	m.Set("a", []byte("1"), time.Minute)
+	_, ok, _ := m.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok, _ = m.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, m.Stats().Size)
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis stores entries in a Redis compatible server through go-redis. The
+// Cache interface carries no context, so every call is bounded by the
+// client's dial, read and write timeouts instead.
+type Redis struct {
+	client *redis.Client
+	counters
+}
+
+// NewRedis connects lazily to addr; a poolSize of 0 keeps the go-redis
+// default.
+func NewRedis(addr string, poolSize int) *Redis {
+	return &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:         addr,
+			PoolSize:     poolSize,
+			DialTimeout:  2 * time.Second,
+			ReadTimeout:  2 * time.Second,
+			WriteTimeout: 2 * time.Second,
+		}),
+	}
+}
+
+func (c *Redis) Get(key string) ([]byte, bool, error) {
+	value, err := c.client.Get(context.Background(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		c.misses.Add(1)
+		return nil, false, nil
+	}
+	if err != nil {
+		c.errors.Add(1)
+		return nil, false, err
+	}
+
+	c.hits.Add(1)
+	return value, true, nil
+}
+
+func (c *Redis) Set(key string, value []byte, ttl time.Duration) error {
+	if err := c.client.Set(context.Background(), key, value, ttl).Err(); err != nil {
+		c.errors.Add(1)
+		return err
+	}
+
+	c.sets.Add(1)
+	return nil
+}
+
+func (c *Redis) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	n, err := c.client.Del(context.Background(), keys...).Result()
+	if err != nil {
+		c.errors.Add(1)
+		return err
+	}
+
+	c.deletes.Add(uint64(n))
+	return nil
+}
+
+func (c *Redis) Stats() Stats {
+	stats := c.snapshot()
+
+	if n, err := c.client.DBSize(context.Background()).Result(); err == nil {
+		stats.Size = int(n)
+	}
+
+	return stats
+}
+
+// Ping checks the server answers, for readiness probes.
+func (c *Redis) Ping() error {
+	return c.client.Ping(context.Background()).Err()
+}
+
+func (c *Redis) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := NewRedis(server.Addr(), 2)
+	defer c.Close()
+
+	assert.Nil(t, c.Ping())
+
+	_, ok, err := c.Get("missing")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, c.Set("article:slug:a", []byte("{\"title\":\"A\"}\r\nwith newline"), 90*time.Second))
+	assert.Equal(t, 90*time.Second, server.TTL("article:slug:a"))
+
+	value, ok, err := c.Get("article:slug:a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "{\"title\":\"A\"}\r\nwith newline", string(value))
+
+	assert.Nil(t, c.Delete("article:slug:a", "missing"))
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Deletes)
+	assert.Equal(t, 0, stats.Size)
+}
+
+func TestRedis_Unreachable(t *testing.T) {
+	c := NewRedis("127.0.0.1:1", 1)
+	defer c.Close()
+
+	_, ok, err := c.Get("a")
+	assert.NotNil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), c.Stats().Errors)
+}
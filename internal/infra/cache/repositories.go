@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sallescosta/conduit-api/internal/dto"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+const tagsKey = "tags:all"
+
+func articleKey(slug string) string     { return "article:slug:" + slug }
+func profileKey(username string) string { return "profile:" + username }
+func userKey(id string) string          { return "user:id:" + id }
+
+// load returns the cached value for key, or calls fetch and stores its
+// result. Cache failures only cost a trip to the database.
+func load[T any](c Cache, key string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	if raw, ok, err := c.Get(key); err == nil && ok {
+		var value T
+		if err := json.Unmarshal(raw, &value); err == nil {
+			return value, nil
+		}
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+
+	if raw, err := json.Marshal(value); err == nil {
+		c.Set(key, raw, ttl)
+	}
+
+	return value, nil
+}
+
+type ArticleRepository struct {
+	database.ArticleInterface
+	Cache Cache
+	TTL   time.Duration
+}
+
+func NewArticleRepository(inner database.ArticleInterface, c Cache, ttl time.Duration) *ArticleRepository {
+	return &ArticleRepository{ArticleInterface: inner, Cache: c, TTL: ttl}
+}
+
+func (a *ArticleRepository) GetArticleBySlug(ctx context.Context, slug string) (*articleEntity.Article, error) {
+	return load(a.Cache, articleKey(slug), a.TTL, func() (*articleEntity.Article, error) {
+		return a.ArticleInterface.GetArticleBySlug(ctx, slug)
+	})
+}
+
+func (a *ArticleRepository) UpdateArticle(ctx context.Context, slug string, article dto.ArticleUpdateInput) (*articleEntity.Article, error) {
+	defer a.Cache.Delete(articleKey(slug))
+	return a.ArticleInterface.UpdateArticle(ctx, slug, article)
+}
+
+// DeleteArticleDB drops the article and the users whose favorites lost it.
+func (a *ArticleRepository) DeleteArticleDB(ctx context.Context, slug string) ([]string, error) {
+	defer a.Cache.Delete(articleKey(slug))
+
+	users, err := a.ArticleInterface.DeleteArticleDB(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, user := range users {
+		keys = append(keys, userKey(user))
+	}
+	a.Cache.Delete(keys...)
+
+	return users, nil
+}
+
+type TagRepository struct {
+	database.TagsInterface
+	Cache Cache
+	TTL   time.Duration
+}
+
+func NewTagRepository(inner database.TagsInterface, c Cache, ttl time.Duration) *TagRepository {
+	return &TagRepository{TagsInterface: inner, Cache: c, TTL: ttl}
+}
+
+func (t *TagRepository) ListTags(ctx context.Context) ([]*tagEntity.Tag, error) {
+	return load(t.Cache, tagsKey, t.TTL, func() ([]*tagEntity.Tag, error) {
+		return t.TagsInterface.ListTags(ctx)
+	})
+}
+
+func (t *TagRepository) CreateTag(ctx context.Context, tags []*tagEntity.Tag) error {
+	defer t.Cache.Delete(tagsKey)
+	return t.TagsInterface.CreateTag(ctx, tags)
+}
+
+func (t *TagRepository) RenameTag(ctx context.Context, from, to string) ([]string, error) {
+	slugs, err := t.TagsInterface.RenameTag(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{tagsKey}
+	for _, slug := range slugs {
+		keys = append(keys, articleKey(slug))
+	}
+	t.Cache.Delete(keys...)
+
+	return slugs, nil
+}
+
+type UserRepository struct {
+	database.UserInterface
+	Cache Cache
+	TTL   time.Duration
+}
+
+func NewUserRepository(inner database.UserInterface, c Cache, ttl time.Duration) *UserRepository {
+	return &UserRepository{UserInterface: inner, Cache: c, TTL: ttl}
+}
+
+// FindById caches the user as its JSON, which leaves out the password
+// hash: credentials are never cached, so password checks must read the user
+// from the database with FindByEmail.
+func (u *UserRepository) FindById(ctx context.Context, id string) (*userEntity.User, error) {
+	return load(u.Cache, userKey(id), u.TTL, func() (*userEntity.User, error) {
+		return u.UserInterface.FindById(ctx, id)
+	})
+}
+
+func (u *UserRepository) GetProfileDb(ctx context.Context, userName string) (*database.ProfileWithId, error) {
+	return load(u.Cache, profileKey(userName), u.TTL, func() (*database.ProfileWithId, error) {
+		return u.UserInterface.GetProfileDb(ctx, userName)
+	})
+}
+
+func (u *UserRepository) UpdateUserDb(ctx context.Context, id, username, password, image, bio string) (*userEntity.User, error) {
+	if previous, err := u.UserInterface.FindById(ctx, id); err == nil && previous != nil {
+		defer u.Cache.Delete(profileKey(previous.UserName))
+	}
+	defer u.Cache.Delete(userKey(id))
+
+	user, err := u.UserInterface.UpdateUserDb(ctx, id, username, password, image, bio)
+	if user != nil {
+		u.Cache.Delete(profileKey(user.UserName))
+	}
+	return user, err
+}
+
+func (u *UserRepository) SetBanned(ctx context.Context, id string, banned bool) error {
+	defer u.Cache.Delete(userKey(id))
+	return u.UserInterface.SetBanned(ctx, id, banned)
+}
+
+func (u *UserRepository) SetRole(ctx context.Context, id string, role string) error {
+	defer u.Cache.Delete(userKey(id))
+	return u.UserInterface.SetRole(ctx, id, role)
+}
+
+func (u *UserRepository) UpdateFollowingUserDb(ctx context.Context, id string, following []entity.ID) error {
+	defer u.Cache.Delete(userKey(id))
+	return u.UserInterface.UpdateFollowingUserDb(ctx, id, following)
+}
+
+func (u *UserRepository) FavoriteArticleDB(ctx context.Context, slug string, isAddToFavorite bool, userID string) error {
+	defer u.Cache.Delete(userKey(userID), articleKey(slug))
+	return u.UserInterface.FavoriteArticleDB(ctx, slug, isAddToFavorite, userID)
+}
+
+// DeleteUser drops the account and everything the deletion changed: the
+// users who followed or favorited, the articles and, when tags were
+// removed, the tag list.
+func (u *UserRepository) DeleteUser(ctx context.Context, id string, mode string) (*database.DeletedAccount, error) {
+	deleted, err := u.UserInterface.DeleteUser(ctx, id, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{userKey(id), profileKey(deleted.UserName)}
+	for _, user := range deleted.Users {
+		keys = append(keys, userKey(user))
+	}
+	for _, slug := range deleted.ArticleSlugs {
+		keys = append(keys, articleKey(slug))
+	}
+	if deleted.TagsChanged {
+		keys = append(keys, tagsKey)
+	}
+	u.Cache.Delete(keys...)
+
+	return deleted, nil
+}
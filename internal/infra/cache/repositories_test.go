@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sallescosta/conduit-api/internal/dto"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeArticles struct {
+	database.ArticleInterface
+	article    *articleEntity.Article
+	reads      int
+	favoriters []string
+}
+
+func (f *fakeArticles) GetArticleBySlug(ctx context.Context, slug string) (*articleEntity.Article, error) {
+	f.reads++
+	copied := *f.article
+	return &copied, nil
+}
+
+func (f *fakeArticles) UpdateArticle(ctx context.Context, slug string, input dto.ArticleUpdateInput) (*articleEntity.Article, error) {
+	f.article.Title = input.Article.Title
+	return f.article, nil
+}
+
+func TestArticleRepository_CachesAndInvalidates(t *testing.T) {
+	article, _ := articleEntity.NewArticle("author123", "My title", "", "body", nil)
+	inner := &fakeArticles{article: article}
+	repo := NewArticleRepository(inner, NewMemory(10), time.Minute)
+	ctx := context.Background()
+
+	first, err := repo.GetArticleBySlug(ctx, "my-title")
+	assert.Nil(t, err)
+	second, _ := repo.GetArticleBySlug(ctx, "my-title")
+	assert.Equal(t, 1, inner.reads)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, "My title", second.Title)
+
+	var input dto.ArticleUpdateInput
+	input.Article.Title = "Other title"
+	_, err = repo.UpdateArticle(ctx, "my-title", input)
+	assert.Nil(t, err)
+
+	updated, _ := repo.GetArticleBySlug(ctx, "my-title")
+	assert.Equal(t, 2, inner.reads)
+	assert.Equal(t, "Other title", updated.Title)
+}
+
+func (f *fakeArticles) DeleteArticleDB(ctx context.Context, slug string) ([]string, error) {
+	return f.favoriters, nil
+}
+
+func TestArticleRepository_DeleteInvalidatesFavoriters(t *testing.T) {
+	c := NewMemory(10)
+	for _, key := range []string{articleKey("hi"), userKey("anna-id"), userKey("kept-id")} {
+		require.NoError(t, c.Set(key, []byte("{}"), time.Minute))
+	}
+
+	repo := NewArticleRepository(&fakeArticles{favoriters: []string{"anna-id"}}, c, time.Minute)
+	users, err := repo.DeleteArticleDB(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anna-id"}, users)
+
+	for _, key := range []string{articleKey("hi"), userKey("anna-id")} {
+		_, ok, _ := c.Get(key)
+		assert.False(t, ok, key)
+	}
+	_, ok, _ := c.Get(userKey("kept-id"))
+	assert.True(t, ok)
+}
+
+type fakeUsers struct {
+	database.UserInterface
+	id      entity.ID
+	deleted *database.DeletedAccount
+}
+
+func (f *fakeUsers) FindById(ctx context.Context, id string) (*userEntity.User, error) {
+	return &userEntity.User{ID: f.id, UserName: "jake", Password: "$2a$10$hash"}, nil
+}
+
+func (f *fakeUsers) DeleteUser(ctx context.Context, id string, mode string) (*database.DeletedAccount, error) {
+	return f.deleted, nil
+}
+
+func TestUserRepository_DeleteUserInvalidates(t *testing.T) {
+	c := NewMemory(10)
+	for _, key := range []string{userKey("jake-id"), profileKey("jake"), userKey("anna-id"), articleKey("hi"), articleKey("kept"), tagsKey} {
+		require.NoError(t, c.Set(key, []byte("{}"), time.Minute))
+	}
+
+	repo := NewUserRepository(&fakeUsers{deleted: &database.DeletedAccount{
+		UserName:     "jake",
+		Users:        []string{"anna-id"},
+		ArticleSlugs: []string{"hi"},
+	}}, c, time.Minute)
+	_, err := repo.DeleteUser(context.Background(), "jake-id", "delete")
+	require.NoError(t, err)
+
+	for _, key := range []string{userKey("jake-id"), profileKey("jake"), userKey("anna-id"), articleKey("hi")} {
+		_, ok, _ := c.Get(key)
+		assert.False(t, ok, key)
+	}
+	for _, key := range []string{articleKey("kept"), tagsKey} {
+		_, ok, _ := c.Get(key)
+		assert.True(t, ok, key)
+	}
+}
+
+func TestUserRepository_DoesNotCachePassword(t *testing.T) {
+	c := NewMemory(10)
+	id := entity.NewID()
+	repo := NewUserRepository(&fakeUsers{id: id}, c, time.Minute)
+
+	_, err := repo.FindById(context.Background(), id.String())
+	require.NoError(t, err)
+
+	raw, ok, err := c.Get(userKey(id.String()))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.NotContains(t, string(raw), "$2a$10$hash")
+
+	cached, err := repo.FindById(context.Background(), id.String())
+	require.NoError(t, err)
+	assert.Equal(t, "jake", cached.UserName)
+	assert.Empty(t, cached.Password)
+}
@@ -1,13 +1,18 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
+	"time"
 
 	slugMaker "github.com/gosimple/slug"
 	"github.com/lib/pq"
 	"github.com/sallescosta/conduit-api/internal/dto"
+	"github.com/sallescosta/conduit-api/pkg/helpers"
 
 	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
 )
@@ -21,24 +26,100 @@ func CreateArticlesTable(db *sql.DB) error {
             title VARCHAR(255) NOT NULL,
             description TEXT,
             body TEXT,
+            body_html TEXT,
+            word_count INT DEFAULT 0,
+            reading_minutes INT DEFAULT 0,
+            excerpt TEXT,
+            table_of_contents JSONB,
             tag_list TEXT[],
             favorited BOOLEAN DEFAULT FALSE,
             favoritesCount INT DEFAULT 0,
             createdAt TIMESTAMP DEFAULT NOW(),
             updatedAt TIMESTAMP DEFAULT NOW(),
+            version INT NOT NULL DEFAULT 1,
             FOREIGN KEY (author_id) REFERENCES users (id)
         );
+
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS body_html TEXT;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS word_count INT DEFAULT 0;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS reading_minutes INT DEFAULT 0;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS excerpt TEXT;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS table_of_contents JSONB;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS canonical_url TEXT;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS cover_image TEXT;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS license TEXT;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS seo_description TEXT;
+        ALTER TABLE articles ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+        -- keyset pagination, alone or narrowed by author or followed authors;
+        -- the tag filter pages through article_tags and favorites are looked
+        -- up by primary key
+        CREATE INDEX IF NOT EXISTS articles_created_at_id_idx ON articles (createdAt, id);
+        CREATE INDEX IF NOT EXISTS articles_author_created_at_id_idx ON articles (author_id, createdAt, id);
+        DROP INDEX IF EXISTS articles_tag_list_idx;
     `
 	_, err := db.Exec(query)
 	if err != nil {
-		log.Fatal(err)
-		return err
+		return fmt.Errorf("error creating articles table: %w", err)
 	}
 
-	fmt.Println("articles table created")
+	slog.Info("table ready", slog.String("table", "articles"))
 	return nil
 }
 
+// CreateArticleRevisionsTable keeps the earlier versions of edited articles.
+// They go with their article when it is deleted.
+func CreateArticleRevisionsTable(db *sql.DB) error {
+	query := `
+        CREATE TABLE IF NOT EXISTS article_revisions (
+            id VARCHAR(255) PRIMARY KEY,
+            article_id VARCHAR(255) NOT NULL REFERENCES articles (id) ON DELETE CASCADE,
+            title VARCHAR(255) NOT NULL,
+            description TEXT,
+            body TEXT,
+            createdAt TIMESTAMP,
+            replacedAt TIMESTAMP DEFAULT NOW()
+        );
+
+        CREATE INDEX IF NOT EXISTS article_revisions_article_idx ON article_revisions (article_id, replacedAt);
+    `
+	_, err := db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("error creating article_revisions table: %w", err)
+	}
+
+	slog.Info("table ready", slog.String("table", "article_revisions"))
+	return nil
+}
+
+const articleColumns = `id, author_id, slug, title, description, body, COALESCE(body_html, ''), word_count,
+	reading_minutes, COALESCE(excerpt, ''), COALESCE(table_of_contents, '[]'), COALESCE(canonical_url, ''),
+	COALESCE(cover_image, ''), COALESCE(license, ''), COALESCE(seo_description, ''), favorited, favoritesCount, tag_list,
+	createdAt, updatedAt, version`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanArticle(row rowScanner) (*articleEntity.Article, error) {
+	article := &articleEntity.Article{}
+	var toc []byte
+
+	err := row.Scan(&article.ID, &article.AuthorID, &article.Slug, &article.Title, &article.Description,
+		&article.Body, &article.BodyHTML, &article.WordCount, &article.ReadingMinutes, &article.Excerpt, &toc,
+		&article.CanonicalURL, &article.CoverImage, &article.License, &article.SEODescription, &article.Favorited, &article.FavoritesCount, pq.Array(&article.TagList), &article.CreatedAt, &article.UpdatedAt,
+		&article.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(toc, &article.TableOfContents); err != nil {
+		return nil, fmt.Errorf("error decoding table of contents: %w", err)
+	}
+
+	return article, nil
+}
+
 type ArticleDB struct {
 	DB *sql.DB
 }
@@ -47,9 +128,9 @@ func NewArticle(db *sql.DB) *ArticleDB {
 	return &ArticleDB{DB: db}
 }
 
-func (a *ArticleDB) CreateArticle(article *articleEntity.Article) error {
+func (a *ArticleDB) CreateArticle(ctx context.Context, article *articleEntity.Article) error {
 	var exists bool
-	err := a.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM articles WHERE title = $1)", article.Title).Scan(&exists)
+	err := a.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM articles WHERE title = $1)", article.Title).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("error checking title existence: %w", err)
 	}
@@ -57,40 +138,58 @@ func (a *ArticleDB) CreateArticle(article *articleEntity.Article) error {
 		return fmt.Errorf("title already used")
 	}
 
-	stmt, err := a.DB.Prepare(`
+	stmt, err := a.DB.PrepareContext(ctx, `
 		INSERT INTO articles (
-			id, author_id, slug, title, description, body, favorited, favoritesCount, tag_list, createdAt, updatedAt
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+			id, author_id, slug, title, description, body, body_html, word_count, reading_minutes, excerpt,
+			table_of_contents, canonical_url, cover_image, license, seo_description, favorited, favoritesCount,
+			tag_list, createdAt, updatedAt, version
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21);
 	`)
 	if err != nil {
 		return fmt.Errorf("error preparing insert statement: %w", err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(
+	toc, err := json.Marshal(article.TableOfContents)
+	if err != nil {
+		return fmt.Errorf("error encoding table of contents: %w", err)
+	}
+
+	_, err = stmt.ExecContext(ctx,
 		article.ID,
 		article.AuthorID,
 		article.Slug,
 		article.Title,
 		article.Description,
 		article.Body,
+		article.BodyHTML,
+		article.WordCount,
+		article.ReadingMinutes,
+		article.Excerpt,
+		toc,
+		article.CanonicalURL,
+		article.CoverImage,
+		article.License,
+		article.SEODescription,
 		article.Favorited,
 		article.FavoritesCount,
 		pq.Array(article.TagList),
 		article.CreatedAt,
 		article.UpdatedAt,
+		article.Version,
 	)
 	if err != nil {
-		log.Printf("Error inserting article: %v\nArticle: %+v\n", err, article)
+		slog.ErrorContext(ctx, "error inserting article", slog.String("error", err.Error()),
+			slog.String("article_id", article.ID.String()), slog.String("slug", article.Slug))
 		return fmt.Errorf("error inserting article: %w", err)
 	}
 	return nil
 }
 
-func (a *ArticleDB) ListAllArticles() ([]articleEntity.Article, error) {
-	query := `SELECT id, author_id, slug, title, description, body, favorited, favoritesCount, tag_list, createdAt, updatedAt FROM articles ORDER BY createdAt ASC`
+func (a *ArticleDB) ListAllArticles(ctx context.Context) ([]articleEntity.Article, error) {
+	query := `SELECT ` + articleColumns + ` FROM articles ORDER BY createdAt ASC`
 
-	rows, err := a.DB.Query(query)
+	rows, err := a.DB.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -99,13 +198,11 @@ func (a *ArticleDB) ListAllArticles() ([]articleEntity.Article, error) {
 	var articles []articleEntity.Article
 
 	for rows.Next() {
-		var article articleEntity.Article
-		err := rows.Scan(&article.ID, &article.AuthorID, &article.Slug, &article.Title, &article.Description,
-			&article.Body, &article.Favorited, &article.FavoritesCount, pq.Array(&article.TagList), &article.CreatedAt, &article.UpdatedAt)
+		article, err := scanArticle(rows)
 		if err != nil {
 			return nil, err
 		}
-		articles = append(articles, article)
+		articles = append(articles, *article)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -115,107 +212,265 @@ func (a *ArticleDB) ListAllArticles() ([]articleEntity.Article, error) {
 	return articles, nil
 }
 
-func (a *ArticleDB) FeedArticles(limit, offset int, sort string) ([]articleEntity.Article, error) {
-	if sort != "asc" && sort != "desc" {
-		sort = "asc"
+// ArticleQuery filters and pages article lists. Offset pagination is kept
+// for the spec; Cursor continues after the last article of a previous page
+// and is stable while new articles are being published. FollowedBy is the ID
+// of a user whose followed authors the list is limited to.
+type ArticleQuery struct {
+	Tag         string
+	Author      string
+	FavoritedBy string
+	FollowedBy  string
+	Limit       int
+	Offset      int
+	Cursor      string
+	Sort        string
+}
+
+type ArticlesPage struct {
+	Articles   []articleEntity.Article
+	Total      int
+	NextCursor string
+}
+
+func (a *ArticleDB) QueryArticles(ctx context.Context, q ArticleQuery) (*ArticlesPage, error) {
+	if q.Sort != "asc" && q.Sort != "desc" {
+		q.Sort = "asc"
+	}
+	if q.Limit <= 0 {
+		q.Limit = 20
 	}
 
-	query := fmt.Sprintf(`SELECT id, author_id, slug, title, description, body, favorited, favoritesCount, tag_list, createdAt, updatedAt FROM articles ORDER BY createdAt %s LIMIT $1 OFFSET $2`, sort)
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
-	rows, err := a.DB.Query(query, limit, offset)
+	// a tag filter reads article_tags in the order of its index, which holds
+	// the creation date of each article
+	from, createdAtColumn, idColumn := "articles", "createdAt", "id"
+	if q.Tag != "" {
+		from = "article_tags JOIN articles ON articles.id = article_tags.article_id"
+		createdAtColumn, idColumn = "article_created_at", "article_id"
+		conditions = append(conditions, "tag_id = (SELECT id FROM tags WHERE name = "+arg(q.Tag)+")")
+	}
+	if q.Author != "" {
+		conditions = append(conditions, "author_id = (SELECT id FROM users WHERE username = "+arg(q.Author)+")")
+	}
+	if q.FavoritedBy != "" {
+		conditions = append(conditions,
+			"id = ANY(SELECT unnest(favorites) FROM users WHERE username = "+arg(q.FavoritedBy)+")")
+	}
+	if q.FollowedBy != "" {
+		conditions = append(conditions,
+			"author_id = ANY(SELECT unnest(following) FROM users WHERE id = "+arg(q.FollowedBy)+")")
+	}
+
+	filter := ""
+	if len(conditions) > 0 {
+		filter = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	page := &ArticlesPage{Articles: []articleEntity.Article{}}
+
+	err := a.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+from+" "+filter, args...).Scan(&page.Total)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var feedArticles []articleEntity.Article
+	if q.Cursor != "" {
+		createdAt, id, err := helpers.DecodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
 
-	for rows.Next() {
-		var article articleEntity.Article
+		cmp := ">"
+		if q.Sort == "desc" {
+			cmp = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, %s) %s (%s, %s)", createdAtColumn, idColumn, cmp,
+			arg(createdAt), arg(id)))
+		filter = "WHERE " + strings.Join(conditions, " AND ")
+	}
 
-		err := rows.Scan(&article.ID, &article.AuthorID, &article.Slug, &article.Title, &article.Description,
-			&article.Body, &article.Favorited, &article.FavoritesCount, pq.Array(&article.TagList), &article.CreatedAt, &article.UpdatedAt)
+	query := fmt.Sprintf(`SELECT %s FROM %s %s ORDER BY %s %s, %s %s LIMIT %s OFFSET %s`,
+		articleColumns, from, filter, createdAtColumn, q.Sort, idColumn, q.Sort, arg(q.Limit+1), arg(q.Offset))
+
+	rows, err := a.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		article, err := scanArticle(rows)
 		if err != nil {
-			fmt.Println("erro no scan", err)
 			return nil, err
 		}
 
-		feedArticles = append(feedArticles, article)
+		page.Articles = append(page.Articles, *article)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return feedArticles, nil
+	if len(page.Articles) > q.Limit {
+		page.Articles = page.Articles[:q.Limit]
+		last := page.Articles[q.Limit-1]
+
+		createdAt, err := time.Parse(time.RFC3339Nano, last.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error building cursor: %w", err)
+		}
+		page.NextCursor = helpers.EncodeCursor(createdAt, last.ID.String())
+	}
+
+	return page, nil
 }
 
-func (a *ArticleDB) GetArticleBySlug(slug string) (*articleEntity.Article, error) {
-	query := "SELECT id, author_id, slug, title, description, body, favorited, favoritesCount, tag_list, createdAt, updatedAt FROM articles WHERE slug = $1"
-	stmt, err := a.DB.Prepare(query)
+func (a *ArticleDB) GetArticleBySlug(ctx context.Context, slug string) (*articleEntity.Article, error) {
+	query := "SELECT " + articleColumns + " FROM articles WHERE slug = $1"
+	stmt, err := a.DB.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
 	defer stmt.Close()
 
-	article := &articleEntity.Article{}
-
-	err = stmt.QueryRow(slug).Scan(&article.ID, &article.AuthorID, &article.Slug, &article.Title, &article.Description,
-		&article.Body, &article.Favorited, &article.FavoritesCount, pq.Array(&article.TagList), &article.CreatedAt, &article.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return article, nil
+	return scanArticle(stmt.QueryRowContext(ctx, slug))
 }
 
-func (a *ArticleDB) UpdateArticle(slug string, article dto.ArticleUpdateInput) (*articleEntity.Article, error) {
-	articleToUpdate, err := a.GetArticleBySlug(slug)
+// UpdateArticle saves the edit and, when it changes the content, the version
+// it replaces as a revision, in one transaction.
+func (a *ArticleDB) UpdateArticle(ctx context.Context, slug string, article dto.ArticleUpdateInput) (*articleEntity.Article, error) {
+	articleToUpdate, err := a.GetArticleBySlug(ctx, slug)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if article.Article.Title != "" {
-		articleToUpdate.Title = article.Article.Title
-		articleToUpdate.Slug = slugMaker.Make(article.Article.Title)
+	revision := articleEntity.NewRevision(articleToUpdate)
+	if err := applyArticleUpdate(articleToUpdate, article); err != nil {
+		return nil, err
 	}
 
-	if article.Article.Description != "" {
-		articleToUpdate.Description = article.Article.Description
-	}
+	articleToUpdate.UpdatedAt = time.Now().Format(time.RFC3339)
 
-	if article.Article.Body != "" {
-		articleToUpdate.Body = article.Article.Body
+	toc, err := json.Marshal(articleToUpdate.TableOfContents)
+	if err != nil {
+		return nil, err
 	}
 
-	stmt, err := a.DB.Prepare("UPDATE articles SET title = $1, description = $2, body = $3,  favorited = $4 WHERE slug = $5")
+	tx, err := a.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.Exec(articleToUpdate.Title, articleToUpdate.Description, articleToUpdate.Body, articleToUpdate.Favorited, slug)
+	if revision.Replaced(articleToUpdate) {
+		_, err = tx.ExecContext(ctx, `INSERT INTO article_revisions (id, article_id, title, description, body, createdAt, replacedAt)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			revision.ID.String(), revision.ArticleID, revision.Title, revision.Description, revision.Body,
+			revision.CreatedAt, revision.ReplacedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `UPDATE articles SET title = $1, description = $2, body = $3, body_html = $4, word_count = $5,
+		reading_minutes = $6, excerpt = $7, table_of_contents = $8, canonical_url = $9, cover_image = $10, license = $11,
+		seo_description = $12, favorited = $13, updatedAt = $14, version = version + 1 WHERE slug = $15 RETURNING version`,
+		articleToUpdate.Title, articleToUpdate.Description, articleToUpdate.Body, articleToUpdate.BodyHTML,
+		articleToUpdate.WordCount, articleToUpdate.ReadingMinutes, articleToUpdate.Excerpt, toc,
+		articleToUpdate.CanonicalURL, articleToUpdate.CoverImage, articleToUpdate.License, articleToUpdate.SEODescription,
+		articleToUpdate.Favorited, articleToUpdate.UpdatedAt, slug).Scan(&articleToUpdate.Version)
 	if err != nil {
 		return nil, err
 	}
 
-	return articleToUpdate, nil
+	return articleToUpdate, tx.Commit()
+}
+
+// applyArticleUpdate copies the non-empty fields of input onto a and
+// recomputes what derives from them.
+func applyArticleUpdate(a *articleEntity.Article, input dto.ArticleUpdateInput) error {
+	if input.Article.Title != "" {
+		a.Title = input.Article.Title
+		a.Slug = slugMaker.Make(input.Article.Title)
+	}
+
+	if input.Article.Description != "" {
+		a.Description = input.Article.Description
+	}
+
+	if input.Article.CanonicalURL != "" {
+		a.CanonicalURL = input.Article.CanonicalURL
+	}
+	if input.Article.CoverImage != "" {
+		a.CoverImage = input.Article.CoverImage
+	}
+	if input.Article.License != "" {
+		a.License = input.Article.License
+	}
+	if input.Article.SEODescription != "" {
+		a.SEODescription = input.Article.SEODescription
+	}
+
+	if input.Article.Body != "" {
+		a.Body = input.Article.Body
+		if err := a.RenderBody(); err != nil {
+			return err
+		}
+	}
+
+	// after the body and description, which it is derived from
+	a.ComputeReadingMetadata()
+	return nil
 }
 
-func (a *ArticleDB) DeleteArticleDB(slug string) error {
-	article, err := a.GetArticleBySlug(slug)
+// DeleteArticleDB removes the article with its comments and notifications
+// and takes it out of every user's favorites, in one transaction. It returns
+// the ids of the users who had favorited it, so that caches can drop them.
+func (a *ArticleDB) DeleteArticleDB(ctx context.Context, slug string) ([]string, error) {
+	article, err := a.GetArticleBySlug(ctx, slug)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("article not found")
+			return nil, fmt.Errorf("article not found")
 		}
-		return fmt.Errorf("error checking article existence: %w", err)
+		return nil, fmt.Errorf("error checking article existence: %w", err)
 	}
 
-	query := "DELETE FROM articles WHERE slug = $1"
-	_, err = a.DB.Exec(query, article.Slug)
+	tx, err := a.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("error deleting article: %w", err)
+		return nil, fmt.Errorf("error deleting article: %w", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		"DELETE FROM comments WHERE article_id = $1",
+		"DELETE FROM notifications WHERE article_id = $1",
+	} {
+		if _, err := tx.ExecContext(ctx, query, article.ID.String()); err != nil {
+			return nil, fmt.Errorf("error deleting article: %w", err)
+		}
+	}
+
+	users, err := queryStrings(ctx, tx,
+		"UPDATE users SET favorites = array_remove(favorites, $1) WHERE $1 = ANY(favorites) RETURNING id",
+		article.ID.String())
+	if err != nil {
+		return nil, fmt.Errorf("error deleting article: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM articles WHERE id = $1", article.ID.String()); err != nil {
+		return nil, fmt.Errorf("error deleting article: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error deleting article: %w", err)
+	}
+	return users, nil
 }
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/sallescosta/conduit-api/internal/dto"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyArticleUpdate_Body(t *testing.T) {
+	article, err := articleEntity.NewArticle("author", "Title", "", "## Old\n\nold body", []string{})
+	require.NoError(t, err)
+	require.Equal(t, 3, article.WordCount)
+
+	var input dto.ArticleUpdateInput
+	input.Article.Body = "## First\n\none two three four\n\n## Second\n\nfive six"
+	require.NoError(t, applyArticleUpdate(article, input))
+
+	assert.Equal(t, 8, article.WordCount)
+	assert.Equal(t, 1, article.ReadingMinutes)
+	assert.Contains(t, article.Excerpt, "one two three four")
+	assert.NotContains(t, article.Excerpt, "old")
+	require.Len(t, article.TableOfContents, 2)
+	assert.Equal(t, "First", article.TableOfContents[0].Text)
+	assert.Equal(t, "Second", article.TableOfContents[1].Text)
+	assert.Contains(t, article.BodyHTML, "five six")
+}
+
+func TestApplyArticleUpdate_Description(t *testing.T) {
+	article, err := articleEntity.NewArticle("author", "Title", "", "some body", []string{})
+	require.NoError(t, err)
+
+	var input dto.ArticleUpdateInput
+	input.Article.Description = "A new description"
+	require.NoError(t, applyArticleUpdate(article, input))
+
+	assert.Equal(t, "A new description", article.Excerpt)
+	assert.Equal(t, "some body", article.Body)
+}
@@ -1,10 +1,16 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sallescosta/conduit-api/internal/dto"
 	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
-	"log"
+	"github.com/sallescosta/conduit-api/pkg/helpers"
 )
 
 func CreateCommentsTable(db *sql.DB) error {
@@ -14,18 +20,24 @@ func CreateCommentsTable(db *sql.DB) error {
             body TEXT,
             author_id VARCHAR(255) NOT NULL,
             article_id VARCHAR(255) NOT NULL,
+            parent_id VARCHAR(255) REFERENCES comments (id),
+            depth INT DEFAULT 0,
+            deleted BOOLEAN DEFAULT FALSE,
             createdAt TIMESTAMP DEFAULT NOW(),
             updatedAt TIMESTAMP DEFAULT NOW(),
             FOREIGN KEY (article_id) REFERENCES articles (id)
         );
+
+        ALTER TABLE comments ADD COLUMN IF NOT EXISTS parent_id VARCHAR(255) REFERENCES comments (id);
+        ALTER TABLE comments ADD COLUMN IF NOT EXISTS depth INT DEFAULT 0;
+        ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted BOOLEAN DEFAULT FALSE;
     `
 	_, err := db.Exec(query)
 	if err != nil {
-		log.Fatal(err)
-		return err
+		return fmt.Errorf("error creating comments table: %w", err)
 	}
 
-	fmt.Println("comments table created")
+	slog.Info("table ready", slog.String("table", "comments"))
 	return nil
 }
 
@@ -37,15 +49,16 @@ func NewComment(db *sql.DB) *CommentDB {
 	return &CommentDB{DB: db}
 }
 
-func (c *CommentDB) CreateCommentDb(comment *entityComment.Comment) error {
-	stmt, err := c.DB.Prepare("INSERT INTO comments (id, body, author_id, article_id, createdAt, updatedAt) VALUES ($1, $2, $3, $4, $5, $6)")
+func (c *CommentDB) CreateCommentDb(ctx context.Context, comment *entityComment.Comment) error {
+	stmt, err := c.DB.PrepareContext(ctx, "INSERT INTO comments (id, body, author_id, article_id, parent_id, depth, deleted, createdAt, updatedAt) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)")
 	if err != nil {
 		return fmt.Errorf("error preparing insert statement: %w", err)
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(comment.ID, comment.Body, comment.AuthorID, comment.ArticleID, comment.CreatedAt, comment.UpdatedAt)
+	_, err = stmt.ExecContext(ctx, comment.ID, comment.Body, comment.AuthorID, comment.ArticleID, comment.ParentID, comment.Depth,
+		comment.Deleted, comment.CreatedAt, comment.UpdatedAt)
 
 	if err != nil {
 		return err
@@ -54,50 +67,231 @@ func (c *CommentDB) CreateCommentDb(comment *entityComment.Comment) error {
 	return nil
 }
 
-func (c *CommentDB) GetCommentsDb(slug string) (*entityComment.AllCommentsFromAnArticle, error) {
+type CommentsQuery struct {
+	ViewerID string
+	Limit    int
+	Cursor   string
+	Sort     string
+}
+
+// CommentsPage holds a page of top-level comments together with all of their
+// replies, and the profile of every author that appears in it.
+type CommentsPage struct {
+	Comments   []entityComment.Comment
+	Authors    map[string]dto.AuthorOutput
+	Total      int
+	NextCursor string
+}
+
+func (c *CommentDB) GetCommentsDb(ctx context.Context, slug string, q CommentsQuery) (*CommentsPage, error) {
 	articleDB := NewArticle(c.DB)
-	article, err := articleDB.GetArticleBySlug(slug)
+	article, err := articleDB.GetArticleBySlug(ctx, slug)
 
 	if err != nil {
 		return nil, fmt.Errorf("error getting article by slug: %w", err)
 	}
 
-	commentsQuery := `SELECT id, body, author_id, article_id, createdAt, updatedAt FROM comments WHERE article_id = $1`
-	rows, err := c.DB.Query(commentsQuery, article.ID)
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	order, cmp := "ASC", ">"
+	if q.Sort == "newest" {
+		order, cmp = "DESC", "<"
+	}
+
+	args := []interface{}{article.ID, q.Limit + 1}
+	cursorFilter := ""
+	if q.Cursor != "" {
+		createdAt, id, err := helpers.DecodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursorFilter = fmt.Sprintf("AND (createdAt, id) %s ($3, $4)", cmp)
+		args = append(args, createdAt, id)
+	}
+
+	rootsQuery := fmt.Sprintf(`SELECT id, createdAt FROM comments
+		WHERE article_id = $1 AND parent_id IS NULL %s
+		ORDER BY createdAt %s, id %s LIMIT $2`, cursorFilter, order, order)
+	rows, err := c.DB.QueryContext(ctx, rootsQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying comments: %w", err)
 	}
 	defer rows.Close()
 
-	var commentsList entityComment.AllCommentsFromAnArticle
-
+	var rootIDs []string
+	var rootTimes []time.Time
 	for rows.Next() {
+		var id string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning comment: %w", err)
+		}
+		rootIDs = append(rootIDs, id)
+		rootTimes = append(rootTimes, createdAt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over comment rows: %w", err)
+	}
+
+	page := &CommentsPage{
+		Comments: []entityComment.Comment{},
+		Authors:  map[string]dto.AuthorOutput{},
+	}
+
+	if len(rootIDs) > q.Limit {
+		rootIDs, rootTimes = rootIDs[:q.Limit], rootTimes[:q.Limit]
+		last := len(rootIDs) - 1
+		page.NextCursor = helpers.EncodeCursor(rootTimes[last], rootIDs[last])
+	}
+
+	// the count matches what the cursor pages through: top-level comments
+	err = c.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments WHERE article_id = $1 AND parent_id IS NULL",
+		article.ID).Scan(&page.Total)
+	if err != nil {
+		return nil, fmt.Errorf("error counting comments: %w", err)
+	}
+
+	if len(rootIDs) == 0 {
+		return page, nil
+	}
+
+	// sort only orders the top-level comments; replies always read oldest
+	// first inside their thread
+	threadQuery := fmt.Sprintf(`
+		WITH RECURSIVE thread AS (
+			SELECT * FROM comments WHERE id = ANY($1)
+			UNION ALL
+			SELECT c.* FROM comments c JOIN thread t ON c.parent_id = t.id
+		)
+		SELECT t.id, t.body, t.author_id, t.article_id, COALESCE(t.parent_id, ''), t.depth, t.deleted,
+			t.createdAt, t.updatedAt, u.username, COALESCE(u.bio, ''), COALESCE(u.image, ''),
+			COALESCE(t.author_id = ANY(v.following), FALSE)
+		FROM thread t
+		JOIN users u ON u.id = t.author_id
+		LEFT JOIN users v ON v.id = $2
+		ORDER BY t.parent_id IS NOT NULL,
+			CASE WHEN t.parent_id IS NULL THEN t.createdAt END %s,
+			CASE WHEN t.parent_id IS NULL THEN t.id END %s,
+			t.createdAt ASC, t.id ASC`, order, order)
+
+	threadRows, err := c.DB.QueryContext(ctx, threadQuery, pq.Array(rootIDs), q.ViewerID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying comments: %w", err)
+	}
+	defer threadRows.Close()
+
+	for threadRows.Next() {
 		var comment entityComment.Comment
-		err := rows.Scan(&comment.ID, &comment.Body, &comment.AuthorID, &comment.ArticleID, &comment.CreatedAt, &comment.UpdatedAt)
+		var author dto.AuthorOutput
+		err := threadRows.Scan(&comment.ID, &comment.Body, &comment.AuthorID, &comment.ArticleID, &comment.ParentID,
+			&comment.Depth, &comment.Deleted, &comment.CreatedAt, &comment.UpdatedAt,
+			&author.Username, &author.Bio, &author.Image, &author.Following)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning comment: %w", err)
 		}
-		commentsList.Comments = append(commentsList.Comments, comment)
+		page.Comments = append(page.Comments, comment)
+		page.Authors[comment.AuthorID] = author
 	}
 
-	if err = rows.Err(); err != nil {
+	if err = threadRows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating over comment rows: %w", err)
 	}
 
-	if len(commentsList.Comments) == 0 {
-		commentsList.Comments = []entityComment.Comment{}
+	return page, nil
+}
+
+func (c *CommentDB) GetCommentByIdDb(ctx context.Context, id string) (*entityComment.Comment, error) {
+	query := `SELECT id, body, author_id, article_id, COALESCE(parent_id, ''), depth, deleted, createdAt, updatedAt
+		FROM comments WHERE id = $1`
+
+	var comment entityComment.Comment
+	err := c.DB.QueryRowContext(ctx, query, id).Scan(&comment.ID, &comment.Body, &comment.AuthorID, &comment.ArticleID,
+		&comment.ParentID, &comment.Depth, &comment.Deleted, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		return nil, err
 	}
 
-	return &commentsList, nil
+	return &comment, nil
 }
 
-func (c *CommentDB) DeleteCommentsDb(id string) error {
+func (c *CommentDB) UpdateCommentDb(ctx context.Context, comment *entityComment.Comment) error {
+	stmt, err := c.DB.PrepareContext(ctx, "UPDATE comments SET body = $1, deleted = $2, updatedAt = $3 WHERE id = $4")
+	if err != nil {
+		return fmt.Errorf("error preparing update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, comment.Body, comment.Deleted, comment.UpdatedAt, comment.ID)
+	if err != nil {
+		return fmt.Errorf("error updating comment: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCommentsDb removes the comment, or replaces it with a placeholder
+// when other comments reply to it so the thread is kept intact.
+func (c *CommentDB) DeleteCommentsDb(ctx context.Context, id string) error {
+	var hasReplies bool
+	err := c.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM comments WHERE parent_id = $1)", id).Scan(&hasReplies)
+	if err != nil {
+		return fmt.Errorf("error checking comment replies: %w", err)
+	}
+
+	if hasReplies {
+		comment, err := c.GetCommentByIdDb(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error getting comment: %w", err)
+		}
+
+		comment.MarkDeleted()
+		return c.UpdateCommentDb(ctx, comment)
+	}
 
 	query := "DELETE FROM comments WHERE id = $1"
-	_, err := c.DB.Exec(query, id)
+	_, err = c.DB.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("error deleting comment: %w", err)
 	}
 
 	return nil
 }
+
+// PurgeCommentDb removes a comment together with all of its replies, for
+// moderation, instead of leaving a placeholder in the thread. It returns the
+// ids of every comment it removed.
+func (c *CommentDB) PurgeCommentDb(ctx context.Context, id string) ([]string, error) {
+	query := `
+		WITH RECURSIVE thread AS (
+			SELECT id, depth FROM comments WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.depth FROM comments c JOIN thread t ON c.parent_id = t.id
+		)
+		DELETE FROM comments WHERE id IN (SELECT id FROM thread) RETURNING id`
+
+	rows, err := c.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting comment: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var removed string
+		if err := rows.Scan(&removed); err != nil {
+			return nil, fmt.Errorf("error deleting comment: %w", err)
+		}
+		ids = append(ids, removed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error deleting comment: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return ids, nil
+}
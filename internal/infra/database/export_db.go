@@ -0,0 +1,321 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	exportEntity "github.com/sallescosta/conduit-api/internal/entity/export"
+	notificationEntity "github.com/sallescosta/conduit-api/internal/entity/notification"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+// CreateExportsTable keeps the archive next to the job, so any instance can
+// serve a download built by another one. Archives are dropped on expiry.
+func CreateExportsTable(db *sql.DB) error {
+	query := `
+        CREATE TABLE IF NOT EXISTS exports (
+            id VARCHAR(255) PRIMARY KEY,
+            user_id VARCHAR(255) NOT NULL REFERENCES users (id),
+            status VARCHAR(20) NOT NULL,
+            token VARCHAR(64) UNIQUE NOT NULL,
+            archive BYTEA,
+            size INT NOT NULL DEFAULT 0,
+            error TEXT NOT NULL DEFAULT '',
+            expires_at TIMESTAMPTZ,
+            lease_until TIMESTAMPTZ,
+            createdAt TIMESTAMPTZ DEFAULT NOW(),
+            updatedAt TIMESTAMPTZ DEFAULT NOW()
+        );
+
+        CREATE INDEX IF NOT EXISTS exports_user_idx ON exports (user_id, createdAt DESC);
+        CREATE INDEX IF NOT EXISTS exports_pending_idx ON exports (createdAt) WHERE status = 'pending';
+    `
+	_, err := db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("error creating exports table: %w", err)
+	}
+
+	slog.Info("table ready", slog.String("table", "exports"))
+	return nil
+}
+
+type ExportDB struct {
+	DB *sql.DB
+}
+
+func NewExport(db *sql.DB) *ExportDB {
+	return &ExportDB{DB: db}
+}
+
+const exportColumns = "id, user_id, status, token, size, error, COALESCE(expires_at, 'epoch'), createdAt, updatedAt"
+
+// scanExport scans exportColumns followed by the extra columns, if any.
+func scanExport(row rowScanner, extra ...interface{}) (*exportEntity.Export, error) {
+	var e exportEntity.Export
+
+	dest := []interface{}{&e.ID, &e.UserID, &e.Status, &e.Token, &e.Size, &e.Error, &e.ExpiresAt, &e.CreatedAt, &e.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	if e.ExpiresAt.Unix() == 0 {
+		e.ExpiresAt = time.Time{}
+	}
+	return &e, nil
+}
+
+func (ed *ExportDB) CreateExport(ctx context.Context, e *exportEntity.Export) error {
+	_, err := ed.DB.ExecContext(ctx,
+		"INSERT INTO exports (id, user_id, status, token, createdAt, updatedAt) VALUES ($1, $2, $3, $4, $5, $6)",
+		e.ID.String(), e.UserID, e.Status, e.Token, e.CreatedAt, e.UpdatedAt)
+	return err
+}
+
+// GetExport returns sql.ErrNoRows when the export does not exist.
+func (ed *ExportDB) GetExport(ctx context.Context, id string) (*exportEntity.Export, error) {
+	return scanExport(ed.DB.QueryRowContext(ctx, "SELECT "+exportColumns+" FROM exports WHERE id = $1", id))
+}
+
+// PendingExport returns the export of the user still waiting to be built,
+// or nil when there is none.
+func (ed *ExportDB) PendingExport(ctx context.Context, userID string) (*exportEntity.Export, error) {
+	e, err := scanExport(ed.DB.QueryRowContext(ctx,
+		"SELECT "+exportColumns+" FROM exports WHERE user_id = $1 AND status = 'pending' ORDER BY createdAt LIMIT 1", userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// GetExportArchive returns the export with the download token and its
+// archive, which is empty unless the export is ready.
+func (ed *ExportDB) GetExportArchive(ctx context.Context, token string) (*exportEntity.Export, []byte, error) {
+	var archive []byte
+	e, err := scanExport(ed.DB.QueryRowContext(ctx,
+		"SELECT "+exportColumns+", COALESCE(archive, '') FROM exports WHERE token = $1", token), &archive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e, archive, nil
+}
+
+// ClaimPendingExports leases up to limit pending exports to the caller, like
+// ClaimDueDeliveries does, so that each one is built by a single instance.
+func (ed *ExportDB) ClaimPendingExports(ctx context.Context, limit int, lease time.Duration) ([]exportEntity.Export, error) {
+	query := `
+        UPDATE exports SET lease_until = NOW() + $2 * INTERVAL '1 millisecond'
+        WHERE id IN (
+            SELECT id FROM exports
+            WHERE status = 'pending' AND (lease_until IS NULL OR lease_until <= NOW())
+            ORDER BY createdAt
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + exportColumns
+	rows, err := ed.DB.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []exportEntity.Export
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *e)
+	}
+
+	return exports, rows.Err()
+}
+
+// CompleteExport saves the outcome of a build with its archive, if any.
+func (ed *ExportDB) CompleteExport(ctx context.Context, e *exportEntity.Export, archive []byte) error {
+	var expiresAt interface{}
+	if !e.ExpiresAt.IsZero() {
+		expiresAt = e.ExpiresAt
+	}
+
+	_, err := ed.DB.ExecContext(ctx, `UPDATE exports SET status = $1, size = $2, error = $3, expires_at = $4,
+		archive = $5, lease_until = NULL, updatedAt = $6 WHERE id = $7`,
+		e.Status, e.Size, e.Error, expiresAt, archive, e.UpdatedAt, e.ID.String())
+	return err
+}
+
+// ExpireExports drops the archives whose link has expired and returns how
+// many there were.
+func (ed *ExportDB) ExpireExports(ctx context.Context) (int, error) {
+	res, err := ed.DB.ExecContext(ctx, `UPDATE exports SET status = 'expired', archive = NULL, updatedAt = NOW()
+		WHERE status = 'ready' AND expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// ArticleReference names an article without its content.
+type ArticleReference struct {
+	ID    string `json:"id"`
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+}
+
+// UserData is everything stored about a user.
+type UserData struct {
+	User          *userEntity.User
+	Articles      []articleEntity.Article
+	Revisions     []articleEntity.Revision
+	Comments      []entityComment.Comment
+	Favorites     []ArticleReference
+	Following     []string
+	Followers     []string
+	Notifications []notificationEntity.Notification
+}
+
+// CollectUserData reads the user's data in one repeatable-read transaction,
+// so the export is a consistent snapshot.
+func (ed *ExportDB) CollectUserData(ctx context.Context, userID string) (*UserData, error) {
+	tx, err := ed.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	data := &UserData{
+		Articles:      []articleEntity.Article{},
+		Revisions:     []articleEntity.Revision{},
+		Comments:      []entityComment.Comment{},
+		Favorites:     []ArticleReference{},
+		Notifications: []notificationEntity.Notification{},
+	}
+
+	data.User, err = scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", userID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+articleColumns+" FROM articles WHERE author_id = $1 ORDER BY createdAt", userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		article, err := scanArticle(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		data.Articles = append(data.Articles, *article)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `SELECT r.id, r.article_id, r.title, COALESCE(r.description, ''), COALESCE(r.body, ''),
+		COALESCE(r.createdAt, r.replacedAt), r.replacedAt
+		FROM article_revisions r JOIN articles a ON a.id = r.article_id
+		WHERE a.author_id = $1 ORDER BY a.createdAt, r.replacedAt`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var r articleEntity.Revision
+		if err := rows.Scan(&r.ID, &r.ArticleID, &r.Title, &r.Description, &r.Body, &r.CreatedAt, &r.ReplacedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		data.Revisions = append(data.Revisions, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `SELECT id, body, author_id, article_id, COALESCE(parent_id, ''), depth, deleted,
+		createdAt, updatedAt FROM comments WHERE author_id = $1 ORDER BY createdAt`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c entityComment.Comment
+		if err := rows.Scan(&c.ID, &c.Body, &c.AuthorID, &c.ArticleID, &c.ParentID, &c.Depth, &c.Deleted,
+			&c.CreatedAt, &c.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		data.Comments = append(data.Comments, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT id, slug, title FROM articles WHERE id = ANY($1) ORDER BY title",
+		pq.Array(idStrings(data.User.Favorites)))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var a ArticleReference
+		if err := rows.Scan(&a.ID, &a.Slug, &a.Title); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		data.Favorites = append(data.Favorites, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	data.Following, err = queryStrings(ctx, tx, "SELECT username FROM users WHERE id = ANY($1) ORDER BY username",
+		pq.Array(idStrings(data.User.Following)))
+	if err != nil {
+		return nil, err
+	}
+	data.Followers, err = queryStrings(ctx, tx, "SELECT username FROM users WHERE $1 = ANY(following) ORDER BY username",
+		userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `SELECT id, recipient_id, type, article_id, actor_ids, count, read, createdAt, updatedAt
+		FROM notifications WHERE recipient_id = $1 ORDER BY updatedAt DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var n notificationEntity.Notification
+		if err := rows.Scan(&n.ID, &n.RecipientID, &n.Type, &n.ArticleID, pq.Array(&n.ActorIDs), &n.Count, &n.Read,
+			&n.CreatedAt, &n.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		data.Notifications = append(data.Notifications, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return data, tx.Commit()
+}
+
+func idStrings(ids []entity.ID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	return s
+}
@@ -1,41 +1,97 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/sallescosta/conduit-api/internal/dto"
 	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
 	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	exportEntity "github.com/sallescosta/conduit-api/internal/entity/export"
+	notificationEntity "github.com/sallescosta/conduit-api/internal/entity/notification"
 	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
 	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
 	"github.com/sallescosta/conduit-api/pkg/entity"
 )
 
 type UserInterface interface {
-	CreateUser(user *userEntity.User) error
-	FindByEmail(email string) (*userEntity.User, error)
-	FindById(id string) (*userEntity.User, error)
-	GetAllUsers() ([]userEntity.User, error)
-	UpdateUserDb(email, username, password, image, bio string) (*userEntity.User, error)
-	GetProfileDb(userName string) (*ProfileWithId, error)
-	UpdateFollowingUserDb(id string, following []entity.ID) error
-	FavoriteArticleDB(slug string, isAddToFavorite bool, userID string) error
+	CreateUser(ctx context.Context, user *userEntity.User) error
+	FindByEmail(ctx context.Context, email string) (*userEntity.User, error)
+	FindById(ctx context.Context, id string) (*userEntity.User, error)
+	ListUsers(ctx context.Context, q UsersQuery) (*UsersPage, error)
+	SetBanned(ctx context.Context, id string, banned bool) error
+	SetRole(ctx context.Context, id string, role string) error
+	UpdateUserDb(ctx context.Context, id, username, password, image, bio string) (*userEntity.User, error)
+	GetProfileDb(ctx context.Context, userName string) (*ProfileWithId, error)
+	UpdateFollowingUserDb(ctx context.Context, id string, following []entity.ID) error
+	FavoriteArticleDB(ctx context.Context, slug string, isAddToFavorite bool, userID string) error
+	DeleteUser(ctx context.Context, id string, mode string) (*DeletedAccount, error)
 }
 
 type ArticleInterface interface {
-	CreateArticle(article *articleEntity.Article) error
-	ListAllArticles() ([]articleEntity.Article, error)
-	FeedArticles(limit, offset int, sort string) ([]articleEntity.Article, error)
-	GetArticleBySlug(slug string) (*articleEntity.Article, error)
-	UpdateArticle(slug string, article dto.ArticleUpdateInput) (*articleEntity.Article, error)
-	DeleteArticleDB(slug string) error
+	CreateArticle(ctx context.Context, article *articleEntity.Article) error
+	ListAllArticles(ctx context.Context) ([]articleEntity.Article, error)
+	QueryArticles(ctx context.Context, q ArticleQuery) (*ArticlesPage, error)
+	GetArticleBySlug(ctx context.Context, slug string) (*articleEntity.Article, error)
+	UpdateArticle(ctx context.Context, slug string, article dto.ArticleUpdateInput) (*articleEntity.Article, error)
+	DeleteArticleDB(ctx context.Context, slug string) ([]string, error)
 }
 
 type CommentInterface interface {
-	CreateCommentDb(comment *entityComment.Comment) error
-	GetCommentsDb(slug string) (*entityComment.AllCommentsFromAnArticle, error)
-	DeleteCommentsDb(id string) error
+	CreateCommentDb(ctx context.Context, comment *entityComment.Comment) error
+	GetCommentsDb(ctx context.Context, slug string, q CommentsQuery) (*CommentsPage, error)
+	GetCommentByIdDb(ctx context.Context, id string) (*entityComment.Comment, error)
+	UpdateCommentDb(ctx context.Context, comment *entityComment.Comment) error
+	DeleteCommentsDb(ctx context.Context, id string) error
+	PurgeCommentDb(ctx context.Context, id string) ([]string, error)
 }
 
 type TagsInterface interface {
-	CreateTag(tags []*tagEntity.Tag) error
-	ListTags() ([]*tagEntity.Tag, error)
+	CreateTag(ctx context.Context, tags []*tagEntity.Tag) error
+	ListTags(ctx context.Context) ([]*tagEntity.Tag, error)
+	LinkArticleTags(ctx context.Context, articleID string, names []string) error
+	RenameTag(ctx context.Context, from, to string) ([]string, error)
+}
+
+type NotificationInterface interface {
+	Notify(ctx context.Context, n *notificationEntity.Notification) (bool, error)
+	ListNotifications(ctx context.Context, recipientID string, q NotificationsQuery) (*NotificationsPage, error)
+	MarkRead(ctx context.Context, recipientID, id string) error
+	MarkAllRead(ctx context.Context, recipientID string) error
+	GetPreferences(ctx context.Context, userID string) (notificationEntity.Preferences, error)
+	SetPreferences(ctx context.Context, userID string, prefs notificationEntity.Preferences) error
+}
+
+type WebhookInterface interface {
+	CreateWebhook(ctx context.Context, hook *webhookEntity.Webhook) error
+	ListWebhooks(ctx context.Context, ownerID string) ([]webhookEntity.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*webhookEntity.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	MatchWebhooks(ctx context.Context, event, subjectID string) ([]webhookEntity.Webhook, error)
+	CreateDelivery(ctx context.Context, d *webhookEntity.Delivery) error
+	GetDelivery(ctx context.Context, id string) (*webhookEntity.Delivery, error)
+	ListDeliveries(ctx context.Context, webhookID string, q DeliveriesQuery) (*DeliveriesPage, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueDelivery, error)
+	UpdateDelivery(ctx context.Context, d *webhookEntity.Delivery) error
+}
+
+type StatsInterface interface {
+	Stats(ctx context.Context) (*Stats, error)
+}
+
+type SitemapInterface interface {
+	CountSitemapEntries(ctx context.Context) (int, error)
+	ListSitemapEntries(ctx context.Context, offset, limit int) ([]SitemapEntry, error)
+}
+
+type ExportInterface interface {
+	CreateExport(ctx context.Context, e *exportEntity.Export) error
+	GetExport(ctx context.Context, id string) (*exportEntity.Export, error)
+	PendingExport(ctx context.Context, userID string) (*exportEntity.Export, error)
+	GetExportArchive(ctx context.Context, token string) (*exportEntity.Export, []byte, error)
+	ClaimPendingExports(ctx context.Context, limit int, lease time.Duration) ([]exportEntity.Export, error)
+	CompleteExport(ctx context.Context, e *exportEntity.Export, archive []byte) error
+	ExpireExports(ctx context.Context) (int, error)
+	CollectUserData(ctx context.Context, userID string) (*UserData, error)
 }
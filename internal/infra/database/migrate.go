@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type migration struct {
+	table  string
+	create func(*sql.DB) error
+	// columns lists what create adds to an existing table with ALTER, so a
+	// table created by an older release is not taken for an up to date one.
+	columns []string
+}
+
+// migrations run in order at startup; each one is idempotent.
+var migrations = []migration{
+	{"users", CreateUsersTable, []string{"role", "banned", "updated_at"}},
+	{"articles", CreateArticlesTable, []string{"body_html", "word_count", "reading_minutes", "excerpt",
+		"table_of_contents", "canonical_url", "cover_image", "license", "seo_description", "version"}},
+	{"article_revisions", CreateArticleRevisionsTable, nil},
+	{"comments", CreateCommentsTable, []string{"parent_id", "depth", "deleted"}},
+	{"tags", CreateTagsTable, nil},
+	{"article_tags", CreateArticleTagsTable, []string{"article_created_at"}},
+	{"notifications", CreateNotificationsTable, nil},
+	{"notification_preferences", CreateNotificationPreferencesTable, nil},
+	{"webhooks", CreateWebhooksTable, nil},
+	{"webhook_deliveries", CreateWebhookDeliveriesTable, nil},
+	{"exports", CreateExportsTable, nil},
+}
+
+// Migrate creates or updates every table the repositories use.
+func Migrate(db *sql.DB) error {
+	for _, m := range migrations {
+		if err := m.create(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckMigrations reports the tables Migrate should have created, or the
+// columns it should have added to them, that are missing.
+func CheckMigrations(ctx context.Context, db *sql.DB) error {
+	var tables, columnTables, columns []string
+	for _, m := range migrations {
+		tables = append(tables, m.table)
+		for _, column := range m.columns {
+			columnTables = append(columnTables, m.table)
+			columns = append(columns, column)
+		}
+	}
+
+	missing, err := queryMissing(ctx, db, "SELECT t FROM unnest($1::text[]) AS t WHERE to_regclass(t) IS NULL",
+		pq.Array(tables))
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+	}
+
+	missing, err = queryMissing(ctx, db, `SELECT c.t || '.' || c.col FROM unnest($1::text[], $2::text[]) AS c (t, col)
+		WHERE NOT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = c.t AND column_name = c.col)`,
+		pq.Array(columnTables), pq.Array(columns))
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing columns: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func queryMissing(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		missing = append(missing, name)
+	}
+	return missing, rows.Err()
+}
+
+// WaitForDB pings db until it answers or timeout elapses, doubling the wait
+// between attempts up to five seconds.
+func WaitForDB(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := 250 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		slog.Warn("database not ready", slog.Int("attempt", attempt), slog.String("error", err.Error()),
+			slog.Duration("retry_in", backoff))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %s: %w", timeout, err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > 5*time.Second {
+			backoff = 5 * time.Second
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrations_ListAddedColumns fails when a migration adds a column with
+// ALTER that CheckMigrations does not look for.
+func TestMigrations_ListAddedColumns(t *testing.T) {
+	files, err := filepath.Glob("*_db.go")
+	require.NoError(t, err)
+
+	added := regexp.MustCompile(`ALTER TABLE (\w+) ADD COLUMN IF NOT EXISTS (\w+)`)
+	listed := map[string]bool{}
+	for _, m := range migrations {
+		for _, column := range m.columns {
+			listed[m.table+"."+column] = true
+		}
+	}
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		for _, match := range added.FindAllStringSubmatch(string(source), -1) {
+			assert.True(t, listed[match[1]+"."+match[2]], "%s.%s in %s", match[1], match[2], file)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+	"github.com/sallescosta/conduit-api/internal/dto"
+	notificationEntity "github.com/sallescosta/conduit-api/internal/entity/notification"
+)
+
+// maxListedActors is how many of the most recent actors of a grouped
+// notification are listed with their profile.
+const maxListedActors = 3
+
+func CreateNotificationsTable(db *sql.DB) error {
+	query := `
+        CREATE TABLE IF NOT EXISTS notifications (
+            id VARCHAR(255) PRIMARY KEY,
+            recipient_id VARCHAR(255) NOT NULL REFERENCES users (id),
+            type VARCHAR(20) NOT NULL,
+            article_id VARCHAR(255) NOT NULL DEFAULT '',
+            actor_ids VARCHAR(255)[] NOT NULL,
+            count INT NOT NULL DEFAULT 1,
+            read BOOLEAN NOT NULL DEFAULT FALSE,
+            createdAt TIMESTAMP DEFAULT NOW(),
+            updatedAt TIMESTAMP DEFAULT NOW()
+        );
+
+        CREATE UNIQUE INDEX IF NOT EXISTS notifications_unread_group_idx
+            ON notifications (recipient_id, type, article_id) WHERE NOT read;
+        CREATE INDEX IF NOT EXISTS notifications_recipient_idx ON notifications (recipient_id, updatedAt DESC);
+    `
+	_, err := db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("error creating notifications table: %w", err)
+	}
+
+	slog.Info("table ready", slog.String("table", "notifications"))
+	return nil
+}
+
+func CreateNotificationPreferencesTable(db *sql.DB) error {
+	query := `
+        CREATE TABLE IF NOT EXISTS notification_preferences (
+            user_id VARCHAR(255) NOT NULL REFERENCES users (id),
+            type VARCHAR(20) NOT NULL,
+            enabled BOOLEAN NOT NULL,
+            PRIMARY KEY (user_id, type)
+        );
+    `
+	_, err := db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("error creating notification_preferences table: %w", err)
+	}
+
+	slog.Info("table ready", slog.String("table", "notification_preferences"))
+	return nil
+}
+
+type NotificationDB struct {
+	DB *sql.DB
+}
+
+func NewNotification(db *sql.DB) *NotificationDB {
+	return &NotificationDB{DB: db}
+}
+
+// Notify stores n, or folds it into the unread notification of the same
+// type about the same subject. It reports false when the recipient turned
+// this type off.
+func (nd *NotificationDB) Notify(ctx context.Context, n *notificationEntity.Notification) (bool, error) {
+	query := `
+        INSERT INTO notifications (id, recipient_id, type, article_id, actor_ids, count, read, createdAt, updatedAt)
+        SELECT $1, $2, $3, $4, $5, 1, FALSE, $6, $6
+        WHERE NOT EXISTS (
+            SELECT 1 FROM notification_preferences WHERE user_id = $2 AND type = $3 AND NOT enabled
+        )
+        ON CONFLICT (recipient_id, type, article_id) WHERE NOT read DO UPDATE SET
+            actor_ids = array_cat(EXCLUDED.actor_ids, (
+                SELECT COALESCE(array_agg(x.id ORDER BY x.ord), '{}') FROM unnest(notifications.actor_ids) WITH ORDINALITY AS x(id, ord)
+                WHERE x.id <> ALL(EXCLUDED.actor_ids)
+            )),
+            count = notifications.count + 1,
+            updatedAt = EXCLUDED.updatedAt
+    `
+	result, err := nd.DB.ExecContext(ctx, query, n.ID.String(), n.RecipientID, n.Type, n.ArticleID,
+		pq.Array(n.ActorIDs), n.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("error storing notification: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+type NotificationsQuery struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+type NotificationsPage struct {
+	Notifications []dto.NotificationOutput
+	Total         int
+	Unread        int
+}
+
+// ListNotifications returns the notifications of recipientID, the most
+// recently updated first, with the article and latest actors of each.
+func (nd *NotificationDB) ListNotifications(ctx context.Context, recipientID string, q NotificationsQuery) (*NotificationsPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	page := &NotificationsPage{Notifications: []dto.NotificationOutput{}}
+
+	err := nd.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE NOT read) FROM notifications WHERE recipient_id = $1",
+		recipientID).Scan(&page.Total, &page.Unread)
+	if err != nil {
+		return nil, err
+	}
+	if q.UnreadOnly {
+		page.Total = page.Unread
+	}
+
+	query := `
+        SELECT n.id, n.type, n.count, n.read, n.createdAt, n.updatedAt, cardinality(n.actor_ids),
+            COALESCE(a.slug, ''), COALESCE(a.title, ''),
+            (SELECT COALESCE(json_agg(json_build_object('username', u.username, 'image', COALESCE(u.image, '')) ORDER BY x.ord), '[]')
+                FROM unnest(n.actor_ids[1:$2]) WITH ORDINALITY AS x(id, ord)
+                JOIN users u ON u.id = x.id)
+        FROM notifications n
+        LEFT JOIN articles a ON a.id = n.article_id
+        WHERE n.recipient_id = $1 AND (NOT $3 OR NOT n.read)
+        ORDER BY n.updatedAt DESC, n.id DESC
+        LIMIT $4 OFFSET $5
+    `
+	rows, err := nd.DB.QueryContext(ctx, query, recipientID, maxListedActors, q.UnreadOnly, q.Limit, q.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying notifications: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n dto.NotificationOutput
+		var slug, title string
+		var actors []byte
+
+		err := rows.Scan(&n.ID, &n.Type, &n.Count, &n.Read, &n.CreatedAt, &n.UpdatedAt, &n.ActorsCount,
+			&slug, &title, &actors)
+		if err != nil {
+			return nil, err
+		}
+
+		if slug != "" {
+			n.Article = &dto.NotificationArticle{Slug: slug, Title: title}
+		}
+		if err := json.Unmarshal(actors, &n.Actors); err != nil {
+			return nil, err
+		}
+
+		page.Notifications = append(page.Notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// MarkRead marks one notification of recipientID as read. It returns
+// sql.ErrNoRows when recipientID has no such notification.
+func (nd *NotificationDB) MarkRead(ctx context.Context, recipientID, id string) error {
+	result, err := nd.DB.ExecContext(ctx, "UPDATE notifications SET read = TRUE WHERE id = $1 AND recipient_id = $2",
+		id, recipientID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (nd *NotificationDB) MarkAllRead(ctx context.Context, recipientID string) error {
+	_, err := nd.DB.ExecContext(ctx, "UPDATE notifications SET read = TRUE WHERE recipient_id = $1 AND NOT read",
+		recipientID)
+	return err
+}
+
+// GetPreferences returns the preferences of userID, with every type the
+// user never changed turned on.
+func (nd *NotificationDB) GetPreferences(ctx context.Context, userID string) (notificationEntity.Preferences, error) {
+	rows, err := nd.DB.QueryContext(ctx, "SELECT type, enabled FROM notification_preferences WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := notificationEntity.DefaultPreferences()
+	for rows.Next() {
+		var notificationType string
+		var enabled bool
+		if err := rows.Scan(&notificationType, &enabled); err != nil {
+			return nil, err
+		}
+		if notificationEntity.ValidType(notificationType) {
+			prefs[notificationType] = enabled
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+func (nd *NotificationDB) SetPreferences(ctx context.Context, userID string, prefs notificationEntity.Preferences) error {
+	tx, err := nd.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for notificationType, enabled := range prefs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO notification_preferences (user_id, type, enabled) VALUES ($1, $2, $3)
+            ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled`, userID, notificationType, enabled)
+		if err != nil {
+			return fmt.Errorf("error saving notification preferences: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
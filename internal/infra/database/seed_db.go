@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+)
+
+// SeedData is a generated data set. Follows and favorites are in the users,
+// and the favorites count of each article matches them.
+type SeedData struct {
+	Users    []userEntity.User
+	Tags     []tagEntity.Tag
+	Articles []articleEntity.Article
+	Comments []entityComment.Comment
+}
+
+type SeedDB struct {
+	DB *sql.DB
+}
+
+func NewSeed(db *sql.DB) *SeedDB {
+	return &SeedDB{DB: db}
+}
+
+// Reset empties every table the migrations create.
+func (s *SeedDB) Reset(ctx context.Context) error {
+	tables := make([]string, len(migrations))
+	for i, m := range migrations {
+		tables[i] = m.table
+	}
+
+	_, err := s.DB.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" CASCADE")
+	if err != nil {
+		return fmt.Errorf("error resetting the database: %w", err)
+	}
+	return nil
+}
+
+// CopySeedData loads data with COPY in one transaction, which is much faster
+// than going through the repositories one row at a time.
+func (s *SeedDB) CopySeedData(ctx context.Context, data *SeedData) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	err = copyRows(ctx, tx, "users", []string{"id", "username", "email", "password", "bio", "image", "following",
+		"favorites", "role", "banned", "updated_at"}, len(data.Users), func(i int) []interface{} {
+		u := &data.Users[i]
+		return []interface{}{u.ID.String(), u.UserName, u.Email, u.Password, u.Bio, u.Image,
+			pq.Array(idStrings(u.Following)), pq.Array(idStrings(u.Favorites)), u.Role, u.Banned, now}
+	})
+	if err != nil {
+		return err
+	}
+
+	tagIDs := map[string]string{}
+	err = copyRows(ctx, tx, "tags", []string{"id", "name"}, len(data.Tags), func(i int) []interface{} {
+		tagIDs[data.Tags[i].Name] = data.Tags[i].ID.String()
+		return []interface{}{data.Tags[i].ID.String(), data.Tags[i].Name}
+	})
+	if err != nil {
+		return err
+	}
+
+	// COPY quotes column names, so they are spelled the way Postgres folded
+	// the unquoted names of CREATE TABLE
+	var tocErr error
+	err = copyRows(ctx, tx, "articles", []string{"id", "author_id", "slug", "title", "description", "body", "body_html",
+		"word_count", "reading_minutes", "excerpt", "table_of_contents", "canonical_url", "cover_image", "license",
+		"seo_description", "favorited", "favoritescount", "tag_list", "createdat", "updatedat"},
+		len(data.Articles), func(i int) []interface{} {
+			a := &data.Articles[i]
+			toc, err := json.Marshal(a.TableOfContents)
+			if err != nil {
+				tocErr = err
+			}
+			return []interface{}{a.ID.String(), a.AuthorID, a.Slug, a.Title, a.Description, a.Body, a.BodyHTML,
+				a.WordCount, a.ReadingMinutes, a.Excerpt, string(toc), a.CanonicalURL, a.CoverImage, a.License,
+				a.SEODescription, a.Favorited, a.FavoritesCount, pq.Array(a.TagList), a.CreatedAt, a.UpdatedAt}
+		})
+	if err != nil {
+		return err
+	}
+	if tocErr != nil {
+		return fmt.Errorf("error encoding table of contents: %w", tocErr)
+	}
+
+	type link struct{ article, tag, createdAt string }
+	var links []link
+	for _, a := range data.Articles {
+		for _, name := range a.TagList {
+			links = append(links, link{a.ID.String(), tagIDs[name], a.CreatedAt})
+		}
+	}
+	err = copyRows(ctx, tx, "article_tags", []string{"article_id", "tag_id", "article_created_at"}, len(links),
+		func(i int) []interface{} {
+			return []interface{}{links[i].article, links[i].tag, links[i].createdAt}
+		})
+	if err != nil {
+		return err
+	}
+
+	err = copyRows(ctx, tx, "comments", []string{"id", "body", "author_id", "article_id", "parent_id", "depth", "deleted",
+		"createdat", "updatedat"}, len(data.Comments), func(i int) []interface{} {
+		c := &data.Comments[i]
+		var parent interface{}
+		if c.ParentID != "" {
+			parent = c.ParentID
+		}
+		return []interface{}{c.ID.String(), c.Body, c.AuthorID, c.ArticleID, parent, c.Depth, c.Deleted,
+			c.CreatedAt, c.UpdatedAt}
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// copyRows copies n rows into table, asking row for the values of each.
+func copyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, n int, row func(int) []interface{}) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return fmt.Errorf("error copying %s: %w", table, err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < n; i++ {
+		if _, err := stmt.ExecContext(ctx, row(i)...); err != nil {
+			return fmt.Errorf("error copying %s: %w", table, err)
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("error copying %s: %w", table, err)
+	}
+	return nil
+}
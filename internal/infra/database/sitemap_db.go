@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	SitemapArticle = "article"
+	SitemapProfile = "profile"
+)
+
+// SitemapEntry is a page of the public site: an article by slug or the
+// profile of a user who is not banned.
+type SitemapEntry struct {
+	Kind    string
+	Name    string
+	LastMod time.Time
+}
+
+type SitemapDB struct {
+	DB *sql.DB
+}
+
+func NewSitemap(db *sql.DB) *SitemapDB {
+	return &SitemapDB{DB: db}
+}
+
+const sitemapEntries = `
+	SELECT 'article' AS kind, slug AS name, updatedAt AS lastmod, 0 AS part FROM articles
+	UNION ALL
+	SELECT 'profile', username, updated_at, 1 FROM users WHERE NOT banned AND username <> ''`
+
+func (s *SitemapDB) CountSitemapEntries(ctx context.Context) (int, error) {
+	var count int
+	err := s.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+sitemapEntries+") entries").Scan(&count)
+	return count, err
+}
+
+// ListSitemapEntries pages through articles, then profiles, in an order that
+// stays the same between requests so sitemap files do not overlap.
+func (s *SitemapDB) ListSitemapEntries(ctx context.Context, offset, limit int) ([]SitemapEntry, error) {
+	rows, err := s.DB.QueryContext(ctx,
+		"SELECT kind, name, lastmod FROM ("+sitemapEntries+") entries ORDER BY part, name LIMIT $1 OFFSET $2",
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []SitemapEntry{}
+	for rows.Next() {
+		var e SitemapEntry
+		if err := rows.Scan(&e.Kind, &e.Name, &e.LastMod); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
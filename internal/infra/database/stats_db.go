@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+)
+
+// Stats counts what the site holds. Users leaves out the deleted-user
+// placeholder.
+type Stats struct {
+	Users         int `json:"users"`
+	Admins        int `json:"admins"`
+	BannedUsers   int `json:"bannedUsers"`
+	Articles      int `json:"articles"`
+	Comments      int `json:"comments"`
+	Tags          int `json:"tags"`
+	Favorites     int `json:"favorites"`
+	Follows       int `json:"follows"`
+	Notifications int `json:"notifications"`
+	Webhooks      int `json:"webhooks"`
+}
+
+type StatsDB struct {
+	DB *sql.DB
+}
+
+func NewStats(db *sql.DB) *StatsDB {
+	return &StatsDB{DB: db}
+}
+
+func (s *StatsDB) Stats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE id <> $1),
+			(SELECT COUNT(*) FROM users WHERE role = $2),
+			(SELECT COUNT(*) FROM users WHERE banned AND id <> $1),
+			(SELECT COUNT(*) FROM articles),
+			(SELECT COUNT(*) FROM comments WHERE NOT COALESCE(deleted, FALSE)),
+			(SELECT COUNT(*) FROM tags),
+			(SELECT COALESCE(SUM(cardinality(favorites)), 0) FROM users),
+			(SELECT COALESCE(SUM(cardinality(following)), 0) FROM users),
+			(SELECT COUNT(*) FROM notifications),
+			(SELECT COUNT(*) FROM webhooks)`,
+		userEntity.DeletedUserID, userEntity.RoleAdmin).Scan(&stats.Users, &stats.Admins, &stats.BannedUsers,
+		&stats.Articles, &stats.Comments, &stats.Tags, &stats.Favorites, &stats.Follows, &stats.Notifications,
+		&stats.Webhooks)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -1,10 +1,13 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
-	"log"
+	"log/slog"
 )
 
 func CreateTagsTable(db *sql.DB) error {
@@ -16,11 +19,10 @@ func CreateTagsTable(db *sql.DB) error {
     `
 	_, err := db.Exec(query)
 	if err != nil {
-		log.Fatal(err)
-		return err
+		return fmt.Errorf("error creating tags table: %w", err)
 	}
 
-	fmt.Println("tags table created")
+	slog.Info("table ready", slog.String("table", "tags"))
 	return nil
 }
 
@@ -33,14 +35,30 @@ func CreateArticleTagsTable(db *sql.DB) error {
             FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE,
             FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
         );
+
+        -- the creation date of the article, copied so that lists filtered by
+        -- tag are read in order from one index
+        ALTER TABLE article_tags ADD COLUMN IF NOT EXISTS article_created_at TIMESTAMP;
+        UPDATE article_tags t SET article_created_at = a.createdAt
+            FROM articles a WHERE a.id = t.article_id AND t.article_created_at IS NULL;
+
+        -- articles written before article_tags was kept up to date only have
+        -- their tag_list; link them, creating the tags they name if missing
+        INSERT INTO tags (id, name)
+            SELECT gen_random_uuid()::text, name FROM (SELECT DISTINCT unnest(tag_list) AS name FROM articles) n
+            ON CONFLICT (name) DO NOTHING;
+        INSERT INTO article_tags (article_id, tag_id, article_created_at)
+            SELECT a.id, t.id, a.createdAt FROM articles a JOIN tags t ON t.name = ANY(a.tag_list)
+            ON CONFLICT DO NOTHING;
+
+        CREATE INDEX IF NOT EXISTS article_tags_tag_created_at_idx ON article_tags (tag_id, article_created_at, article_id);
     `
 	_, err := db.Exec(query)
 	if err != nil {
-		log.Fatal(err)
-		return err
+		return fmt.Errorf("error creating article_tags table: %w", err)
 	}
 
-	fmt.Println("article_tags table created")
+	slog.Info("table ready", slog.String("table", "article_tags"))
 	return nil
 }
 
@@ -52,24 +70,25 @@ func NewTag(db *sql.DB) *TagDB {
 	return &TagDB{DB: db}
 }
 
-func (t *TagDB) CreateTag(tags []*tagEntity.Tag) error {
+func (t *TagDB) CreateTag(ctx context.Context, tags []*tagEntity.Tag) error {
 	for _, tag := range tags {
 		var exists bool
-		_ = t.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tags WHERE name = $1)", tag.Name).Scan(&exists)
+		_ = t.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM tags WHERE name = $1)", tag.Name).Scan(&exists)
 		if exists {
 			continue
 		}
 
-		stmt, err := t.DB.Prepare("INSERT INTO tags (id, name) VALUES ($1, $2)")
+		stmt, err := t.DB.PrepareContext(ctx, "INSERT INTO tags (id, name) VALUES ($1, $2)")
 		if err != nil {
-			fmt.Println("error preparing insert statement")
+			slog.ErrorContext(ctx, "error preparing insert statement", slog.String("error", err.Error()))
 			return err
 		}
 		defer stmt.Close()
 
-		_, err = stmt.Exec(tag.ID, tag.Name)
+		_, err = stmt.ExecContext(ctx, tag.ID, tag.Name)
 		if err != nil {
-			fmt.Println("error inserting tag")
+			slog.ErrorContext(ctx, "error inserting tag", slog.String("error", err.Error()),
+				slog.String("tag", tag.Name))
 			return err
 		}
 	}
@@ -77,13 +96,82 @@ func (t *TagDB) CreateTag(tags []*tagEntity.Tag) error {
 	return nil
 }
 
-func (t *TagDB) ListTags() ([]*tagEntity.Tag, error) {
-	rows, err := t.DB.Query("SELECT id, name FROM tags")
+// LinkArticleTags records in article_tags that the article has the tags with
+// these names. Links that exist already are kept.
+func (t *TagDB) LinkArticleTags(ctx context.Context, articleID string, names []string) error {
+	_, err := t.DB.ExecContext(ctx, `INSERT INTO article_tags (article_id, tag_id, article_created_at)
+		SELECT a.id, t.id, a.createdAt FROM articles a JOIN tags t ON t.name = ANY($2)
+		WHERE a.id = $1 ON CONFLICT DO NOTHING`, articleID, pq.Array(names))
+	if err != nil {
+		return fmt.Errorf("error linking tags: %w", err)
+	}
+	return nil
+}
+
+// RenameTag renames the tag in the tag list of every article, merging it
+// into the tag named to when that one exists already, and returns the slugs
+// of the articles changed. It returns sql.ErrNoRows when there is no tag
+// named from.
+func (t *TagDB) RenameTag(ctx context.Context, from, to string) ([]string, error) {
+	tx, err := t.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var fromID string
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM tags WHERE name = $1 FOR UPDATE", from).Scan(&fromID); err != nil {
+		return nil, err
+	}
+
+	var toID string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM tags WHERE name = $1 FOR UPDATE", to).Scan(&toID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		_, err = tx.ExecContext(ctx, "UPDATE tags SET name = $1 WHERE id = $2", to, fromID)
+	case err == nil:
+		_, err = tx.ExecContext(ctx, `INSERT INTO article_tags (article_id, tag_id, article_created_at)
+			SELECT article_id, $1, article_created_at FROM article_tags WHERE tag_id = $2 ON CONFLICT DO NOTHING`, toID, fromID)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "DELETE FROM tags WHERE id = $1", fromID)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error renaming tag: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `UPDATE articles SET tag_list = CASE
+			WHEN $2 = ANY(tag_list) THEN array_remove(tag_list, $1)
+			ELSE array_replace(tag_list, $1, $2)
+		END, version = version + 1
+		WHERE $1 = ANY(tag_list) RETURNING slug`, from, to)
 	if err != nil {
-		fmt.Errorf("error querying tags: %v", err)
+		return nil, fmt.Errorf("error renaming tag: %w", err)
+	}
+
+	slugs := []string{}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
+	return slugs, tx.Commit()
+}
+
+func (t *TagDB) ListTags(ctx context.Context) ([]*tagEntity.Tag, error) {
+	rows, err := t.DB.QueryContext(ctx, "SELECT id, name FROM tags")
+	if err != nil {
+		return nil, fmt.Errorf("error querying tags: %w", err)
+	}
+
 	defer rows.Close()
 
 	var tags []*tagEntity.Tag
@@ -93,8 +181,7 @@ func (t *TagDB) ListTags() ([]*tagEntity.Tag, error) {
 
 		err = rows.Scan(&tag.ID, &tag.Name)
 		if err != nil {
-			fmt.Errorf("error scan", err)
-			return nil, err
+			return nil, fmt.Errorf("error scanning tag: %w", err)
 		}
 		tags = append(tags, tag)
 	}
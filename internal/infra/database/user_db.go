@@ -1,15 +1,19 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
-	"log"
+	"log/slog"
+	"strings"
 
+	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
 	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
 	"github.com/sallescosta/conduit-api/pkg/entity"
 	"github.com/sallescosta/conduit-api/pkg/helpers"
+	"github.com/sallescosta/conduit-api/pkg/tracing"
 )
 
 type Error error
@@ -32,19 +36,46 @@ func CreateUsersTable(db *sql.DB) error {
             bio TEXT,
             image VARCHAR(255),
             following TEXT[],
-            favorites TEXT[]
+            favorites TEXT[],
+            role VARCHAR(20) NOT NULL DEFAULT 'user',
+            banned BOOLEAN NOT NULL DEFAULT FALSE,
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
         );
+
+        ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
+        ALTER TABLE users ADD COLUMN IF NOT EXISTS banned BOOLEAN NOT NULL DEFAULT FALSE;
+        ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+        CREATE INDEX IF NOT EXISTS users_username_idx ON users (username);
     `
 	_, err := db.Exec(query)
 	if err != nil {
-		log.Fatal(err)
-		return err
+		return fmt.Errorf("error creating users table: %w", err)
 	}
 
-	fmt.Println("Users table created")
+	slog.Info("table ready", slog.String("table", "users"))
 	return nil
 }
 
+const userColumns = "id, username, email, password, bio, image, following, favorites, role, banned"
+
+func scanUser(row rowScanner) (*userEntity.User, error) {
+	var user userEntity.User
+
+	err := row.Scan(&user.ID, &user.UserName, &user.Email, &user.Password, &user.Bio, &user.Image,
+		pq.Array(&user.Following), pq.Array(&user.Favorites), &user.Role, &user.Banned)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// escapeLike makes s match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 type UserDB struct {
 	DB *sql.DB
 }
@@ -53,13 +84,13 @@ func NewUser(db *sql.DB) *UserDB {
 	return &UserDB{DB: db}
 }
 
-func (u *UserDB) CreateUser(user *userEntity.User) error {
-	stmt, err := u.DB.Prepare("INSERT INTO users (id, username, email, password, bio, image, following, " +
-		"favorites) VALUES ($1," +
-		" $2," +
-		" $3, $4, $5, $6, $7, $8)")
+func (u *UserDB) CreateUser(ctx context.Context, user *userEntity.User) error {
+	stmt, err := u.DB.PrepareContext(ctx, "INSERT INTO users (id, username, email, password, bio, image, following, "+
+		"favorites, role, banned) VALUES ($1,"+
+		" $2,"+
+		" $3, $4, $5, $6, $7, $8, $9, $10)")
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("error preparing insert statement: %w", err)
 	}
 
 	defer stmt.Close()
@@ -69,18 +100,24 @@ func (u *UserDB) CreateUser(user *userEntity.User) error {
 		following[i] = id.String()
 	}
 
-	_, err = stmt.Exec(user.ID, user.UserName, user.Email, user.Password, user.Bio, user.Image, pq.Array(following),
-		pq.Array(user.Favorites))
+	role := user.Role
+	if role == "" {
+		role = userEntity.RoleUser
+	}
+
+	_, err = stmt.ExecContext(ctx, user.ID, user.UserName, user.Email, user.Password, user.Bio, user.Image, pq.Array(following),
+		pq.Array(user.Favorites), role, user.Banned)
 	if err != nil {
-		log.Fatal(err)
+		slog.ErrorContext(ctx, "error inserting user", slog.String("error", err.Error()))
+		return fmt.Errorf("error inserting user: %w", err)
 	}
 
 	return nil
 }
 
-func (u *UserDB) FindUserBy(field, value string) (*userEntity.User, error) {
-	query := fmt.Sprintf("SELECT id, username, email, password, bio, image, following, favorites FROM users WHERE %s = $1", field)
-	stmt, err := u.DB.Prepare(query)
+func (u *UserDB) FindUserBy(ctx context.Context, field, value string) (*userEntity.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE %s = $1", userColumns, field)
+	stmt, err := u.DB.PrepareContext(ctx, query)
 
 	if err != nil {
 		return nil, err
@@ -88,10 +125,7 @@ func (u *UserDB) FindUserBy(field, value string) (*userEntity.User, error) {
 
 	defer stmt.Close()
 
-	var user userEntity.User
-
-	err = stmt.QueryRow(value).Scan(&user.ID, &user.UserName, &user.Email, &user.Password, &user.Bio, &user.Image,
-		pq.Array(&user.Following), pq.Array(&user.Favorites))
+	user, err := scanUser(stmt.QueryRowContext(ctx, value))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -99,22 +133,27 @@ func (u *UserDB) FindUserBy(field, value string) (*userEntity.User, error) {
 		return nil, err
 	}
 
-	return &user, nil
+	return user, nil
 }
 
-func (u *UserDB) FindByEmail(email string) (*userEntity.User, error) {
-	return u.FindUserBy("email", email)
+func (u *UserDB) FindByEmail(ctx context.Context, email string) (*userEntity.User, error) {
+	return u.FindUserBy(ctx, "email", email)
 }
 
-func (u *UserDB) FindById(id string) (*userEntity.User, error) {
-	return u.FindUserBy("id", id)
+func (u *UserDB) FindById(ctx context.Context, id string) (*userEntity.User, error) {
+	return u.FindUserBy(ctx, "id", id)
 }
 
-func (u *UserDB) UpdateUserDb(email, username, password, image, bio string) (*userEntity.User, error) {
-	user, err := u.FindByEmail(email)
+// UpdateUserDb changes the non-empty fields of the user with this ID. The
+// email is never changed.
+func (u *UserDB) UpdateUserDb(ctx context.Context, id, username, password, image, bio string) (*userEntity.User, error) {
+	user, err := u.FindById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if user == nil {
+		return nil, sql.ErrNoRows
+	}
 
 	if username != "" {
 		user.UserName = username
@@ -128,18 +167,24 @@ func (u *UserDB) UpdateUserDb(email, username, password, image, bio string) (*us
 		user.Bio = bio
 	}
 
-	hashedPass, err := userEntity.DoHash(password)
 	if password != "" {
+		_, span := tracing.Start(ctx, "bcrypt.hash")
+		hashedPass, err := userEntity.DoHash(password)
+		tracing.SetError(span, err)
+		span.End()
+		if err != nil {
+			return nil, err
+		}
 		user.Password = string(hashedPass)
 	}
 
-	stmt, err := u.DB.Prepare("UPDATE users SET username = $1, password = $2, image = $3, bio = $4 WHERE email = $5")
+	stmt, err := u.DB.PrepareContext(ctx, "UPDATE users SET username = $1, password = $2, image = $3, bio = $4, updated_at = NOW() WHERE id = $5")
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(user.UserName, user.Password, user.Image, user.Bio, email)
+	_, err = stmt.ExecContext(ctx, user.UserName, user.Password, user.Image, user.Bio, id)
 	if err != nil {
 		return nil, err
 	}
@@ -147,40 +192,81 @@ func (u *UserDB) UpdateUserDb(email, username, password, image, bio string) (*us
 	return user, nil
 }
 
-func (u *UserDB) GetAllUsers() ([]userEntity.User, error) {
-	rows, err := u.DB.Query("SELECT id, username, email, password, bio, image, following, favorites FROM users")
+// UsersQuery searches users by username or email, a page at a time.
+type UsersQuery struct {
+	Search string
+	Limit  int
+	Offset int
+}
+
+type UsersPage struct {
+	Users []userEntity.User
+	Total int
+}
+
+func (u *UserDB) ListUsers(ctx context.Context, q UsersQuery) (*UsersPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	filter := ""
+	var args []interface{}
+	if q.Search != "" {
+		filter = "WHERE username ILIKE $1 OR email ILIKE $1"
+		args = append(args, "%"+escapeLike(q.Search)+"%")
+	}
+
+	page := &UsersPage{Users: []userEntity.User{}}
+
+	err := u.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users "+filter, args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM users %s ORDER BY username, id LIMIT $%d OFFSET $%d",
+		userColumns, filter, len(args)+1, len(args)+2)
+	rows, err := u.DB.QueryContext(ctx, query, append(args, q.Limit, q.Offset)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var users []userEntity.User
 	for rows.Next() {
-		var user userEntity.User
-
-		err := rows.Scan(&user.ID, &user.UserName, &user.Email, &user.Password, &user.Bio, &user.Image,
-			pq.Array(&user.Following), pq.Array(&user.Favorites))
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		users = append(users, user)
+		page.Users = append(page.Users, *user)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	if len(users) == 0 {
-		users = []userEntity.User{}
-	}
+	return page, nil
+}
+
+func (u *UserDB) SetBanned(ctx context.Context, id string, banned bool) error {
+	_, err := u.DB.ExecContext(ctx, "UPDATE users SET banned = $1 WHERE id = $2", banned, id)
+	return err
+}
 
-	return users, nil
+func (u *UserDB) SetRole(ctx context.Context, id string, role string) error {
+	_, err := u.DB.ExecContext(ctx, "UPDATE users SET role = $1 WHERE id = $2", role, id)
+	return err
 }
 
-func (u *UserDB) GetProfileDb(userName string) (*ProfileWithId, error) {
+// PromoteAdmins gives the admin role to the users with these emails, so a
+// fresh install has someone who can use the admin API.
+func (u *UserDB) PromoteAdmins(ctx context.Context, emails []string) error {
+	_, err := u.DB.ExecContext(ctx, "UPDATE users SET role = $1 WHERE email = ANY($2)", userEntity.RoleAdmin, pq.Array(emails))
+	return err
+}
+
+func (u *UserDB) GetProfileDb(ctx context.Context, userName string) (*ProfileWithId, error) {
 	query := "SELECT id, bio, image FROM users WHERE username = $1"
-	stmt, err := u.DB.Prepare(query)
+	stmt, err := u.DB.PrepareContext(ctx, query)
 
 	if err != nil {
 		return nil, err
@@ -190,7 +276,7 @@ func (u *UserDB) GetProfileDb(userName string) (*ProfileWithId, error) {
 
 	var profile ProfileWithId
 
-	err = stmt.QueryRow(userName).Scan(&profile.Profile.ID, &profile.Profile.Bio, &profile.Profile.Image)
+	err = stmt.QueryRowContext(ctx, userName).Scan(&profile.Profile.ID, &profile.Profile.Bio, &profile.Profile.Image)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -202,8 +288,8 @@ func (u *UserDB) GetProfileDb(userName string) (*ProfileWithId, error) {
 	return &profile, nil
 }
 
-func (u *UserDB) UpdateFollowingUserDb(id string, following []entity.ID) error {
-	stmt, err := u.DB.Prepare("UPDATE users SET following = $1 WHERE id = $2")
+func (u *UserDB) UpdateFollowingUserDb(ctx context.Context, id string, following []entity.ID) error {
+	stmt, err := u.DB.PrepareContext(ctx, "UPDATE users SET following = $1 WHERE id = $2")
 	if err != nil {
 		return err
 	}
@@ -214,7 +300,7 @@ func (u *UserDB) UpdateFollowingUserDb(id string, following []entity.ID) error {
 		followingStr[i] = id.String()
 	}
 
-	_, err = stmt.Exec(pq.Array(followingStr), id)
+	_, err = stmt.ExecContext(ctx, pq.Array(followingStr), id)
 	if err != nil {
 		return err
 	}
@@ -222,16 +308,16 @@ func (u *UserDB) UpdateFollowingUserDb(id string, following []entity.ID) error {
 	return nil
 }
 
-func (u *UserDB) FavoriteArticleDB(slug string, isAddToFavorite bool, userID string) error {
+func (u *UserDB) FavoriteArticleDB(ctx context.Context, slug string, isAddToFavorite bool, userID string) error {
 	articleDB := NewArticle(u.DB)
-	articleToFavorite, err := articleDB.GetArticleBySlug(slug)
+	articleToFavorite, err := articleDB.GetArticleBySlug(ctx, slug)
 	if err != nil {
 		return err
 	}
 
 	articleId := articleToFavorite.ID
 
-	user, err := u.FindById(userID)
+	user, err := u.FindById(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -261,7 +347,7 @@ func (u *UserDB) FavoriteArticleDB(slug string, isAddToFavorite bool, userID str
 		}
 	}
 
-	stmt, err := u.DB.Prepare("UPDATE users SET favorites = $1 WHERE id = $2")
+	stmt, err := u.DB.PrepareContext(ctx, "UPDATE users SET favorites = $1 WHERE id = $2")
 	if err != nil {
 		return err
 	}
@@ -272,21 +358,223 @@ func (u *UserDB) FavoriteArticleDB(slug string, isAddToFavorite bool, userID str
 		favoritesStr[i] = id.String()
 	}
 
-	_, err = stmt.Exec(pq.Array(favoritesStr), userID)
+	_, err = stmt.ExecContext(ctx, pq.Array(favoritesStr), userID)
 	if err != nil {
 		return err
 	}
 
-	stmt, err = u.DB.Prepare("UPDATE articles SET favoritesCount = $1 WHERE id = $2")
+	stmt, err = u.DB.PrepareContext(ctx, "UPDATE articles SET favoritesCount = $1, version = version + 1 WHERE id = $2")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(articleToFavorite.FavoritesCount, articleId.String())
+	_, err = stmt.ExecContext(ctx, articleToFavorite.FavoritesCount, articleId.String())
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// DeletedAccount lists what a deletion changed besides the account itself, so
+// that caches can drop it.
+type DeletedAccount struct {
+	UserName     string
+	Users        []string
+	ArticleSlugs []string
+	// ArticleIDs are the articles deleted with the account.
+	ArticleIDs  []string
+	TagsChanged bool
+}
+
+// DeleteUser removes the user in one transaction. Follows, favorites,
+// notifications, notification preferences, webhooks and exports go with the
+// account. With userEntity.DeleteContent the user's articles are deleted,
+// together with their comments and with the tags no other article uses, and
+// so are the user's comments, except those with replies, which are kept as
+// deleted placeholders. With userEntity.AnonymizeContent articles and
+// comments are reassigned to the deleted-user placeholder instead.
+// DeleteUser returns sql.ErrNoRows when the user does not exist.
+func (u *UserDB) DeleteUser(ctx context.Context, id string, mode string) (*DeletedAccount, error) {
+	tx, err := u.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted := &DeletedAccount{Users: []string{}, ArticleSlugs: []string{}, ArticleIDs: []string{}}
+
+	var favorites []string
+	err = tx.QueryRowContext(ctx, "SELECT username, favorites FROM users WHERE id = $1 FOR UPDATE", id).
+		Scan(&deleted.UserName, pq.Array(&favorites))
+	if err != nil {
+		return nil, err
+	}
+
+	followers, err := queryStrings(ctx, tx,
+		"UPDATE users SET following = array_remove(following, $1) WHERE $1 = ANY(following) RETURNING id", id)
+	if err != nil {
+		return nil, fmt.Errorf("error removing follows: %w", err)
+	}
+	deleted.Users = append(deleted.Users, followers...)
+
+	favorited, err := queryStrings(ctx, tx,
+		`UPDATE articles SET favoritesCount = GREATEST(favoritesCount - 1, 0), version = version + 1
+			WHERE id = ANY($1) RETURNING slug`,
+		pq.Array(favorites))
+	if err != nil {
+		return nil, fmt.Errorf("error removing favorites: %w", err)
+	}
+	deleted.ArticleSlugs = append(deleted.ArticleSlugs, favorited...)
+
+	for _, query := range []string{
+		"DELETE FROM notifications WHERE recipient_id = $1",
+		"UPDATE notifications SET actor_ids = array_remove(actor_ids, $1) WHERE $1 = ANY(actor_ids)",
+		"DELETE FROM notifications WHERE cardinality(actor_ids) = 0",
+		"DELETE FROM notification_preferences WHERE user_id = $1",
+		"DELETE FROM webhooks WHERE owner_id = $1",
+		"DELETE FROM exports WHERE user_id = $1",
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return nil, fmt.Errorf("error deleting account data: %w", err)
+		}
+	}
+
+	if mode == userEntity.AnonymizeContent {
+		err = u.anonymizeContent(ctx, tx, id, deleted)
+	} else {
+		err = u.deleteContent(ctx, tx, id, deleted)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id); err != nil {
+		return nil, fmt.Errorf("error deleting user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// deleteContent deletes the articles of the user the way DeleteArticleDB does
+// and then the user's comments on other articles.
+func (u *UserDB) deleteContent(ctx context.Context, tx *sql.Tx, id string, deleted *DeletedAccount) error {
+	tags, err := queryStrings(ctx, tx, "SELECT DISTINCT unnest(tag_list) FROM articles WHERE author_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting articles: %w", err)
+	}
+
+	slugs, err := queryStrings(ctx, tx, "SELECT slug FROM articles WHERE author_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting articles: %w", err)
+	}
+	deleted.ArticleSlugs = append(deleted.ArticleSlugs, slugs...)
+
+	favoritedBy, err := queryStrings(ctx, tx, `UPDATE users
+		SET favorites = ARRAY(SELECT f FROM unnest(favorites) f WHERE f NOT IN (SELECT id FROM articles WHERE author_id = $1))
+		WHERE favorites && ARRAY(SELECT id::text FROM articles WHERE author_id = $1)
+		RETURNING id`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting articles: %w", err)
+	}
+	deleted.Users = append(deleted.Users, favoritedBy...)
+
+	for _, query := range []string{
+		"DELETE FROM comments WHERE article_id IN (SELECT id FROM articles WHERE author_id = $1)",
+		"DELETE FROM notifications WHERE article_id IN (SELECT id FROM articles WHERE author_id = $1)",
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("error deleting articles: %w", err)
+		}
+	}
+
+	articleIDs, err := queryStrings(ctx, tx, "DELETE FROM articles WHERE author_id = $1 RETURNING id", id)
+	if err != nil {
+		return fmt.Errorf("error deleting articles: %w", err)
+	}
+	deleted.ArticleIDs = append(deleted.ArticleIDs, articleIDs...)
+
+	if len(tags) > 0 {
+		res, err := tx.ExecContext(ctx, `DELETE FROM tags t WHERE t.name = ANY($1)
+			AND NOT EXISTS (SELECT 1 FROM articles a WHERE t.name = ANY(a.tag_list))`, pq.Array(tags))
+		if err != nil {
+			return fmt.Errorf("error deleting tags: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			deleted.TagsChanged = true
+		}
+	}
+
+	if err := ensureDeletedUser(ctx, tx); err != nil {
+		return err
+	}
+
+	// comments with replies become placeholders, as in DeleteCommentsDb
+	_, err = tx.ExecContext(ctx, `UPDATE comments c SET body = $2, deleted = TRUE, author_id = $3, updatedAt = NOW()
+		WHERE author_id = $1 AND EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id)`,
+		id, entityComment.DeletedPlaceholder, userEntity.DeletedUserID)
+	if err != nil {
+		return fmt.Errorf("error deleting comments: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM comments WHERE author_id = $1", id); err != nil {
+		return fmt.Errorf("error deleting comments: %w", err)
+	}
+
+	return nil
+}
+
+func (u *UserDB) anonymizeContent(ctx context.Context, tx *sql.Tx, id string, deleted *DeletedAccount) error {
+	if err := ensureDeletedUser(ctx, tx); err != nil {
+		return err
+	}
+
+	slugs, err := queryStrings(ctx, tx,
+		"UPDATE articles SET author_id = $2, version = version + 1 WHERE author_id = $1 RETURNING slug",
+		id, userEntity.DeletedUserID)
+	if err != nil {
+		return fmt.Errorf("error anonymizing articles: %w", err)
+	}
+	deleted.ArticleSlugs = append(deleted.ArticleSlugs, slugs...)
+
+	if _, err := tx.ExecContext(ctx, "UPDATE comments SET author_id = $2 WHERE author_id = $1",
+		id, userEntity.DeletedUserID); err != nil {
+		return fmt.Errorf("error anonymizing comments: %w", err)
+	}
+
+	return nil
+}
+
+// ensureDeletedUser creates the deleted-user placeholder the first time
+// content is credited to it.
+func ensureDeletedUser(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO users (id, username, email, password, bio, image, following, favorites, role, banned)
+		VALUES ($1, $2, '', '', '', '', '{}', '{}', $3, TRUE) ON CONFLICT (id) DO NOTHING`,
+		userEntity.DeletedUserID, userEntity.DeletedUserName, userEntity.RoleUser)
+	if err != nil {
+		return fmt.Errorf("error creating the deleted user: %w", err)
+	}
+	return nil
+}
+
+func queryStrings(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
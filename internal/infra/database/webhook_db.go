@@ -0,0 +1,270 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+)
+
+func CreateWebhooksTable(db *sql.DB) error {
+	query := `
+        CREATE TABLE IF NOT EXISTS webhooks (
+            id VARCHAR(255) PRIMARY KEY,
+            owner_id VARCHAR(255) NOT NULL REFERENCES users (id),
+            url TEXT NOT NULL,
+            secret VARCHAR(255) NOT NULL,
+            events TEXT[] NOT NULL,
+            global BOOLEAN NOT NULL DEFAULT FALSE,
+            createdAt TIMESTAMP DEFAULT NOW()
+        );
+
+        CREATE INDEX IF NOT EXISTS webhooks_owner_idx ON webhooks (owner_id);
+    `
+	_, err := db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("error creating webhooks table: %w", err)
+	}
+
+	slog.Info("table ready", slog.String("table", "webhooks"))
+	return nil
+}
+
+// CreateWebhookDeliveriesTable stores payloads as TEXT rather than JSONB,
+// which would not keep the bytes the signature was computed over, and
+// schedules attempts with time zones so they compare right with NOW().
+func CreateWebhookDeliveriesTable(db *sql.DB) error {
+	query := `
+        CREATE TABLE IF NOT EXISTS webhook_deliveries (
+            id VARCHAR(255) PRIMARY KEY,
+            webhook_id VARCHAR(255) NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+            event VARCHAR(50) NOT NULL,
+            payload TEXT NOT NULL,
+            status VARCHAR(20) NOT NULL,
+            attempts INT NOT NULL DEFAULT 0,
+            response_status INT NOT NULL DEFAULT 0,
+            error TEXT NOT NULL DEFAULT '',
+            next_attempt_at TIMESTAMPTZ NOT NULL,
+            createdAt TIMESTAMPTZ DEFAULT NOW(),
+            updatedAt TIMESTAMPTZ DEFAULT NOW()
+        );
+
+        CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+            ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+        CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, createdAt DESC);
+    `
+	_, err := db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("error creating webhook_deliveries table: %w", err)
+	}
+
+	slog.Info("table ready", slog.String("table", "webhook_deliveries"))
+	return nil
+}
+
+type WebhookDB struct {
+	DB *sql.DB
+}
+
+func NewWebhook(db *sql.DB) *WebhookDB {
+	return &WebhookDB{DB: db}
+}
+
+const webhookColumns = "id, owner_id, url, secret, events, global, createdAt"
+
+func scanWebhook(row rowScanner) (*webhookEntity.Webhook, error) {
+	var hook webhookEntity.Webhook
+	err := row.Scan(&hook.ID, &hook.OwnerID, &hook.URL, &hook.Secret, pq.Array(&hook.Events), &hook.Global, &hook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (wd *WebhookDB) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]webhookEntity.Webhook, error) {
+	rows, err := wd.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []webhookEntity.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func (wd *WebhookDB) CreateWebhook(ctx context.Context, hook *webhookEntity.Webhook) error {
+	_, err := wd.DB.ExecContext(ctx,
+		"INSERT INTO webhooks (id, owner_id, url, secret, events, global, createdAt) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		hook.ID.String(), hook.OwnerID, hook.URL, hook.Secret, pq.Array(hook.Events), hook.Global, hook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating webhook: %w", err)
+	}
+	return nil
+}
+
+func (wd *WebhookDB) ListWebhooks(ctx context.Context, ownerID string) ([]webhookEntity.Webhook, error) {
+	return wd.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE owner_id = $1 ORDER BY createdAt, id", ownerID)
+}
+
+func (wd *WebhookDB) GetWebhook(ctx context.Context, id string) (*webhookEntity.Webhook, error) {
+	return scanWebhook(wd.DB.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+}
+
+func (wd *WebhookDB) DeleteWebhook(ctx context.Context, id string) error {
+	_, err := wd.DB.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	return err
+}
+
+// MatchWebhooks returns the webhooks subscribed to event that belong to
+// subjectID, the user the event concerns, and every global one.
+func (wd *WebhookDB) MatchWebhooks(ctx context.Context, event, subjectID string) ([]webhookEntity.Webhook, error) {
+	return wd.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE $1 = ANY(events) AND (global OR owner_id = $2)",
+		event, subjectID)
+}
+
+const deliveryColumns = "id, webhook_id, event, payload, status, attempts, response_status, error, next_attempt_at, createdAt, updatedAt"
+
+func scanDelivery(row rowScanner) (*webhookEntity.Delivery, error) {
+	var d webhookEntity.Delivery
+	var payload string
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus, &d.Error,
+		&d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = []byte(payload)
+	return &d, nil
+}
+
+func (wd *WebhookDB) CreateDelivery(ctx context.Context, d *webhookEntity.Delivery) error {
+	_, err := wd.DB.ExecContext(ctx, "INSERT INTO webhook_deliveries ("+deliveryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		d.ID.String(), d.WebhookID, d.Event, string(d.Payload), d.Status, d.Attempts, d.ResponseStatus, d.Error,
+		d.NextAttemptAt, d.CreatedAt, d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (wd *WebhookDB) GetDelivery(ctx context.Context, id string) (*webhookEntity.Delivery, error) {
+	return scanDelivery(wd.DB.QueryRowContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1", id))
+}
+
+type DeliveriesQuery struct {
+	Limit  int
+	Offset int
+}
+
+type DeliveriesPage struct {
+	Deliveries []webhookEntity.Delivery
+	Total      int
+}
+
+// ListDeliveries returns the delivery log of a webhook, the newest first.
+func (wd *WebhookDB) ListDeliveries(ctx context.Context, webhookID string, q DeliveriesQuery) (*DeliveriesPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	page := &DeliveriesPage{Deliveries: []webhookEntity.Delivery{}}
+
+	err := wd.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1", webhookID).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := wd.DB.QueryContext(ctx, "SELECT "+deliveryColumns+` FROM webhook_deliveries WHERE webhook_id = $1
+		ORDER BY createdAt DESC, id DESC LIMIT $2 OFFSET $3`, webhookID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Deliveries = append(page.Deliveries, *d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// DueDelivery is a delivery to attempt, with where and how to sign it.
+type DueDelivery struct {
+	webhookEntity.Delivery
+	URL    string
+	Secret string
+}
+
+// ClaimDueDeliveries picks up to limit pending deliveries whose time has
+// come and pushes their next attempt lease into the future, so other
+// instances do not pick them too while they are being sent.
+func (wd *WebhookDB) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueDelivery, error) {
+	query := `
+        UPDATE webhook_deliveries d SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+        FROM webhooks w
+        WHERE w.id = d.webhook_id AND d.id IN (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_status, d.error,
+            d.next_attempt_at, d.createdAt, d.updatedAt, w.url, w.secret
+    `
+	rows, err := wd.DB.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		var payload string
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus, &d.Error,
+			&d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		due = append(due, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return due, nil
+}
+
+// UpdateDelivery saves the outcome of an attempt.
+func (wd *WebhookDB) UpdateDelivery(ctx context.Context, d *webhookEntity.Delivery) error {
+	_, err := wd.DB.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = $2, response_status = $3,
+		error = $4, next_attempt_at = $5, updatedAt = $6 WHERE id = $7`,
+		d.Status, d.Attempts, d.ResponseStatus, d.Error, d.NextAttemptAt, d.UpdatedAt, d.ID.String())
+	return err
+}
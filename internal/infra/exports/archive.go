@@ -0,0 +1,119 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+)
+
+const readme = `This archive holds everything Conduit stores about your account.
+
+profile.json        your profile, without the password
+articles.json       your articles, as stored
+articles/*.md       a Markdown copy of each article, with its metadata as front matter
+revisions.json      the earlier versions of your articles, saved each time one was edited
+comments.json       the comments you wrote
+favorites.json      the articles you favorited
+follows.json        who you follow and who follows you
+notifications.json  your notifications
+`
+
+// Build writes the ZIP archive of data, dating its files at now.
+func Build(data *database.UserData, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	add := func(name string, content []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(content)
+		return err
+	}
+	addJSON := func(name string, v interface{}) error {
+		content, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return add(name, append(content, '\n'))
+	}
+
+	if err := add("README.txt", []byte(readme)); err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", data.User},
+		{"articles.json", data.Articles},
+		{"revisions.json", data.Revisions},
+		{"comments.json", data.Comments},
+		{"favorites.json", data.Favorites},
+		{"follows.json", map[string][]string{"following": data.Following, "followers": data.Followers}},
+		{"notifications.json", data.Notifications},
+	}
+	for _, f := range files {
+		if err := addJSON(f.name, f.value); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range data.Articles {
+		article := &data.Articles[i]
+		if err := add("articles/"+article.Slug+".md", []byte(markdownCopy(article))); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// markdownCopy is the article body preceded by YAML front matter. Values are
+// double-quoted, which YAML reads the same way Go quotes them.
+func markdownCopy(article *articleEntity.Article) string {
+	var sb strings.Builder
+
+	field := func(key, value string) {
+		if value != "" {
+			sb.WriteString(key + ": " + strconv.Quote(value) + "\n")
+		}
+	}
+
+	sb.WriteString("---\n")
+	field("title", article.Title)
+	field("slug", article.Slug)
+	field("description", article.Description)
+
+	tags := make([]string, len(article.TagList))
+	for i, tag := range article.TagList {
+		tags[i] = strconv.Quote(tag)
+	}
+	sb.WriteString("tags: [" + strings.Join(tags, ", ") + "]\n")
+
+	field("canonical_url", article.CanonicalURL)
+	field("cover_image", article.CoverImage)
+	field("license", article.License)
+	field("seo_description", article.SEODescription)
+	field("created_at", article.CreatedAt)
+	field("updated_at", article.UpdatedAt)
+	sb.WriteString("---\n\n")
+
+	sb.WriteString(article.Body)
+	if !strings.HasSuffix(article.Body, "\n") {
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
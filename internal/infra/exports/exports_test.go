@@ -0,0 +1,156 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	exportEntity "github.com/sallescosta/conduit-api/internal/entity/export"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func userData(t *testing.T) *database.UserData {
+	user, err := userEntity.NewUser("jake", "jake@jake.jake", "jakejake")
+	require.NoError(t, err)
+	article, err := articleEntity.NewArticle(user.ID.String(), `Say "hi"`, "Greetings", "# Hello", []string{"go", "intro"})
+	require.NoError(t, err)
+	revision := articleEntity.NewRevision(article)
+	article.Body = "# Hi\n\nThere"
+	article.License = "CC-BY-4.0"
+
+	return &database.UserData{
+		User:      user,
+		Articles:  []articleEntity.Article{*article},
+		Revisions: []articleEntity.Revision{*revision},
+		Favorites: []database.ArticleReference{{ID: "a1", Slug: "other", Title: "Other"}},
+		Following: []string{"anna"},
+		Followers: []string{},
+	}
+}
+
+func readZip(t *testing.T, archive []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestBuild(t *testing.T) {
+	archive, err := Build(userData(t), time.Now())
+	require.NoError(t, err)
+
+	files := readZip(t, archive)
+	assert.ElementsMatch(t, []string{"README.txt", "profile.json", "articles.json", "revisions.json", "comments.json",
+		"favorites.json", "follows.json", "notifications.json", "articles/say-hi.md"}, keys(files))
+
+	var profile map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(files["profile.json"]), &profile))
+	assert.Equal(t, "jake", profile["username"])
+	assert.NotContains(t, profile, "password")
+
+	assert.JSONEq(t, `{"following":["anna"],"followers":[]}`, files["follows.json"])
+
+	var revisions []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(files["revisions.json"]), &revisions))
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "# Hello", revisions[0]["body"])
+	assert.JSONEq(t, `[{"id":"a1","slug":"other","title":"Other"}]`, files["favorites.json"])
+
+	md := files["articles/say-hi.md"]
+	assert.Contains(t, md, "---\ntitle: \"Say \\\"hi\\\"\"\nslug: \"say-hi\"\ndescription: \"Greetings\"\ntags: [\"go\", \"intro\"]\n")
+	assert.Contains(t, md, "license: \"CC-BY-4.0\"\n")
+	assert.NotContains(t, md, "cover_image")
+	assert.Contains(t, md, "---\n\n# Hi\n\nThere\n")
+}
+
+func keys(m map[string]string) []string {
+	var k []string
+	for name := range m {
+		k = append(k, name)
+	}
+	return k
+}
+
+type fakeExports struct {
+	database.ExportInterface
+
+	pending   []exportEntity.Export
+	completed map[string][]byte
+	saved     []*exportEntity.Export
+	data      *database.UserData
+	collect   error
+	expired   int
+}
+
+func (f *fakeExports) ExpireExports(ctx context.Context) (int, error) {
+	f.expired++
+	return 0, nil
+}
+
+func (f *fakeExports) ClaimPendingExports(ctx context.Context, limit int, lease time.Duration) ([]exportEntity.Export, error) {
+	claimed := f.pending
+	f.pending = nil
+	return claimed, nil
+}
+
+func (f *fakeExports) CollectUserData(ctx context.Context, userID string) (*database.UserData, error) {
+	return f.data, f.collect
+}
+
+func (f *fakeExports) CompleteExport(ctx context.Context, e *exportEntity.Export, archive []byte) error {
+	copied := *e
+	f.saved = append(f.saved, &copied)
+	f.completed[e.ID.String()] = archive
+	return nil
+}
+
+func TestWorker_RunOnce(t *testing.T) {
+	e, err := exportEntity.NewExport("user-1")
+	require.NoError(t, err)
+
+	db := &fakeExports{pending: []exportEntity.Export{*e}, completed: map[string][]byte{}, data: userData(t)}
+	w := NewWorker(db, time.Hour, time.Second)
+
+	assert.Equal(t, 1, w.RunOnce(context.Background()))
+	assert.Equal(t, 1, db.expired)
+	require.Len(t, db.saved, 1)
+
+	saved := db.saved[0]
+	assert.Equal(t, exportEntity.StatusReady, saved.Status)
+	assert.Equal(t, len(db.completed[e.ID.String()]), saved.Size)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), saved.ExpiresAt, time.Minute)
+	assert.Contains(t, readZip(t, db.completed[e.ID.String()]), "profile.json")
+
+	assert.Equal(t, 0, w.RunOnce(context.Background()))
+}
+
+func TestWorker_RunOnceFailure(t *testing.T) {
+	e, err := exportEntity.NewExport("user-1")
+	require.NoError(t, err)
+
+	db := &fakeExports{pending: []exportEntity.Export{*e}, completed: map[string][]byte{}, collect: errors.New("connection reset")}
+	NewWorker(db, time.Hour, time.Second).RunOnce(context.Background())
+
+	require.Len(t, db.saved, 1)
+	assert.Equal(t, exportEntity.StatusFailed, db.saved[0].Status)
+	assert.Equal(t, "connection reset", db.saved[0].Error)
+	assert.Nil(t, db.completed[e.ID.String()])
+}
@@ -0,0 +1,100 @@
+package exports
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	exportEntity "github.com/sallescosta/conduit-api/internal/entity/export"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
+)
+
+const (
+	batchSize = 5
+	// lease keeps a claimed export from being built twice; a build that
+	// outlives it is picked up again by another worker.
+	lease = 5 * time.Minute
+)
+
+// Worker builds pending exports, polling every interval, and drops the
+// archives whose link expired. Archives are downloadable for TTL.
+type Worker struct {
+	ExportDB database.ExportInterface
+	TTL      time.Duration
+	Interval time.Duration
+}
+
+func NewWorker(exportDB database.ExportInterface, ttl, interval time.Duration) *Worker {
+	return &Worker{ExportDB: exportDB, TTL: ttl, Interval: interval}
+}
+
+// Run polls until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		for w.RunOnce(ctx) == batchSize {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce expires old archives, then builds one batch of pending exports
+// and returns its size.
+func (w *Worker) RunOnce(ctx context.Context) int {
+	if n, err := w.ExportDB.ExpireExports(ctx); err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error expiring exports", slog.String("error", err.Error()))
+		}
+	} else if n > 0 {
+		slog.InfoContext(ctx, "exports expired", slog.Int("count", n))
+	}
+
+	pending, err := w.ExportDB.ClaimPendingExports(ctx, batchSize, lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error claiming exports", slog.String("error", err.Error()))
+		}
+		return 0
+	}
+
+	for i := range pending {
+		w.build(ctx, &pending[i])
+	}
+
+	return len(pending)
+}
+
+func (w *Worker) build(ctx context.Context, e *exportEntity.Export) {
+	archive, err := w.archive(ctx, e.UserID)
+	now := time.Now().UTC()
+
+	if err != nil {
+		e.MarkFailed(err, now)
+		slog.ErrorContext(ctx, "export failed", slog.String("export_id", e.ID.String()), slog.String("error", err.Error()))
+	} else {
+		e.MarkReady(len(archive), now, w.TTL)
+	}
+
+	if err := w.ExportDB.CompleteExport(ctx, e, archive); err != nil {
+		slog.ErrorContext(ctx, "error saving export", slog.String("export_id", e.ID.String()), slog.String("error", err.Error()))
+		return
+	}
+
+	instrument.Exports.WithLabelValues(e.Status).Inc()
+}
+
+func (w *Worker) archive(ctx context.Context, userID string) ([]byte, error) {
+	data, err := w.ExportDB.CollectUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return Build(data, time.Now())
+}
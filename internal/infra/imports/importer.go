@@ -0,0 +1,264 @@
+package imports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+)
+
+const (
+	StatusCreated = "created"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+// Result is what happened to one file.
+type Result struct {
+	Path   string
+	Slug   string
+	Status string
+	Reason string
+}
+
+// Report sums up an import.
+type Report struct {
+	DryRun  bool
+	Results []Result
+	Created int
+	Skipped int
+	Failed  int
+}
+
+func (r *Report) add(result Result) {
+	r.Results = append(r.Results, result)
+	switch result.Status {
+	case StatusCreated:
+		r.Created++
+	case StatusSkipped:
+		r.Skipped++
+	default:
+		r.Failed++
+	}
+}
+
+// Write prints one line per file and the totals.
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, result := range r.Results {
+		status := result.Status
+		if r.DryRun && status == StatusCreated {
+			status = "would create"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", status, result.Path, result.Slug, result.Reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	created := "created"
+	if r.DryRun {
+		created = "to create"
+	}
+	_, err := fmt.Fprintf(w, "%d files: %d %s, %d skipped, %d failed\n",
+		len(r.Results), r.Created, created, r.Skipped, r.Failed)
+	return err
+}
+
+// Importer creates articles from Markdown files the way CreateArticle does,
+// keeping their slugs and dates. An article whose slug exists already is
+// skipped, so an import can be run again after fixing the files that
+// failed. Articles are written straight to the database: no webhooks or
+// notifications are sent for them.
+type Importer struct {
+	UserDB    database.UserInterface
+	ArticleDB database.ArticleInterface
+	TagDB     database.TagsInterface
+	// Author is the email of the author of the files without author_email.
+	Author string
+	DryRun bool
+
+	authors map[string]string
+	slugs   map[string]string
+}
+
+func NewImporter(userDB database.UserInterface, articleDB database.ArticleInterface, tagDB database.TagsInterface,
+	author string, dryRun bool) *Importer {
+	return &Importer{
+		UserDB:    userDB,
+		ArticleDB: articleDB,
+		TagDB:     tagDB,
+		Author:    author,
+		DryRun:    dryRun,
+		authors:   map[string]string{},
+		slugs:     map[string]string{},
+	}
+}
+
+// ImportDir imports every .md file under dir, in lexical order. It only
+// fails when dir cannot be read; failed files are in the report.
+func (i *Importer) ImportDir(ctx context.Context, dir string) (*Report, error) {
+	report := &Report{DryRun: i.DryRun}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			report.add(Result{Path: rel, Status: StatusFailed, Reason: err.Error()})
+			return nil
+		}
+
+		report.add(i.Import(ctx, rel, content))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Import creates the article of one file.
+func (i *Importer) Import(ctx context.Context, path string, content []byte) Result {
+	result := Result{Path: path, Status: StatusFailed}
+
+	post, err := Parse(content, time.Now())
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+	result.Slug = post.Slug
+
+	if other, ok := i.slugs[post.Slug]; ok {
+		result.Reason = "same slug as " + other
+		return result
+	}
+	i.slugs[post.Slug] = path
+
+	authorID, err := i.author(ctx, post.AuthorEmail)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+
+	existing, err := i.ArticleDB.GetArticleBySlug(ctx, post.Slug)
+	switch {
+	case err == nil && existing.AuthorID != authorID:
+		result.Reason = "slug used by an article of another author"
+		return result
+	case err == nil:
+		// a previous run may have stopped before linking the tags
+		if !i.DryRun {
+			if err := i.TagDB.LinkArticleTags(ctx, existing.ID.String(), existing.TagList); err != nil {
+				result.Reason = err.Error()
+				return result
+			}
+		}
+		result.Status, result.Reason = StatusSkipped, "already imported"
+		return result
+	case !errors.Is(err, sql.ErrNoRows):
+		result.Reason = err.Error()
+		return result
+	}
+
+	article, err := newArticle(authorID, post)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+
+	if !i.DryRun {
+		if err := i.create(ctx, article); err != nil {
+			result.Reason = err.Error()
+			return result
+		}
+	}
+
+	result.Status = StatusCreated
+	return result
+}
+
+func (i *Importer) create(ctx context.Context, article *articleEntity.Article) error {
+	tags := make([]*tagEntity.Tag, len(article.TagList))
+	for n, name := range article.TagList {
+		tags[n] = tagEntity.NewTag(name)
+	}
+
+	if err := i.TagDB.CreateTag(ctx, tags); err != nil {
+		return fmt.Errorf("error creating tags: %w", err)
+	}
+	if err := i.ArticleDB.CreateArticle(ctx, article); err != nil {
+		return err
+	}
+	return i.TagDB.LinkArticleTags(ctx, article.ID.String(), article.TagList)
+}
+
+// author resolves the email of the author of a post, or the default one.
+func (i *Importer) author(ctx context.Context, email string) (string, error) {
+	if email == "" {
+		email = i.Author
+	}
+	if email == "" {
+		return "", errors.New("no author_email and no default author")
+	}
+
+	if id, ok := i.authors[email]; ok {
+		return id, nil
+	}
+
+	user, err := i.UserDB.FindByEmail(ctx, email)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", fmt.Errorf("unknown author %s", email)
+	}
+
+	i.authors[email] = user.ID.String()
+	return i.authors[email], nil
+}
+
+func newArticle(authorID string, post *Post) (*articleEntity.Article, error) {
+	article, err := articleEntity.NewArticle(authorID, post.Title, post.Description, post.Body, post.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	article.Slug = post.Slug
+	article.CreatedAt = post.Published.Format(time.RFC3339)
+	article.UpdatedAt = post.Updated.Format(time.RFC3339)
+	article.CanonicalURL = post.CanonicalURL
+	article.CoverImage = post.CoverImage
+	article.License = post.License
+	article.SEODescription = post.SEODescription
+
+	if err := article.ValidateMetadata(); err != nil {
+		return nil, err
+	}
+	return article, nil
+}
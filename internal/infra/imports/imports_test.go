@@ -0,0 +1,192 @@
+package imports
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	post, err := Parse([]byte("---\ntitle: Hello, world\ntags:\n  - go\n  - intro\ndate: 2019-04-01\n"+
+		"author_email: jake@jake.jake\n---\n\n# Hi\n"), time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello, world", post.Title)
+	assert.Equal(t, "hello-world", post.Slug)
+	assert.Equal(t, []string{"go", "intro"}, post.Tags)
+	assert.Equal(t, "jake@jake.jake", post.AuthorEmail)
+	assert.Equal(t, time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC), post.Published)
+	assert.Equal(t, post.Published, post.Updated)
+	assert.Equal(t, "# Hi\n", post.Body)
+}
+
+func TestParse_ExportedArticle(t *testing.T) {
+	post, err := Parse([]byte("---\r\ntitle: \"Say \\\"hi\\\"\"\r\nslug: \"say-hi\"\r\ndescription: \"Greetings\"\r\n"+
+		"tags: [\"go\"]\r\nlicense: \"CC-BY-4.0\"\r\ncreated_at: \"2024-01-02T03:04:05Z\"\r\n"+
+		"updated_at: \"2024-02-02T03:04:05Z\"\r\n---\r\n\r\nThere\r\n"), time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, `Say "hi"`, post.Title)
+	assert.Equal(t, "say-hi", post.Slug)
+	assert.Equal(t, "CC-BY-4.0", post.License)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), post.Published)
+	assert.Equal(t, time.Date(2024, 2, 2, 3, 4, 5, 0, time.UTC), post.Updated)
+	assert.Equal(t, "There\n", post.Body)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	now := time.Now()
+
+	_, err := Parse([]byte("# No front matter\n"), now)
+	assert.ErrorIs(t, err, ErrNoFrontMatter)
+
+	_, err = Parse([]byte("---\ndescription: untitled\n---\nbody"), now)
+	assert.ErrorIs(t, err, ErrTitleRequired)
+
+	_, err = Parse([]byte("---\ntitle: T\nslug: Not A Slug\n---\n"), now)
+	assert.ErrorContains(t, err, "invalid slug")
+
+	_, err = Parse([]byte("---\ntitle: T\ndate: yesterday\n---\n"), now)
+	assert.ErrorContains(t, err, "invalid date")
+}
+
+type fakeUsers struct {
+	database.UserInterface
+	users map[string]*userEntity.User
+}
+
+func (f *fakeUsers) FindByEmail(ctx context.Context, email string) (*userEntity.User, error) {
+	return f.users[email], nil
+}
+
+type fakeArticles struct {
+	database.ArticleInterface
+	articles map[string]*articleEntity.Article
+}
+
+func (f *fakeArticles) GetArticleBySlug(ctx context.Context, slug string) (*articleEntity.Article, error) {
+	if article, ok := f.articles[slug]; ok {
+		return article, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeArticles) CreateArticle(ctx context.Context, article *articleEntity.Article) error {
+	f.articles[article.Slug] = article
+	return nil
+}
+
+type fakeTags struct {
+	database.TagsInterface
+	tags  map[string]bool
+	links map[string][]string
+}
+
+func (f *fakeTags) CreateTag(ctx context.Context, tags []*tagEntity.Tag) error {
+	for _, tag := range tags {
+		f.tags[tag.Name] = true
+	}
+	return nil
+}
+
+func (f *fakeTags) LinkArticleTags(ctx context.Context, articleID string, names []string) error {
+	f.links[articleID] = names
+	return nil
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestImporter_ImportDir(t *testing.T) {
+	jake, err := userEntity.NewUser("jake", "jake@jake.jake", "jakejake")
+	require.NoError(t, err)
+	anna, err := userEntity.NewUser("anna", "anna@anna.anna", "annaanna")
+	require.NoError(t, err)
+
+	dir := writeFiles(t, map[string]string{
+		"a.md":        "---\ntitle: First\ntags: [go]\ndate: 2020-05-06T07:08:09Z\n---\nOne",
+		"b.md":        "---\ntitle: Second\nauthor_email: anna@anna.anna\n---\nTwo",
+		"nested/c.md": "---\ntitle: Third\nauthor_email: nobody@example.com\n---\nThree",
+		"nested/d.md": "---\ntitle: First again\nslug: first\n---\nDuplicate",
+		"notes.txt":   "not markdown",
+		"nested/e.md": "no front matter",
+	})
+
+	users := &fakeUsers{users: map[string]*userEntity.User{jake.Email: jake, anna.Email: anna}}
+	articles := &fakeArticles{articles: map[string]*articleEntity.Article{}}
+	tags := &fakeTags{tags: map[string]bool{}, links: map[string][]string{}}
+	ctx := context.Background()
+
+	report, err := NewImporter(users, articles, tags, jake.Email, true).ImportDir(ctx, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 3, report.Failed)
+	assert.Empty(t, articles.articles, "a dry run creates nothing")
+
+	report, err = NewImporter(users, articles, tags, jake.Email, false).ImportDir(ctx, dir)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 5)
+	assert.Equal(t, Result{Path: "a.md", Slug: "first", Status: StatusCreated}, report.Results[0])
+	assert.Equal(t, Result{Path: "b.md", Slug: "second", Status: StatusCreated}, report.Results[1])
+	assert.Equal(t, "unknown author nobody@example.com", report.Results[2].Reason)
+	assert.Equal(t, "same slug as a.md", report.Results[3].Reason)
+	assert.Equal(t, ErrNoFrontMatter.Error(), report.Results[4].Reason)
+
+	first := articles.articles["first"]
+	require.NotNil(t, first)
+	assert.Equal(t, jake.ID.String(), first.AuthorID)
+	assert.Equal(t, "2020-05-06T07:08:09Z", first.CreatedAt)
+	assert.Equal(t, anna.ID.String(), articles.articles["second"].AuthorID)
+	assert.True(t, tags.tags["go"])
+	assert.Equal(t, []string{"go"}, tags.links[first.ID.String()])
+
+	report, err = NewImporter(users, articles, tags, jake.Email, false).ImportDir(ctx, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Created)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Len(t, articles.articles, 2)
+}
+
+func TestImporter_SlugOfAnotherAuthor(t *testing.T) {
+	jake, err := userEntity.NewUser("jake", "jake@jake.jake", "jakejake")
+	require.NoError(t, err)
+	existing, err := articleEntity.NewArticle("someone-else", "Taken", "", "body", nil)
+	require.NoError(t, err)
+
+	importer := NewImporter(&fakeUsers{users: map[string]*userEntity.User{jake.Email: jake}},
+		&fakeArticles{articles: map[string]*articleEntity.Article{"taken": existing}},
+		&fakeTags{tags: map[string]bool{}, links: map[string][]string{}}, jake.Email, false)
+
+	result := importer.Import(context.Background(), "taken.md", []byte("---\ntitle: Taken\n---\nmine"))
+	assert.Equal(t, StatusFailed, result.Status)
+	assert.Equal(t, "slug used by an article of another author", result.Reason)
+}
+
+func TestReport_Write(t *testing.T) {
+	report := &Report{DryRun: true}
+	report.add(Result{Path: "a.md", Slug: "a", Status: StatusCreated})
+	report.add(Result{Path: "b.md", Status: StatusFailed, Reason: "title is required"})
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf))
+	assert.Equal(t, "would create  a.md  a  \nfailed        b.md     title is required\n"+
+		"2 files: 1 to create, 0 skipped, 1 failed\n", buf.String())
+}
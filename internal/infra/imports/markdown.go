@@ -0,0 +1,128 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNoFrontMatter = errors.New("missing front matter")
+	ErrTitleRequired = errors.New("title is required")
+)
+
+// Post is a Markdown file ready to become an article.
+type Post struct {
+	Title          string
+	Description    string
+	Slug           string
+	Tags           []string
+	AuthorEmail    string
+	Published      time.Time
+	Updated        time.Time
+	CanonicalURL   string
+	CoverImage     string
+	License        string
+	SEODescription string
+	Body           string
+}
+
+// frontMatter also reads the created_at and updated_at keys written by the
+// personal data export, so exported articles can be imported back.
+type frontMatter struct {
+	Title          string   `yaml:"title"`
+	Description    string   `yaml:"description"`
+	Slug           string   `yaml:"slug"`
+	Tags           []string `yaml:"tags"`
+	AuthorEmail    string   `yaml:"author_email"`
+	Date           string   `yaml:"date"`
+	CreatedAt      string   `yaml:"created_at"`
+	UpdatedAt      string   `yaml:"updated_at"`
+	CanonicalURL   string   `yaml:"canonical_url"`
+	CoverImage     string   `yaml:"cover_image"`
+	License        string   `yaml:"license"`
+	SEODescription string   `yaml:"seo_description"`
+}
+
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// Parse reads a Markdown file that starts with YAML front matter between
+// "---" lines. The slug defaults to the one CreateArticle would make from the
+// title, the date to now and the update date to the date.
+func Parse(content []byte, now time.Time) (*Post, error) {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		return nil, ErrNoFrontMatter
+	}
+	header, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		if header, ok = strings.CutSuffix(rest, "\n---"); !ok {
+			return nil, ErrNoFrontMatter
+		}
+	}
+
+	var fm frontMatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	post := &Post{
+		Title:          strings.TrimSpace(fm.Title),
+		Description:    fm.Description,
+		Slug:           fm.Slug,
+		Tags:           fm.Tags,
+		AuthorEmail:    strings.TrimSpace(fm.AuthorEmail),
+		CanonicalURL:   fm.CanonicalURL,
+		CoverImage:     fm.CoverImage,
+		License:        fm.License,
+		SEODescription: fm.SEODescription,
+		Body:           strings.TrimLeft(body, "\n"),
+	}
+
+	if post.Title == "" {
+		return nil, ErrTitleRequired
+	}
+	if post.Slug == "" {
+		post.Slug = slug.Make(post.Title)
+	}
+	if !slug.IsSlug(post.Slug) {
+		return nil, fmt.Errorf("invalid slug %q", post.Slug)
+	}
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+
+	date := fm.Date
+	if date == "" {
+		date = fm.CreatedAt
+	}
+
+	var err error
+	if post.Published, err = parseDate(date, now); err != nil {
+		return nil, err
+	}
+	if post.Updated, err = parseDate(fm.UpdatedAt, post.Published); err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+func parseDate(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,116 @@
+package instrument
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "conduit_http_requests_total",
+		Help: "HTTP requests by method, chi route pattern and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "conduit_http_request_duration_seconds",
+		Help: "HTTP request latency by method and chi route pattern.",
+	}, []string{"method", "route"})
+	repositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "conduit_repository_call_duration_seconds",
+		Help: "Repository call latency by method and outcome.",
+	}, []string{"method", "outcome"})
+
+	Registrations = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "conduit_registrations_total", Help: "Users registered.",
+	})
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "conduit_logins_total", Help: "Login attempts by result.",
+	}, []string{"result"})
+	ArticlesCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "conduit_articles_created_total", Help: "Articles created.",
+	})
+	CommentsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "conduit_comments_created_total", Help: "Comments created.",
+	})
+	Favorites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "conduit_favorites_total", Help: "Favorites added and removed.",
+	}, []string{"action"})
+	Notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "conduit_notifications_total", Help: "Notifications delivered by type.",
+	}, []string{"type"})
+	WebhookAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "conduit_webhook_attempts_total", Help: "Webhook delivery attempts by event and outcome.",
+	}, []string{"event", "outcome"})
+	Exports = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "conduit_exports_total", Help: "Personal data exports built by outcome.",
+	}, []string{"outcome"})
+	AccountDeletions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "conduit_account_deletions_total", Help: "Deleted accounts by what happened to their content.",
+	}, []string{"content"})
+	Uploads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "conduit_uploads_total", Help: "Image uploads by kind and outcome.",
+	}, []string{"kind", "outcome"})
+)
+
+// Register adds the application metrics and the connection pool statistics
+// of db to reg. The metrics are shared by every registry they are added to,
+// so a registry per server, or per test, is fine.
+func Register(reg prometheus.Registerer, db *sql.DB, dbName string) error {
+	for _, c := range []prometheus.Collector{
+		httpRequests, httpDuration, repositoryDuration,
+		Registrations, Logins, ArticlesCreated, CommentsCreated, Favorites,
+		Notifications, WebhookAttempts, Exports, AccountDeletions, Uploads,
+		collectors.NewDBStatsCollector(db, dbName),
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Middleware records one request count and latency sample per request,
+// labelled with the chi route pattern so paths with IDs do not explode the
+// number of series.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RepositoryTimer is an Observer that records the duration of every
+// repository call.
+func RepositoryTimer(ctx context.Context, method string) (context.Context, func(error)) {
+	start := time.Now()
+
+	return ctx, func(err error) {
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		repositoryDuration.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+	}
+}
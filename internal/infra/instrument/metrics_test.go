@@ -0,0 +1,71 @@
+package instrument
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/api/articles/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/articles/some-slug", nil))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/api/articles/{slug}", "404")))
+	assert.Equal(t, 1, testutil.CollectAndCount(httpRequests), "one series for the route, not one per slug")
+}
+
+func TestRegister(t *testing.T) {
+	db, err := sql.Open("postgres", "sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	first, second := prometheus.NewRegistry(), prometheus.NewRegistry()
+	require.NoError(t, Register(first, db, "conduit"))
+	require.NoError(t, Register(second, db, "conduit"), "the metrics can back several registries")
+
+	var already prometheus.AlreadyRegisteredError
+	assert.ErrorAs(t, Register(first, db, "conduit"), &already)
+
+	families, err := second.Gather()
+	require.NoError(t, err)
+	var names []string
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	assert.Contains(t, names, "go_sql_open_connections")
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	observer := func(name string) Observer {
+		return func(ctx context.Context, method string) (context.Context, func(error)) {
+			calls = append(calls, name+" start "+method)
+			return ctx, func(err error) { calls = append(calls, name+" done "+err.Error()) }
+		}
+	}
+
+	_, done := Chain(observer("a"), observer("b"))(context.Background(), "TagDB.ListTags")
+	done(errors.New("boom"))
+
+	assert.Equal(t, []string{
+		"a start TagDB.ListTags",
+		"b start TagDB.ListTags",
+		"b done boom",
+		"a done boom",
+	}, calls)
+}
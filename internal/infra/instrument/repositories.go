@@ -0,0 +1,481 @@
+package instrument
+
+import (
+	"context"
+	"time"
+
+	"github.com/sallescosta/conduit-api/internal/dto"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	exportEntity "github.com/sallescosta/conduit-api/internal/entity/export"
+	notificationEntity "github.com/sallescosta/conduit-api/internal/entity/notification"
+	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+// Observer is called before every repository call with the method name.
+// It may return a derived context for the call and must return a function
+// that is called with the outcome once the call returns.
+type Observer func(ctx context.Context, method string) (context.Context, func(error))
+
+// Chain runs several observers around the same call, the first one outermost.
+func Chain(observers ...Observer) Observer {
+	return func(ctx context.Context, method string) (context.Context, func(error)) {
+		dones := make([]func(error), len(observers))
+		for i, observe := range observers {
+			ctx, dones[i] = observe(ctx, method)
+		}
+
+		return ctx, func(err error) {
+			for i := len(dones) - 1; i >= 0; i-- {
+				dones[i](err)
+			}
+		}
+	}
+}
+
+type UserRepository struct {
+	inner   database.UserInterface
+	observe Observer
+}
+
+func NewUserRepository(inner database.UserInterface, observe Observer) *UserRepository {
+	return &UserRepository{inner: inner, observe: observe}
+}
+
+func (u *UserRepository) CreateUser(ctx context.Context, user *userEntity.User) error {
+	ctx, done := u.observe(ctx, "UserDB.CreateUser")
+	err := u.inner.CreateUser(ctx, user)
+	done(err)
+	return err
+}
+
+func (u *UserRepository) FindByEmail(ctx context.Context, email string) (*userEntity.User, error) {
+	ctx, done := u.observe(ctx, "UserDB.FindByEmail")
+	user, err := u.inner.FindByEmail(ctx, email)
+	done(err)
+	return user, err
+}
+
+func (u *UserRepository) FindById(ctx context.Context, id string) (*userEntity.User, error) {
+	ctx, done := u.observe(ctx, "UserDB.FindById")
+	user, err := u.inner.FindById(ctx, id)
+	done(err)
+	return user, err
+}
+
+func (u *UserRepository) ListUsers(ctx context.Context, q database.UsersQuery) (*database.UsersPage, error) {
+	ctx, done := u.observe(ctx, "UserDB.ListUsers")
+	page, err := u.inner.ListUsers(ctx, q)
+	done(err)
+	return page, err
+}
+
+func (u *UserRepository) SetBanned(ctx context.Context, id string, banned bool) error {
+	ctx, done := u.observe(ctx, "UserDB.SetBanned")
+	err := u.inner.SetBanned(ctx, id, banned)
+	done(err)
+	return err
+}
+
+func (u *UserRepository) SetRole(ctx context.Context, id string, role string) error {
+	ctx, done := u.observe(ctx, "UserDB.SetRole")
+	err := u.inner.SetRole(ctx, id, role)
+	done(err)
+	return err
+}
+
+func (u *UserRepository) UpdateUserDb(ctx context.Context, id, username, password, image, bio string) (*userEntity.User, error) {
+	ctx, done := u.observe(ctx, "UserDB.UpdateUserDb")
+	user, err := u.inner.UpdateUserDb(ctx, id, username, password, image, bio)
+	done(err)
+	return user, err
+}
+
+func (u *UserRepository) GetProfileDb(ctx context.Context, userName string) (*database.ProfileWithId, error) {
+	ctx, done := u.observe(ctx, "UserDB.GetProfileDb")
+	profile, err := u.inner.GetProfileDb(ctx, userName)
+	done(err)
+	return profile, err
+}
+
+func (u *UserRepository) UpdateFollowingUserDb(ctx context.Context, id string, following []entity.ID) error {
+	ctx, done := u.observe(ctx, "UserDB.UpdateFollowingUserDb")
+	err := u.inner.UpdateFollowingUserDb(ctx, id, following)
+	done(err)
+	return err
+}
+
+func (u *UserRepository) FavoriteArticleDB(ctx context.Context, slug string, isAddToFavorite bool, userID string) error {
+	ctx, done := u.observe(ctx, "UserDB.FavoriteArticleDB")
+	err := u.inner.FavoriteArticleDB(ctx, slug, isAddToFavorite, userID)
+	done(err)
+	return err
+}
+
+func (u *UserRepository) DeleteUser(ctx context.Context, id string, mode string) (*database.DeletedAccount, error) {
+	ctx, done := u.observe(ctx, "UserDB.DeleteUser")
+	deleted, err := u.inner.DeleteUser(ctx, id, mode)
+	done(err)
+	return deleted, err
+}
+
+type ArticleRepository struct {
+	inner   database.ArticleInterface
+	observe Observer
+}
+
+func NewArticleRepository(inner database.ArticleInterface, observe Observer) *ArticleRepository {
+	return &ArticleRepository{inner: inner, observe: observe}
+}
+
+func (a *ArticleRepository) CreateArticle(ctx context.Context, article *articleEntity.Article) error {
+	ctx, done := a.observe(ctx, "ArticleDB.CreateArticle")
+	err := a.inner.CreateArticle(ctx, article)
+	done(err)
+	return err
+}
+
+func (a *ArticleRepository) ListAllArticles(ctx context.Context) ([]articleEntity.Article, error) {
+	ctx, done := a.observe(ctx, "ArticleDB.ListAllArticles")
+	articles, err := a.inner.ListAllArticles(ctx)
+	done(err)
+	return articles, err
+}
+
+func (a *ArticleRepository) QueryArticles(ctx context.Context, q database.ArticleQuery) (*database.ArticlesPage, error) {
+	ctx, done := a.observe(ctx, "ArticleDB.QueryArticles")
+	page, err := a.inner.QueryArticles(ctx, q)
+	done(err)
+	return page, err
+}
+
+func (a *ArticleRepository) GetArticleBySlug(ctx context.Context, slug string) (*articleEntity.Article, error) {
+	ctx, done := a.observe(ctx, "ArticleDB.GetArticleBySlug")
+	article, err := a.inner.GetArticleBySlug(ctx, slug)
+	done(err)
+	return article, err
+}
+
+func (a *ArticleRepository) UpdateArticle(ctx context.Context, slug string, article dto.ArticleUpdateInput) (*articleEntity.Article, error) {
+	ctx, done := a.observe(ctx, "ArticleDB.UpdateArticle")
+	updated, err := a.inner.UpdateArticle(ctx, slug, article)
+	done(err)
+	return updated, err
+}
+
+func (a *ArticleRepository) DeleteArticleDB(ctx context.Context, slug string) ([]string, error) {
+	ctx, done := a.observe(ctx, "ArticleDB.DeleteArticleDB")
+	users, err := a.inner.DeleteArticleDB(ctx, slug)
+	done(err)
+	return users, err
+}
+
+type CommentRepository struct {
+	inner   database.CommentInterface
+	observe Observer
+}
+
+func NewCommentRepository(inner database.CommentInterface, observe Observer) *CommentRepository {
+	return &CommentRepository{inner: inner, observe: observe}
+}
+
+func (c *CommentRepository) CreateCommentDb(ctx context.Context, comment *entityComment.Comment) error {
+	ctx, done := c.observe(ctx, "CommentDB.CreateCommentDb")
+	err := c.inner.CreateCommentDb(ctx, comment)
+	done(err)
+	return err
+}
+
+func (c *CommentRepository) GetCommentsDb(ctx context.Context, slug string, q database.CommentsQuery) (*database.CommentsPage, error) {
+	ctx, done := c.observe(ctx, "CommentDB.GetCommentsDb")
+	page, err := c.inner.GetCommentsDb(ctx, slug, q)
+	done(err)
+	return page, err
+}
+
+func (c *CommentRepository) GetCommentByIdDb(ctx context.Context, id string) (*entityComment.Comment, error) {
+	ctx, done := c.observe(ctx, "CommentDB.GetCommentByIdDb")
+	comment, err := c.inner.GetCommentByIdDb(ctx, id)
+	done(err)
+	return comment, err
+}
+
+func (c *CommentRepository) UpdateCommentDb(ctx context.Context, comment *entityComment.Comment) error {
+	ctx, done := c.observe(ctx, "CommentDB.UpdateCommentDb")
+	err := c.inner.UpdateCommentDb(ctx, comment)
+	done(err)
+	return err
+}
+
+func (c *CommentRepository) DeleteCommentsDb(ctx context.Context, id string) error {
+	ctx, done := c.observe(ctx, "CommentDB.DeleteCommentsDb")
+	err := c.inner.DeleteCommentsDb(ctx, id)
+	done(err)
+	return err
+}
+
+func (c *CommentRepository) PurgeCommentDb(ctx context.Context, id string) ([]string, error) {
+	ctx, done := c.observe(ctx, "CommentDB.PurgeCommentDb")
+	ids, err := c.inner.PurgeCommentDb(ctx, id)
+	done(err)
+	return ids, err
+}
+
+type TagRepository struct {
+	inner   database.TagsInterface
+	observe Observer
+}
+
+func NewTagRepository(inner database.TagsInterface, observe Observer) *TagRepository {
+	return &TagRepository{inner: inner, observe: observe}
+}
+
+func (t *TagRepository) CreateTag(ctx context.Context, tags []*tagEntity.Tag) error {
+	ctx, done := t.observe(ctx, "TagDB.CreateTag")
+	err := t.inner.CreateTag(ctx, tags)
+	done(err)
+	return err
+}
+
+func (t *TagRepository) ListTags(ctx context.Context) ([]*tagEntity.Tag, error) {
+	ctx, done := t.observe(ctx, "TagDB.ListTags")
+	tags, err := t.inner.ListTags(ctx)
+	done(err)
+	return tags, err
+}
+
+func (t *TagRepository) LinkArticleTags(ctx context.Context, articleID string, names []string) error {
+	ctx, done := t.observe(ctx, "TagDB.LinkArticleTags")
+	err := t.inner.LinkArticleTags(ctx, articleID, names)
+	done(err)
+	return err
+}
+
+func (t *TagRepository) RenameTag(ctx context.Context, from, to string) ([]string, error) {
+	ctx, done := t.observe(ctx, "TagDB.RenameTag")
+	slugs, err := t.inner.RenameTag(ctx, from, to)
+	done(err)
+	return slugs, err
+}
+
+type NotificationRepository struct {
+	inner   database.NotificationInterface
+	observe Observer
+}
+
+func NewNotificationRepository(inner database.NotificationInterface, observe Observer) *NotificationRepository {
+	return &NotificationRepository{inner: inner, observe: observe}
+}
+
+func (n *NotificationRepository) Notify(ctx context.Context, notification *notificationEntity.Notification) (bool, error) {
+	ctx, done := n.observe(ctx, "NotificationDB.Notify")
+	delivered, err := n.inner.Notify(ctx, notification)
+	done(err)
+	return delivered, err
+}
+
+func (n *NotificationRepository) ListNotifications(ctx context.Context, recipientID string, q database.NotificationsQuery) (*database.NotificationsPage, error) {
+	ctx, done := n.observe(ctx, "NotificationDB.ListNotifications")
+	page, err := n.inner.ListNotifications(ctx, recipientID, q)
+	done(err)
+	return page, err
+}
+
+func (n *NotificationRepository) MarkRead(ctx context.Context, recipientID, id string) error {
+	ctx, done := n.observe(ctx, "NotificationDB.MarkRead")
+	err := n.inner.MarkRead(ctx, recipientID, id)
+	done(err)
+	return err
+}
+
+func (n *NotificationRepository) MarkAllRead(ctx context.Context, recipientID string) error {
+	ctx, done := n.observe(ctx, "NotificationDB.MarkAllRead")
+	err := n.inner.MarkAllRead(ctx, recipientID)
+	done(err)
+	return err
+}
+
+func (n *NotificationRepository) GetPreferences(ctx context.Context, userID string) (notificationEntity.Preferences, error) {
+	ctx, done := n.observe(ctx, "NotificationDB.GetPreferences")
+	prefs, err := n.inner.GetPreferences(ctx, userID)
+	done(err)
+	return prefs, err
+}
+
+func (n *NotificationRepository) SetPreferences(ctx context.Context, userID string, prefs notificationEntity.Preferences) error {
+	ctx, done := n.observe(ctx, "NotificationDB.SetPreferences")
+	err := n.inner.SetPreferences(ctx, userID, prefs)
+	done(err)
+	return err
+}
+
+type WebhookRepository struct {
+	inner   database.WebhookInterface
+	observe Observer
+}
+
+func NewWebhookRepository(inner database.WebhookInterface, observe Observer) *WebhookRepository {
+	return &WebhookRepository{inner: inner, observe: observe}
+}
+
+func (w *WebhookRepository) CreateWebhook(ctx context.Context, hook *webhookEntity.Webhook) error {
+	ctx, done := w.observe(ctx, "WebhookDB.CreateWebhook")
+	err := w.inner.CreateWebhook(ctx, hook)
+	done(err)
+	return err
+}
+
+func (w *WebhookRepository) ListWebhooks(ctx context.Context, ownerID string) ([]webhookEntity.Webhook, error) {
+	ctx, done := w.observe(ctx, "WebhookDB.ListWebhooks")
+	hooks, err := w.inner.ListWebhooks(ctx, ownerID)
+	done(err)
+	return hooks, err
+}
+
+func (w *WebhookRepository) GetWebhook(ctx context.Context, id string) (*webhookEntity.Webhook, error) {
+	ctx, done := w.observe(ctx, "WebhookDB.GetWebhook")
+	hook, err := w.inner.GetWebhook(ctx, id)
+	done(err)
+	return hook, err
+}
+
+func (w *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, done := w.observe(ctx, "WebhookDB.DeleteWebhook")
+	err := w.inner.DeleteWebhook(ctx, id)
+	done(err)
+	return err
+}
+
+func (w *WebhookRepository) MatchWebhooks(ctx context.Context, event, subjectID string) ([]webhookEntity.Webhook, error) {
+	ctx, done := w.observe(ctx, "WebhookDB.MatchWebhooks")
+	hooks, err := w.inner.MatchWebhooks(ctx, event, subjectID)
+	done(err)
+	return hooks, err
+}
+
+func (w *WebhookRepository) CreateDelivery(ctx context.Context, d *webhookEntity.Delivery) error {
+	ctx, done := w.observe(ctx, "WebhookDB.CreateDelivery")
+	err := w.inner.CreateDelivery(ctx, d)
+	done(err)
+	return err
+}
+
+func (w *WebhookRepository) GetDelivery(ctx context.Context, id string) (*webhookEntity.Delivery, error) {
+	ctx, done := w.observe(ctx, "WebhookDB.GetDelivery")
+	d, err := w.inner.GetDelivery(ctx, id)
+	done(err)
+	return d, err
+}
+
+func (w *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string, q database.DeliveriesQuery) (*database.DeliveriesPage, error) {
+	ctx, done := w.observe(ctx, "WebhookDB.ListDeliveries")
+	page, err := w.inner.ListDeliveries(ctx, webhookID, q)
+	done(err)
+	return page, err
+}
+
+func (w *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]database.DueDelivery, error) {
+	ctx, done := w.observe(ctx, "WebhookDB.ClaimDueDeliveries")
+	due, err := w.inner.ClaimDueDeliveries(ctx, limit, lease)
+	done(err)
+	return due, err
+}
+
+func (w *WebhookRepository) UpdateDelivery(ctx context.Context, d *webhookEntity.Delivery) error {
+	ctx, done := w.observe(ctx, "WebhookDB.UpdateDelivery")
+	err := w.inner.UpdateDelivery(ctx, d)
+	done(err)
+	return err
+}
+
+type SitemapRepository struct {
+	inner   database.SitemapInterface
+	observe Observer
+}
+
+func NewSitemapRepository(inner database.SitemapInterface, observe Observer) *SitemapRepository {
+	return &SitemapRepository{inner: inner, observe: observe}
+}
+
+func (s *SitemapRepository) CountSitemapEntries(ctx context.Context) (int, error) {
+	ctx, done := s.observe(ctx, "SitemapDB.CountSitemapEntries")
+	count, err := s.inner.CountSitemapEntries(ctx)
+	done(err)
+	return count, err
+}
+
+func (s *SitemapRepository) ListSitemapEntries(ctx context.Context, offset, limit int) ([]database.SitemapEntry, error) {
+	ctx, done := s.observe(ctx, "SitemapDB.ListSitemapEntries")
+	entries, err := s.inner.ListSitemapEntries(ctx, offset, limit)
+	done(err)
+	return entries, err
+}
+
+type ExportRepository struct {
+	inner   database.ExportInterface
+	observe Observer
+}
+
+func NewExportRepository(inner database.ExportInterface, observe Observer) *ExportRepository {
+	return &ExportRepository{inner: inner, observe: observe}
+}
+
+func (e *ExportRepository) CreateExport(ctx context.Context, export *exportEntity.Export) error {
+	ctx, done := e.observe(ctx, "ExportDB.CreateExport")
+	err := e.inner.CreateExport(ctx, export)
+	done(err)
+	return err
+}
+
+func (e *ExportRepository) GetExport(ctx context.Context, id string) (*exportEntity.Export, error) {
+	ctx, done := e.observe(ctx, "ExportDB.GetExport")
+	export, err := e.inner.GetExport(ctx, id)
+	done(err)
+	return export, err
+}
+
+func (e *ExportRepository) PendingExport(ctx context.Context, userID string) (*exportEntity.Export, error) {
+	ctx, done := e.observe(ctx, "ExportDB.PendingExport")
+	export, err := e.inner.PendingExport(ctx, userID)
+	done(err)
+	return export, err
+}
+
+func (e *ExportRepository) GetExportArchive(ctx context.Context, token string) (*exportEntity.Export, []byte, error) {
+	ctx, done := e.observe(ctx, "ExportDB.GetExportArchive")
+	export, archive, err := e.inner.GetExportArchive(ctx, token)
+	done(err)
+	return export, archive, err
+}
+
+func (e *ExportRepository) ClaimPendingExports(ctx context.Context, limit int, lease time.Duration) ([]exportEntity.Export, error) {
+	ctx, done := e.observe(ctx, "ExportDB.ClaimPendingExports")
+	exports, err := e.inner.ClaimPendingExports(ctx, limit, lease)
+	done(err)
+	return exports, err
+}
+
+func (e *ExportRepository) CompleteExport(ctx context.Context, export *exportEntity.Export, archive []byte) error {
+	ctx, done := e.observe(ctx, "ExportDB.CompleteExport")
+	err := e.inner.CompleteExport(ctx, export, archive)
+	done(err)
+	return err
+}
+
+func (e *ExportRepository) ExpireExports(ctx context.Context) (int, error) {
+	ctx, done := e.observe(ctx, "ExportDB.ExpireExports")
+	n, err := e.inner.ExpireExports(ctx)
+	done(err)
+	return n, err
+}
+
+func (e *ExportRepository) CollectUserData(ctx context.Context, userID string) (*database.UserData, error) {
+	ctx, done := e.observe(ctx, "ExportDB.CollectUserData")
+	data, err := e.inner.CollectUserData(ctx, userID)
+	done(err)
+	return data, err
+}
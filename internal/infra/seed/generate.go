@@ -0,0 +1,318 @@
+// Package seed generates realistic demo data from a random seed and writes
+// it to the database, through the repositories or in bulk.
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+const (
+	// history is how far back articles go.
+	history = 365 * 24 * time.Hour
+	// maxReplyDepth stays below the default COMMENT_MAX_DEPTH.
+	maxReplyDepth = 3
+)
+
+// Config sizes the data set. Comments, Follows and Favorites are averages,
+// per article and per user.
+type Config struct {
+	Seed      int64
+	Users     int
+	Articles  int
+	Comments  int
+	Follows   int
+	Favorites int
+	// Now is the date of the newest content.
+	Now time.Time
+	// PasswordHash is shared by every user, since hashing thousands of
+	// passwords would dominate the run time.
+	PasswordHash string
+}
+
+// Generate builds the data set of cfg. The same Config always yields the
+// same data. A few users write most articles, a few tags are on most of
+// them, and a few users and articles get most follows and favorites.
+func Generate(cfg Config) (*database.SeedData, error) {
+	g := &generator{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed))}
+	data := &database.SeedData{}
+
+	if cfg.Users <= 0 {
+		return data, nil
+	}
+
+	data.Users = g.users()
+	data.Tags = g.tags()
+
+	var err error
+	if data.Articles, err = g.articles(data.Users); err != nil {
+		return nil, err
+	}
+	data.Comments = g.comments(data.Users, data.Articles)
+	g.follows(data.Users)
+	g.favorites(data.Users, data.Articles)
+
+	return data, nil
+}
+
+type generator struct {
+	cfg Config
+	rng *rand.Rand
+}
+
+func (g *generator) id() entity.ID {
+	id, _ := uuid.NewRandomFromReader(g.rng)
+	return id
+}
+
+// zipf picks indexes in [0, n) with the first ones far more likely.
+func (g *generator) zipf(n int) *rand.Zipf {
+	return rand.NewZipf(g.rng, 1.1, 2, uint64(n-1))
+}
+
+// around returns a count that averages mean.
+func (g *generator) around(mean int) int {
+	if mean <= 0 {
+		return 0
+	}
+	return g.rng.Intn(2*mean + 1)
+}
+
+func (g *generator) pick(list []string) string {
+	return list[g.rng.Intn(len(list))]
+}
+
+func (g *generator) users() []userEntity.User {
+	users := make([]userEntity.User, g.cfg.Users)
+	taken := map[string]bool{}
+
+	for i := range users {
+		username := g.pick(firstNames) + "." + g.pick(lastNames)
+		for n := 2; taken[username]; n++ {
+			username = fmt.Sprintf("%s.%s%d", g.pick(firstNames), g.pick(lastNames), n)
+		}
+		taken[username] = true
+
+		users[i] = userEntity.User{
+			ID:        g.id(),
+			UserName:  username,
+			Email:     username + "@example.com",
+			Password:  g.cfg.PasswordHash,
+			Bio:       g.pick(bios),
+			Image:     "",
+			Following: []entity.ID{},
+			Favorites: []entity.ID{},
+			Role:      userEntity.RoleUser,
+		}
+	}
+	return users
+}
+
+func (g *generator) tags() []tagEntity.Tag {
+	tags := make([]tagEntity.Tag, len(tagNames))
+	for i, name := range tagNames {
+		tags[i] = tagEntity.Tag{ID: g.id(), Name: name}
+	}
+	return tags
+}
+
+func (g *generator) articles(users []userEntity.User) ([]articleEntity.Article, error) {
+	articles := make([]articleEntity.Article, g.cfg.Articles)
+	authors := g.zipf(len(users))
+	tags := g.zipf(len(tagNames))
+	slugs := map[string]bool{}
+	oldest := g.cfg.Now.Add(-history)
+
+	for i := range articles {
+		base := g.title()
+		title := base
+		for n := 2; slugs[slug.Make(title)]; n++ {
+			title = fmt.Sprintf("%s, part %d", base, n)
+		}
+		slugs[slug.Make(title)] = true
+
+		tagList := []string{}
+		for n := 1 + g.rng.Intn(4); len(tagList) < n; {
+			tag := tagNames[tags.Uint64()]
+			if !contains(tagList, tag) {
+				tagList = append(tagList, tag)
+			}
+		}
+
+		author := users[authors.Uint64()]
+		article, err := articleEntity.NewArticle(author.ID.String(), title, g.sentence(8, 16), g.body(), tagList)
+		if err != nil {
+			return nil, err
+		}
+
+		created := oldest.Add(time.Duration(g.rng.Int63n(int64(history)))).UTC()
+		updated := created
+		if g.rng.Intn(4) == 0 {
+			updated = created.Add(time.Duration(g.rng.Int63n(int64(g.cfg.Now.Sub(created)) + 1)))
+		}
+
+		article.ID = g.id()
+		article.Slug = slug.Make(title)
+		article.CreatedAt = created.Format(time.RFC3339)
+		article.UpdatedAt = updated.Format(time.RFC3339)
+		articles[i] = *article
+	}
+	return articles, nil
+}
+
+// comments writes threads in order, so parents always come before replies.
+func (g *generator) comments(users []userEntity.User, articles []articleEntity.Article) []entityComment.Comment {
+	var comments []entityComment.Comment
+
+	for _, article := range articles {
+		created, _ := time.Parse(time.RFC3339, article.CreatedAt)
+		first := len(comments)
+
+		for n := g.around(g.cfg.Comments); n > 0; n-- {
+			created = created.Add(time.Duration(1+g.rng.Intn(24*60)) * time.Minute)
+			if created.After(g.cfg.Now) {
+				created = g.cfg.Now
+			}
+
+			c := entityComment.Comment{
+				ID:        g.id(),
+				Body:      g.sentence(5, 30),
+				AuthorID:  users[g.rng.Intn(len(users))].ID.String(),
+				ArticleID: article.ID.String(),
+				CreatedAt: created.UTC().Format(time.RFC3339),
+				UpdatedAt: created.UTC().Format(time.RFC3339),
+			}
+
+			if thread := comments[first:]; len(thread) > 0 && g.rng.Intn(3) == 0 {
+				parent := thread[g.rng.Intn(len(thread))]
+				if parent.Depth < maxReplyDepth {
+					c.ParentID = parent.ID.String()
+					c.Depth = parent.Depth + 1
+				}
+			}
+
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+func (g *generator) follows(users []userEntity.User) {
+	if len(users) < 2 {
+		return
+	}
+	popular := g.zipf(len(users))
+
+	for i := range users {
+		want := min(g.around(g.cfg.Follows), len(users)-1)
+		for tries := 0; len(users[i].Following) < want && tries < 4*want; tries++ {
+			target := users[popular.Uint64()].ID
+			if target != users[i].ID && !containsID(users[i].Following, target) {
+				users[i].Following = append(users[i].Following, target)
+			}
+		}
+	}
+}
+
+func (g *generator) favorites(users []userEntity.User, articles []articleEntity.Article) {
+	if len(articles) == 0 {
+		return
+	}
+	popular := g.zipf(len(articles))
+
+	for i := range users {
+		want := min(g.around(g.cfg.Favorites), len(articles))
+		for tries := 0; len(users[i].Favorites) < want && tries < 4*want; tries++ {
+			n := popular.Uint64()
+			if !containsID(users[i].Favorites, articles[n].ID) {
+				users[i].Favorites = append(users[i].Favorites, articles[n].ID)
+				articles[n].FavoritesCount++
+			}
+		}
+	}
+}
+
+func (g *generator) title() string {
+	title := fmt.Sprintf(g.pick(titleTemplates), g.pick(verbs), g.pick(nouns), g.pick(adjectives), g.pick(techs))
+	return capitalize(title)
+}
+
+func (g *generator) sentence(minWords, maxWords int) string {
+	n := minWords + g.rng.Intn(maxWords-minWords+1)
+	sentence := make([]string, n)
+	for i := range sentence {
+		sentence[i] = g.pick(words)
+	}
+	return capitalize(strings.Join(sentence, " ")) + "."
+}
+
+func (g *generator) paragraph() string {
+	sentences := make([]string, 2+g.rng.Intn(4))
+	for i := range sentences {
+		sentences[i] = g.sentence(6, 18)
+	}
+	return strings.Join(sentences, " ")
+}
+
+// body is Markdown with the elements real articles have: sections,
+// lists, code, quotes, links and emphasis.
+func (g *generator) body() string {
+	var sb strings.Builder
+	sb.WriteString(g.paragraph() + "\n")
+
+	for section := 1 + g.rng.Intn(4); section > 0; section-- {
+		sb.WriteString("\n## " + capitalize(g.pick(nouns)) + "\n\n" + g.paragraph() + "\n")
+
+		switch g.rng.Intn(5) {
+		case 0:
+			sb.WriteString("\n" + g.pick(codeSnippets) + "\n")
+		case 1:
+			sb.WriteString("\n")
+			for item := 2 + g.rng.Intn(3); item > 0; item-- {
+				sb.WriteString("- " + g.sentence(3, 8) + "\n")
+			}
+		case 2:
+			sb.WriteString("\n> " + g.sentence(8, 16) + "\n")
+		case 3:
+			sb.WriteString(fmt.Sprintf("\nSee [the docs](https://example.com/%s) for **%s**.\n",
+				slug.Make(g.pick(nouns)), g.pick(nouns)))
+		}
+	}
+	return sb.String()
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []entity.ID, id entity.ID) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func config(seed int64) Config {
+	return Config{
+		Seed:         seed,
+		Users:        30,
+		Articles:     80,
+		Comments:     4,
+		Follows:      5,
+		Favorites:    6,
+		Now:          time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		PasswordHash: "hash",
+	}
+}
+
+func generate(t *testing.T, cfg Config) *database.SeedData {
+	data, err := Generate(cfg)
+	require.NoError(t, err)
+	return data
+}
+
+func marshal(t *testing.T, v interface{}) string {
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestGenerate_Deterministic(t *testing.T) {
+	first := generate(t, config(42))
+	second := generate(t, config(42))
+	other := generate(t, config(43))
+
+	assert.Equal(t, marshal(t, first), marshal(t, second))
+	assert.NotEqual(t, marshal(t, first), marshal(t, other))
+}
+
+func TestGenerate_Consistent(t *testing.T) {
+	cfg := config(7)
+	data := generate(t, cfg)
+
+	require.Len(t, data.Users, cfg.Users)
+	require.Len(t, data.Articles, cfg.Articles)
+	assert.NotEmpty(t, data.Comments)
+
+	users := map[string]bool{}
+	usernames := map[string]bool{}
+	for _, u := range data.Users {
+		users[u.ID.String()] = true
+		assert.False(t, usernames[u.UserName], "duplicate username %s", u.UserName)
+		usernames[u.UserName] = true
+		assert.Equal(t, "hash", u.Password)
+	}
+
+	tags := map[string]bool{}
+	for _, tag := range data.Tags {
+		tags[tag.Name] = true
+	}
+
+	favorites := map[entity.ID]int{}
+	for _, u := range data.Users {
+		assert.NotContains(t, u.Following, u.ID)
+		for _, id := range u.Favorites {
+			favorites[id]++
+		}
+	}
+
+	slugs := map[string]bool{}
+	articles := map[string]bool{}
+	for _, a := range data.Articles {
+		articles[a.ID.String()] = true
+		assert.False(t, slugs[a.Slug], "duplicate slug %s", a.Slug)
+		slugs[a.Slug] = true
+		assert.True(t, users[a.AuthorID])
+		assert.NotEmpty(t, a.BodyHTML)
+		assert.Equal(t, favorites[a.ID], a.FavoritesCount)
+
+		require.NotEmpty(t, a.TagList)
+		for _, tag := range a.TagList {
+			assert.True(t, tags[tag], tag)
+		}
+
+		created, err := time.Parse(time.RFC3339, a.CreatedAt)
+		require.NoError(t, err)
+		assert.False(t, created.After(cfg.Now))
+		assert.True(t, created.After(cfg.Now.Add(-history)))
+	}
+
+	seen := map[string]entityComment.Comment{}
+	for _, c := range data.Comments {
+		assert.True(t, users[c.AuthorID])
+		assert.True(t, articles[c.ArticleID])
+		if c.ParentID != "" {
+			parent, ok := seen[c.ParentID]
+			require.True(t, ok, "replies come after their parent")
+			assert.Equal(t, parent.ArticleID, c.ArticleID)
+			assert.Equal(t, parent.Depth+1, c.Depth)
+			assert.LessOrEqual(t, c.Depth, maxReplyDepth)
+		}
+		seen[c.ID.String()] = c
+	}
+}
+
+func TestGenerate_PopularTags(t *testing.T) {
+	data := generate(t, config(1))
+
+	counts := map[string]int{}
+	for _, a := range data.Articles {
+		for _, tag := range a.TagList {
+			counts[tag]++
+		}
+	}
+	assert.Greater(t, counts[tagNames[0]], counts[tagNames[len(tagNames)-1]])
+}
+
+func TestGenerate_Body(t *testing.T) {
+	data := generate(t, config(3))
+
+	body := data.Articles[0].Body
+	assert.Contains(t, body, "\n## ")
+	assert.Equal(t, strings.ToUpper(body[:1]), body[:1])
+}
+
+func TestGenerate_Empty(t *testing.T) {
+	data := generate(t, Config{Seed: 1, Articles: 10})
+	assert.Empty(t, data.Users)
+	assert.Empty(t, data.Articles)
+}
+
+type fakeUsers struct {
+	database.UserInterface
+	created   []userEntity.User
+	following map[string]int
+	favorites []string
+}
+
+func (f *fakeUsers) CreateUser(ctx context.Context, u *userEntity.User) error {
+	f.created = append(f.created, *u)
+	return nil
+}
+
+func (f *fakeUsers) UpdateFollowingUserDb(ctx context.Context, id string, following []entity.ID) error {
+	f.following[id] = len(following)
+	return nil
+}
+
+func (f *fakeUsers) FavoriteArticleDB(ctx context.Context, slug string, isAddToFavorite bool, userID string) error {
+	f.favorites = append(f.favorites, slug)
+	return nil
+}
+
+type fakeArticles struct {
+	database.ArticleInterface
+	created []articleEntity.Article
+}
+
+func (f *fakeArticles) CreateArticle(ctx context.Context, a *articleEntity.Article) error {
+	f.created = append(f.created, *a)
+	return nil
+}
+
+type fakeComments struct {
+	database.CommentInterface
+	created int
+}
+
+func (f *fakeComments) CreateCommentDb(ctx context.Context, c *entityComment.Comment) error {
+	f.created++
+	return nil
+}
+
+type fakeTags struct {
+	database.TagsInterface
+	created int
+	links   int
+}
+
+func (f *fakeTags) CreateTag(ctx context.Context, tags []*tagEntity.Tag) error {
+	f.created += len(tags)
+	return nil
+}
+
+func (f *fakeTags) LinkArticleTags(ctx context.Context, articleID string, names []string) error {
+	f.links++
+	return nil
+}
+
+func TestRepositories_Write(t *testing.T) {
+	data := generate(t, config(5))
+
+	users := &fakeUsers{following: map[string]int{}}
+	articles := &fakeArticles{}
+	comments := &fakeComments{}
+	tags := &fakeTags{}
+	repos := &Repositories{UserDB: users, ArticleDB: articles, CommentDB: comments, TagDB: tags}
+	require.NoError(t, repos.Write(context.Background(), data))
+
+	require.Len(t, users.created, len(data.Users))
+	assert.Empty(t, users.created[0].Favorites, "favorites go through FavoriteArticleDB")
+	require.Len(t, articles.created, len(data.Articles))
+	assert.Zero(t, articles.created[0].FavoritesCount)
+	assert.Equal(t, len(data.Comments), comments.created)
+	assert.Equal(t, len(data.Tags), tags.created)
+	assert.Equal(t, len(data.Articles), tags.links)
+
+	favorites, follows := 0, 0
+	for _, u := range data.Users {
+		favorites += len(u.Favorites)
+		follows += len(u.Following)
+	}
+	assert.Len(t, users.favorites, favorites)
+	followed := 0
+	for _, n := range users.following {
+		followed += n
+	}
+	assert.Equal(t, follows, followed)
+
+	counted := 0
+	for _, a := range data.Articles {
+		counted += a.FavoritesCount
+	}
+	assert.Equal(t, favorites, counted, "the data set is left intact")
+}
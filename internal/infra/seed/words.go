@@ -0,0 +1,75 @@
+package seed
+
+// The vocabulary of generated content. Tags come first-most-popular: they
+// are drawn with a Zipf distribution over this order.
+var (
+	tagNames = []string{
+		"go", "javascript", "webdev", "programming", "tutorial", "react", "python", "devops", "beginners",
+		"database", "postgres", "testing", "architecture", "career", "docker", "kubernetes", "security",
+		"performance", "opensource", "api", "css", "typescript", "rust", "linux", "cloud", "productivity",
+		"design", "machinelearning", "git", "frontend", "backend", "microservices", "observability", "redis",
+		"graphql", "mobile", "accessibility", "ux", "functional", "concurrency",
+	}
+
+	firstNames = []string{
+		"ana", "bruno", "carla", "diego", "elena", "felipe", "gabriela", "hugo", "isabela", "joao", "karina",
+		"lucas", "marina", "nicolas", "olivia", "pedro", "quiteria", "rafael", "sofia", "tiago", "ursula",
+		"vitor", "wanda", "xavier", "yara", "zeca", "alex", "sam", "jordan", "taylor",
+	}
+
+	lastNames = []string{
+		"silva", "santos", "oliveira", "souza", "lima", "pereira", "costa", "rodrigues", "almeida", "nunes",
+		"carvalho", "gomes", "martins", "araujo", "ribeiro", "barbosa", "rocha", "dias", "teixeira", "moreira",
+	}
+
+	bios = []string{
+		"Backend developer. Coffee first.",
+		"Writing about the things I break at work.",
+		"Frontend, accessibility and good typography.",
+		"SRE by day, home cook by night.",
+		"Learning in public.",
+		"",
+	}
+
+	adjectives = []string{
+		"practical", "hidden", "simple", "modern", "lazy", "concurrent", "pragmatic", "surprising", "boring",
+		"fast", "small", "resilient", "honest", "incremental", "minimal",
+	}
+
+	nouns = []string{
+		"caching", "pagination", "error handling", "migrations", "feature flags", "code review", "logging",
+		"rate limiting", "retries", "queues", "indexes", "deployments", "tests", "refactoring", "state machines",
+		"interfaces", "benchmarks", "transactions", "webhooks", "feeds",
+	}
+
+	techs = []string{"Go", "Postgres", "React", "Kubernetes", "Rust", "Python", "TypeScript", "Redis", "Docker", "Linux"}
+
+	verbs = []string{"build", "debug", "measure", "ship", "design", "test", "scale", "document", "profile", "simplify"}
+
+	titleTemplates = []string{
+		"How to %[1]s %[2]s in %[4]s",
+		"The %[3]s guide to %[2]s",
+		"%[3]s %[2]s with %[4]s",
+		"Why I stopped worrying about %[2]s",
+		"Notes on %[2]s",
+		"Lessons from a year of %[2]s in %[4]s",
+		"What nobody tells you about %[2]s",
+	}
+
+	words = []string{
+		"the", "a", "service", "request", "response", "latency", "team", "database", "query", "index", "user",
+		"handler", "cache", "we", "it", "is", "was", "because", "when", "then", "without", "with", "every",
+		"deploy", "error", "value", "function", "test", "review", "change", "small", "fast", "simple", "slow",
+		"production", "staging", "metric", "trace", "log", "client", "server", "network", "timeout", "retry",
+		"budget", "incident", "design", "trade-off", "interface", "package", "module", "release", "version",
+		"schema", "migration", "rollback", "feature", "flag", "queue", "worker", "job", "batch", "stream",
+	}
+
+	codeSnippets = []string{
+		"```go\nfunc retry(n int, f func() error) (err error) {\n\tfor i := 0; i < n; i++ {\n\t\tif err = f(); err == nil {\n\t\t\treturn nil\n\t\t}\n\t}\n\treturn err\n}\n```",
+		"```sql\nSELECT slug, title FROM articles\nWHERE createdAt < $1\nORDER BY createdAt DESC\nLIMIT 20;\n```",
+		"```js\nconst res = await fetch(`/api/articles?limit=${limit}`);\nconst { articles } = await res.json();\n```",
+		"```bash\ndocker compose up -d\ngo test ./...\n```",
+		"```python\ndef chunks(items, size):\n    for i in range(0, len(items), size):\n        yield items[i:i + size]\n```",
+	}
+)
@@ -0,0 +1,72 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+
+	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+// Repositories writes a data set one row at a time through the same
+// repository methods the API uses, which is slower than
+// database.SeedDB.CopySeedData but exercises them.
+type Repositories struct {
+	UserDB    database.UserInterface
+	ArticleDB database.ArticleInterface
+	CommentDB database.CommentInterface
+	TagDB     database.TagsInterface
+}
+
+func (r *Repositories) Write(ctx context.Context, data *database.SeedData) error {
+	for _, u := range data.Users {
+		// follows and favorites are added once everything they point to exists
+		u.Following, u.Favorites = []entity.ID{}, []entity.ID{}
+		if err := r.UserDB.CreateUser(ctx, &u); err != nil {
+			return fmt.Errorf("user %s: %w", u.UserName, err)
+		}
+	}
+
+	tags := make([]*tagEntity.Tag, len(data.Tags))
+	for i := range data.Tags {
+		tags[i] = &data.Tags[i]
+	}
+	if err := r.TagDB.CreateTag(ctx, tags); err != nil {
+		return err
+	}
+
+	slugs := map[string]string{}
+	for _, a := range data.Articles {
+		slugs[a.ID.String()] = a.Slug
+
+		a.FavoritesCount = 0
+		if err := r.ArticleDB.CreateArticle(ctx, &a); err != nil {
+			return fmt.Errorf("article %s: %w", a.Slug, err)
+		}
+		if err := r.TagDB.LinkArticleTags(ctx, a.ID.String(), a.TagList); err != nil {
+			return fmt.Errorf("article %s: %w", a.Slug, err)
+		}
+	}
+
+	for i := range data.Comments {
+		if err := r.CommentDB.CreateCommentDb(ctx, &data.Comments[i]); err != nil {
+			return fmt.Errorf("comment %s: %w", data.Comments[i].ID, err)
+		}
+	}
+
+	for _, u := range data.Users {
+		if len(u.Following) > 0 {
+			if err := r.UserDB.UpdateFollowingUserDb(ctx, u.ID.String(), u.Following); err != nil {
+				return fmt.Errorf("follows of %s: %w", u.UserName, err)
+			}
+		}
+		for _, id := range u.Favorites {
+			if err := r.UserDB.FavoriteArticleDB(ctx, slugs[id.String()], true, u.ID.String()); err != nil {
+				return fmt.Errorf("favorites of %s: %w", u.UserName, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package uploads deletes the uploaded images of users and articles together
+// with them. Its repositories wrap the database ones, like those of the cache
+// package, so the API, the admin endpoints and conduitctl all clean up the
+// same way.
+package uploads
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/blob"
+)
+
+// Upload kinds, which are also the first segment of their blob keys. The
+// second one is the owner: the user for avatars and the article for covers.
+const (
+	Avatars = "avatars"
+	Covers  = "covers"
+)
+
+// Prefix is the blob key prefix of every upload of kind for owner.
+func Prefix(kind, owner string) string {
+	return kind + "/" + owner
+}
+
+type UserRepository struct {
+	database.UserInterface
+	Store blob.Store
+}
+
+func NewUserRepository(inner database.UserInterface, store blob.Store) *UserRepository {
+	return &UserRepository{UserInterface: inner, Store: store}
+}
+
+// DeleteUser deletes the avatars of the user and the covers of the articles
+// deleted with the account. Anonymized articles keep theirs.
+func (u *UserRepository) DeleteUser(ctx context.Context, id string, mode string) (*database.DeletedAccount, error) {
+	deleted, err := u.UserInterface.DeleteUser(ctx, id, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteAll(ctx, u.Store, Avatars, id)
+	for _, articleID := range deleted.ArticleIDs {
+		deleteAll(ctx, u.Store, Covers, articleID)
+	}
+	return deleted, nil
+}
+
+type ArticleRepository struct {
+	database.ArticleInterface
+	Store blob.Store
+}
+
+func NewArticleRepository(inner database.ArticleInterface, store blob.Store) *ArticleRepository {
+	return &ArticleRepository{ArticleInterface: inner, Store: store}
+}
+
+// DeleteArticleDB deletes the covers of the article with it.
+func (a *ArticleRepository) DeleteArticleDB(ctx context.Context, slug string) ([]string, error) {
+	article, err := a.ArticleInterface.GetArticleBySlug(ctx, slug)
+	if err != nil {
+		// let the database report the missing article
+		return a.ArticleInterface.DeleteArticleDB(ctx, slug)
+	}
+
+	users, err := a.ArticleInterface.DeleteArticleDB(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	deleteAll(ctx, a.Store, Covers, article.ID.String())
+	return users, nil
+}
+
+// deleteAll runs once the database change is committed, so a failure only
+// leaves unreachable files behind; it is logged rather than returned.
+func deleteAll(ctx context.Context, store blob.Store, kind, owner string) {
+	if err := store.DeleteAll(ctx, Prefix(kind, owner)); err != nil {
+		slog.WarnContext(ctx, "error deleting uploads", slog.String("kind", kind),
+			slog.String("owner", owner), slog.String("error", err.Error()))
+	}
+}
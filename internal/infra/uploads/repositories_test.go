@@ -0,0 +1,83 @@
+package uploads
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeUsers struct {
+	database.UserInterface
+	deleted *database.DeletedAccount
+}
+
+func (f *fakeUsers) DeleteUser(ctx context.Context, id string, mode string) (*database.DeletedAccount, error) {
+	return f.deleted, nil
+}
+
+type fakeArticles struct {
+	database.ArticleInterface
+	article *articleEntity.Article
+	deleted bool
+}
+
+func (f *fakeArticles) GetArticleBySlug(ctx context.Context, slug string) (*articleEntity.Article, error) {
+	return f.article, nil
+}
+
+func (f *fakeArticles) DeleteArticleDB(ctx context.Context, slug string) ([]string, error) {
+	f.deleted = true
+	return nil, nil
+}
+
+func put(t *testing.T, store blob.Store, keys ...string) {
+	for _, key := range keys {
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("x"), "image/jpeg"))
+	}
+}
+
+func exists(store blob.Store, key string) bool {
+	f, _, err := store.Open(context.Background(), key)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+func TestUserRepository_DeleteUserDeletesUploads(t *testing.T) {
+	store := blob.NewLocal(t.TempDir())
+	put(t, store, "avatars/jake-id/1-large.jpg", "covers/a1/2-large.jpg", "covers/kept/3-large.jpg",
+		"avatars/anna-id/4-large.jpg")
+
+	repo := NewUserRepository(&fakeUsers{deleted: &database.DeletedAccount{ArticleIDs: []string{"a1"}}}, store)
+	_, err := repo.DeleteUser(context.Background(), "jake-id", "delete")
+	require.NoError(t, err)
+
+	assert.False(t, exists(store, "avatars/jake-id/1-large.jpg"))
+	assert.False(t, exists(store, "covers/a1/2-large.jpg"))
+	assert.True(t, exists(store, "covers/kept/3-large.jpg"))
+	assert.True(t, exists(store, "avatars/anna-id/4-large.jpg"))
+}
+
+func TestArticleRepository_DeleteArticleDeletesCovers(t *testing.T) {
+	article, err := articleEntity.NewArticle("author123", "My title", "", "body", nil)
+	require.NoError(t, err)
+
+	store := blob.NewLocal(t.TempDir())
+	put(t, store, "covers/"+article.ID.String()+"/1-large.jpg", "covers/other/1-large.jpg")
+
+	inner := &fakeArticles{article: article}
+	_, err = NewArticleRepository(inner, store).DeleteArticleDB(context.Background(), "my-title")
+	require.NoError(t, err)
+
+	assert.True(t, inner.deleted)
+	assert.False(t, exists(store, "covers/"+article.ID.String()+"/1-large.jpg"))
+	assert.True(t, exists(store, "covers/other/1-large.jpg"))
+}
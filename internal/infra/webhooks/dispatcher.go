@@ -0,0 +1,66 @@
+// Package webhooks queues the events webhooks subscribe to and delivers
+// them in the background.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+)
+
+// Payload is the body of every webhook request. ID identifies the event and
+// stays the same across redeliveries, so receivers can drop duplicates.
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt string      `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher queues one delivery per matching webhook. Deliveries are stored
+// before the request returns and sent by a Worker, so they survive restarts.
+type Dispatcher struct {
+	WebhookDB database.WebhookInterface
+}
+
+func NewDispatcher(webhookDB database.WebhookInterface) *Dispatcher {
+	return &Dispatcher{WebhookDB: webhookDB}
+}
+
+// Emit queues event for the webhooks of subjectID, the user it concerns,
+// and the global ones. Failures are logged and never fail the request that
+// triggered them.
+func (d *Dispatcher) Emit(ctx context.Context, event, subjectID string, data interface{}) {
+	hooks, err := d.WebhookDB.MatchWebhooks(ctx, event, subjectID)
+	if err != nil {
+		slog.WarnContext(ctx, "webhook event not queued", slog.String("event", event), slog.String("error", err.Error()))
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(Payload{
+		ID:        entity.NewID().String(),
+		Event:     event,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Data:      data,
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "webhook event not queued", slog.String("event", event), slog.String("error", err.Error()))
+		return
+	}
+
+	for _, hook := range hooks {
+		delivery := webhookEntity.NewDelivery(hook.ID.String(), event, payload)
+		if err := d.WebhookDB.CreateDelivery(ctx, delivery); err != nil {
+			slog.WarnContext(ctx, "webhook event not queued", slog.String("event", event),
+				slog.String("webhook_id", hook.ID.String()), slog.String("error", err.Error()))
+		}
+	}
+}
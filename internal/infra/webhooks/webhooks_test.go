@@ -0,0 +1,136 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhooks struct {
+	database.WebhookInterface
+
+	mu         sync.Mutex
+	hooks      []webhookEntity.Webhook
+	deliveries []*webhookEntity.Delivery
+}
+
+func (f *fakeWebhooks) MatchWebhooks(ctx context.Context, event, subjectID string) ([]webhookEntity.Webhook, error) {
+	var matched []webhookEntity.Webhook
+	for _, hook := range f.hooks {
+		if (hook.Global || hook.OwnerID == subjectID) && webhookEntity.ValidEvent(event) {
+			for _, e := range hook.Events {
+				if e == event {
+					matched = append(matched, hook)
+				}
+			}
+		}
+	}
+	return matched, nil
+}
+
+func (f *fakeWebhooks) CreateDelivery(ctx context.Context, d *webhookEntity.Delivery) error {
+	f.deliveries = append(f.deliveries, d)
+	return nil
+}
+
+func (f *fakeWebhooks) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]database.DueDelivery, error) {
+	var due []database.DueDelivery
+	for _, d := range f.deliveries {
+		if d.Status == webhookEntity.DeliveryPending && !d.NextAttemptAt.After(time.Now()) && len(due) < limit {
+			due = append(due, database.DueDelivery{Delivery: *d, URL: "https://example.com/" + d.WebhookID, Secret: "secret"})
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeWebhooks) UpdateDelivery(ctx context.Context, d *webhookEntity.Delivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, stored := range f.deliveries {
+		if stored.ID == d.ID {
+			copied := *d
+			f.deliveries[i] = &copied
+		}
+	}
+	return nil
+}
+
+type fakeSender struct {
+	mu       sync.Mutex
+	requests []webhook.Request
+	status   int
+	err      error
+}
+
+func (f *fakeSender) Send(ctx context.Context, req webhook.Request) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, req)
+	return f.status, f.err
+}
+
+func TestDispatcher_Emit(t *testing.T) {
+	mine, _ := webhookEntity.NewWebhook("author", "https://chat.example.com", []string{webhookEntity.EventCommentCreated}, false)
+	global, _ := webhookEntity.NewWebhook("admin", "https://ci.example.com", webhookEntity.Events, true)
+	other, _ := webhookEntity.NewWebhook("someone", "https://other.example.com", webhookEntity.Events, false)
+	repo := &fakeWebhooks{hooks: []webhookEntity.Webhook{*mine, *global, *other}}
+
+	NewDispatcher(repo).Emit(context.Background(), webhookEntity.EventCommentCreated, "author", map[string]string{"body": "Nice"})
+
+	require.Len(t, repo.deliveries, 2)
+	assert.Equal(t, mine.ID.String(), repo.deliveries[0].WebhookID)
+	assert.Equal(t, global.ID.String(), repo.deliveries[1].WebhookID)
+	assert.Equal(t, repo.deliveries[0].Payload, repo.deliveries[1].Payload)
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal(repo.deliveries[0].Payload, &payload))
+	assert.NotEmpty(t, payload.ID)
+	assert.Equal(t, webhookEntity.EventCommentCreated, payload.Event)
+	assert.Equal(t, map[string]interface{}{"body": "Nice"}, payload.Data)
+}
+
+func TestWorker_RunOnce(t *testing.T) {
+	ok := webhookEntity.NewDelivery("ok", webhookEntity.EventUserFollowed, []byte(`{"a":1}`))
+	later := webhookEntity.NewDelivery("later", webhookEntity.EventUserFollowed, []byte(`{}`))
+	later.NextAttemptAt = time.Now().Add(time.Hour)
+	repo := &fakeWebhooks{deliveries: []*webhookEntity.Delivery{ok, later}}
+	sender := &fakeSender{status: 204}
+
+	n := NewWorker(repo, sender, time.Second).RunOnce(context.Background())
+	assert.Equal(t, 1, n)
+
+	require.Len(t, sender.requests, 1)
+	assert.Equal(t, webhook.Request{
+		URL: "https://example.com/ok", Secret: "secret", Event: webhookEntity.EventUserFollowed,
+		DeliveryID: ok.ID.String(), Body: []byte(`{"a":1}`),
+	}, sender.requests[0])
+
+	assert.Equal(t, webhookEntity.DeliverySucceeded, repo.deliveries[0].Status)
+	assert.Equal(t, 1, repo.deliveries[0].Attempts)
+	assert.Equal(t, webhookEntity.DeliveryPending, repo.deliveries[1].Status)
+}
+
+func TestWorker_Retries(t *testing.T) {
+	d := webhookEntity.NewDelivery("down", webhookEntity.EventArticlePublished, []byte(`{}`))
+	repo := &fakeWebhooks{deliveries: []*webhookEntity.Delivery{d}}
+	sender := &fakeSender{err: errors.New("connection refused")}
+	worker := NewWorker(repo, sender, time.Second)
+
+	worker.RunOnce(context.Background())
+	assert.Equal(t, webhookEntity.DeliveryPending, repo.deliveries[0].Status)
+	assert.Equal(t, "connection refused", repo.deliveries[0].Error)
+	assert.True(t, repo.deliveries[0].NextAttemptAt.After(time.Now()))
+
+	assert.Equal(t, 0, worker.RunOnce(context.Background()), "not due again before its backoff")
+}
@@ -0,0 +1,109 @@
+package webhooks
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
+	"github.com/sallescosta/conduit-api/pkg/webhook"
+)
+
+type Sender interface {
+	Send(ctx context.Context, req webhook.Request) (int, error)
+}
+
+const (
+	batchSize = 20
+	// lease keeps a claimed delivery from being picked again while it is
+	// sent; it must be longer than the send timeout.
+	lease = time.Minute
+)
+
+// Worker sends the deliveries that are due, polling every interval. Several
+// instances can run at once: each delivery is claimed by a single one.
+type Worker struct {
+	WebhookDB database.WebhookInterface
+	Sender    Sender
+	Interval  time.Duration
+}
+
+func NewWorker(webhookDB database.WebhookInterface, sender Sender, interval time.Duration) *Worker {
+	return &Worker{WebhookDB: webhookDB, Sender: sender, Interval: interval}
+}
+
+// Run polls until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		// keep going while batches come back full
+		for w.RunOnce(ctx) == batchSize {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of due deliveries and returns its size.
+func (w *Worker) RunOnce(ctx context.Context) int {
+	due, err := w.WebhookDB.ClaimDueDeliveries(ctx, batchSize, lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error claiming webhook deliveries", slog.String("error", err.Error()))
+		}
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for i := range due {
+		wg.Add(1)
+		go func(d *database.DueDelivery) {
+			defer wg.Done()
+			w.deliver(ctx, d)
+		}(&due[i])
+	}
+	wg.Wait()
+
+	return len(due)
+}
+
+func (w *Worker) deliver(ctx context.Context, d *database.DueDelivery) {
+	status, err := w.Sender.Send(ctx, webhook.Request{
+		URL:        d.URL,
+		Secret:     d.Secret,
+		Event:      d.Event,
+		DeliveryID: d.ID.String(),
+		Body:       d.Payload,
+	})
+
+	d.RecordAttempt(status, err, time.Now().UTC())
+
+	outcome := "retrying"
+	switch d.Status {
+	case webhookEntity.DeliverySucceeded:
+		outcome = "succeeded"
+	case webhookEntity.DeliveryFailed:
+		outcome = "failed"
+	}
+	instrument.WebhookAttempts.WithLabelValues(d.Event, outcome).Inc()
+
+	if err != nil {
+		slog.WarnContext(ctx, "webhook delivery failed", slog.String("delivery_id", d.ID.String()),
+			slog.String("event", d.Event), slog.Int("attempt", d.Attempts), slog.String("outcome", outcome),
+			slog.String("error", err.Error()))
+	}
+
+	if err := w.WebhookDB.UpdateDelivery(ctx, &d.Delivery); err != nil {
+		slog.ErrorContext(ctx, "error saving webhook delivery", slog.String("delivery_id", d.ID.String()),
+			slog.String("error", err.Error()))
+	}
+}
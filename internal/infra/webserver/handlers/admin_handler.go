@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sallescosta/conduit-api/internal/dto"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/webhooks"
+	"github.com/sallescosta/conduit-api/pkg/helpers"
+	"github.com/sallescosta/conduit-api/pkg/stream"
+)
+
+type AdminHandler struct {
+	UserDB    database.UserInterface
+	ArticleDB database.ArticleInterface
+	CommentDB database.CommentInterface
+	Broker    stream.Broker
+	Webhooks  *webhooks.Dispatcher
+	SiteURL   string
+}
+
+func NewAdminHandler(userDB database.UserInterface, articleDB database.ArticleInterface, commentDB database.CommentInterface,
+	broker stream.Broker, dispatcher *webhooks.Dispatcher, siteURL string) *AdminHandler {
+	return &AdminHandler{UserDB: userDB, ArticleDB: articleDB, CommentDB: commentDB, Broker: broker, Webhooks: dispatcher,
+		SiteURL: strings.TrimRight(siteURL, "/")}
+}
+
+// ListUsers searches users by username or email with ?q, paged with ?limit
+// and ?offset.
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(params.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	page, err := h.UserDB.ListUsers(r.Context(), database.UsersQuery{
+		Search: params.Get("q"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.AdminUsersOutput{Users: []dto.AdminUserOutput{}, UsersCount: page.Total}
+	for _, user := range page.Users {
+		response.Users = append(response.Users, h.toAdminUserOutput(&user))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// BanUser bans the user on POST and lifts the ban on DELETE.
+func (h *AdminHandler) BanUser(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, func(user *userEntity.User) error {
+		user.Banned = r.Method == http.MethodPost
+		return h.UserDB.SetBanned(r.Context(), user.ID.String(), user.Banned)
+	})
+}
+
+// PromoteUser makes the user an admin on POST and a regular user on DELETE.
+func (h *AdminHandler) PromoteUser(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, func(user *userEntity.User) error {
+		user.Role = helpers.Ternary(r.Method == http.MethodPost, userEntity.RoleAdmin, userEntity.RoleUser).(string)
+		return h.UserDB.SetRole(r.Context(), user.ID.String(), user.Role)
+	})
+}
+
+// updateUser loads the user named in the URL, refuses to let admins act on
+// themselves, applies update and answers with the updated user.
+func (h *AdminHandler) updateUser(w http.ResponseWriter, r *http.Request, update func(*userEntity.User) error) {
+	username := chi.URLParam(r, "username")
+
+	profile, err := h.UserDB.GetProfileDb(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if profile.Profile.ID.String() == myId {
+		http.Error(w, "Admins cannot change their own account", http.StatusConflict)
+		return
+	}
+
+	user, err := h.UserDB.FindById(r.Context(), profile.Profile.ID.String())
+	if err != nil || user == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := update(user); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin updated user", slog.String("username", user.UserName),
+		slog.String("role", user.Role), slog.Bool("banned", user.Banned))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.toAdminUserOutput(user))
+}
+
+// DeleteArticle removes any article, whoever wrote it.
+func (h *AdminHandler) DeleteArticle(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	article, err := h.ArticleDB.GetArticleBySlug(r.Context(), slug)
+	if err != nil {
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	_, err = h.ArticleDB.DeleteArticleDB(r.Context(), slug)
+	if err != nil {
+		if strings.Contains(err.Error(), "article not found") {
+			http.Error(w, "Article not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.Webhooks.Emit(r.Context(), webhookEntity.EventArticleDeleted, article.AuthorID,
+		map[string]interface{}{"article": articleReference(article)})
+
+	slog.InfoContext(r.Context(), "admin deleted article", slog.String("slug", slug))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteComment removes any comment and its whole thread of replies.
+func (h *AdminHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	comment, err := h.CommentDB.GetCommentByIdDb(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	removed, err := h.CommentDB.PurgeCommentDb(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// the replies go with the comment, so listeners hear about each of them
+	for _, removedID := range removed {
+		publishCommentEvent(r.Context(), h.Broker, EventCommentDeleted, comment.ArticleID, commentDeletedEvent{ID: removedID})
+	}
+
+	slog.InfoContext(r.Context(), "admin deleted comment", slog.String("comment_id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) toAdminUserOutput(user *userEntity.User) dto.AdminUserOutput {
+	return dto.AdminUserOutput{
+		Username: user.UserName,
+		Email:    user.Email,
+		Bio:      user.Bio,
+		Image:    userEntity.AvatarURL(h.SiteURL, user.UserName, user.Image),
+		Role:     user.Role,
+		Banned:   user.Banned,
+	}
+}
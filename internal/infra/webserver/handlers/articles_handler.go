@@ -1,27 +1,39 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sallescosta/conduit-api/internal/dto"
 	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
 	tagEntity "github.com/sallescosta/conduit-api/internal/entity/tag"
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
 	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
+	"github.com/sallescosta/conduit-api/internal/infra/webhooks"
+	"github.com/sallescosta/conduit-api/pkg/conditional"
 	"github.com/sallescosta/conduit-api/pkg/helpers"
+	"github.com/sallescosta/conduit-api/pkg/markdown"
 )
 
+const maxPreviewSize = 1 << 20
+
 type ArticleHandler struct {
 	ArticleDB database.ArticleInterface
 	TagDB     database.TagsInterface
+	Webhooks  *webhooks.Dispatcher
 }
 
-func NewArticleHandler(articleDB database.ArticleInterface, tagDB database.TagsInterface) *ArticleHandler {
-	return &ArticleHandler{ArticleDB: articleDB, TagDB: tagDB}
+func NewArticleHandler(articleDB database.ArticleInterface, tagDB database.TagsInterface, dispatcher *webhooks.Dispatcher) *ArticleHandler {
+	return &ArticleHandler{ArticleDB: articleDB, TagDB: tagDB, Webhooks: dispatcher}
 }
 
 func (a *ArticleHandler) CreateArticle(w http.ResponseWriter, r *http.Request) {
@@ -48,7 +60,7 @@ func (a *ArticleHandler) CreateArticle(w http.ResponseWriter, r *http.Request) {
 		lTag = append(lTag, newTage)
 	}
 
-	err = a.TagDB.CreateTag(lTag)
+	err = a.TagDB.CreateTag(r.Context(), lTag)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Error creating tag\n"))
@@ -69,7 +81,16 @@ func (a *ArticleHandler) CreateArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = a.ArticleDB.CreateArticle(art)
+	art.CanonicalURL = article.Article.CanonicalURL
+	art.CoverImage = article.Article.CoverImage
+	art.License = article.Article.License
+	art.SEODescription = article.Article.SEODescription
+	if err := art.ValidateMetadata(); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = a.ArticleDB.CreateArticle(r.Context(), art)
 	if err != nil {
 		if strings.Contains(err.Error(), "title already used") {
 			w.WriteHeader(http.StatusConflict)
@@ -96,66 +117,101 @@ func (a *ArticleHandler) CreateArticle(w http.ResponseWriter, r *http.Request) {
 
 			return
 		}
-		return
 	}
 
+	if err := a.TagDB.LinkArticleTags(r.Context(), art.ID.String(), art.TagList); err != nil {
+		slog.WarnContext(r.Context(), "tags not linked", slog.String("slug", art.Slug), slog.String("error", err.Error()))
+	}
+
+	instrument.ArticlesCreated.Inc()
+	a.Webhooks.Emit(r.Context(), webhookEntity.EventArticlePublished, authorId, map[string]interface{}{"article": art})
+
 	successResponse := fmt.Sprintf("ArticleDB created successfully, title: %s", art.Title)
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte(successResponse))
 }
 
 func (a *ArticleHandler) ListAllArticle(w http.ResponseWriter, r *http.Request) {
-	w.Write([]byte(fmt.Sprintf("chegou: %v", "v")))
-	articles, err := a.ArticleDB.ListAllArticles()
+	a.listArticles(w, r, database.ArticleQuery{
+		Tag:         r.URL.Query().Get("tag"),
+		Author:      r.URL.Query().Get("author"),
+		FavoritedBy: r.URL.Query().Get("favorited"),
+	})
+}
+
+// FeedArticles lists the articles of the authors the caller follows.
+func (a *ArticleHandler) FeedArticles(w http.ResponseWriter, r *http.Request) {
+	userId, err := helpers.GetMyOwnIdbyToken(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(fmt.Appendf(nil, "Error: %v", err))
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
 		return
 	}
 
-	if len(articles) == 0 {
-		articles = []articleEntity.Article{}
-	}
+	a.listArticles(w, r, database.ArticleQuery{FollowedBy: userId})
+}
 
-	response := articleEntity.AllArticlesOutput{
-		Articles:      articles,
-		ArticlesCount: len(articles),
+// listArticles pages with ?limit and either ?offset or the opaque ?cursor
+// returned as nextCursor and in the Link header of the previous page.
+func (a *ArticleHandler) listArticles(w http.ResponseWriter, r *http.Request, query database.ArticleQuery) {
+	params := r.URL.Query()
+
+	limitInt, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limitInt <= 0 {
+		limitInt = 20
+	}
+	if limitInt > 100 {
+		limitInt = 100
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	offsetInt, err := strconv.Atoi(params.Get("offset"))
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
 
-	err = json.NewEncoder(w).Encode(response)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Error: %v", err)))
+	query.Cursor = params.Get("cursor")
+	if query.Cursor != "" && offsetInt > 0 {
+		http.Error(w, "Use either offset or cursor", http.StatusBadRequest)
+		return
 	}
-}
 
-func (a *ArticleHandler) FeedArticles(w http.ResponseWriter, r *http.Request) {
-	limit := r.URL.Query().Get("limit")
-	offset := r.URL.Query().Get("offset")
-	sort := r.URL.Query().Get("sort")
+	query.Limit = limitInt
+	query.Offset = offsetInt
+	query.Sort = params.Get("sort")
+	if query.Sort == "" {
+		query.Sort = "asc"
+	}
 
-	limitInt, err := strconv.Atoi(limit)
+	page, err := a.ArticleDB.QueryArticles(r.Context(), query)
 	if err != nil {
-		limitInt = 20
+		if errors.Is(err, helpers.ErrInvalidCursor) {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(fmt.Appendf(nil, "Error: %v", err))
+		return
 	}
 
-	offsetInt, err := strconv.Atoi(offset)
-	if err != nil {
-		limitInt = 0
+	response := articleEntity.AllArticlesOutput{
+		Articles:      page.Articles,
+		ArticlesCount: page.Total,
+		NextCursor:    page.NextCursor,
 	}
 
-	if sort == "" {
-		sort = "asc"
+	if page.NextCursor != "" {
+		next := *r.URL
+		nextParams := next.Query()
+		nextParams.Del("offset")
+		nextParams.Set("cursor", page.NextCursor)
+		next.RawQuery = nextParams.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
 	}
 
-	feed, err := a.ArticleDB.FeedArticles(limitInt, offsetInt, sort)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	err = json.NewEncoder(w).Encode(feed)
+	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
@@ -164,12 +220,36 @@ func (a *ArticleHandler) FeedArticles(w http.ResponseWriter, r *http.Request) {
 func (a *ArticleHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
-	article, err := a.ArticleDB.GetArticleBySlug(slug)
+	article, err := a.ArticleDB.GetArticleBySlug(r.Context(), slug)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
+	conditional.SetWeakETag(w, article.ID.String(), strconv.Itoa(article.Version), strconv.Itoa(article.FavoritesCount),
+		r.URL.Query().Get("format"))
+	if updatedAt, err := time.Parse(time.RFC3339Nano, article.UpdatedAt); err == nil {
+		conditional.SetLastModified(w, updatedAt)
+	}
+	if conditional.NotModified(w, r) {
+		return
+	}
+
+	if article.BodyHTML == "" && article.Body != "" {
+		if err := article.RenderBody(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		article.ComputeReadingMetadata()
+	}
+
+	if r.URL.Query().Get("format") == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(article.BodyHTML))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -182,19 +262,63 @@ func (a *ArticleHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
 func (a *ArticleHandler) UpdateArticle(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
+	authorId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
 	var modif dto.ArticleUpdateInput
 
-	err := json.NewDecoder(r.Body).Decode(&modif)
+	err = json.NewDecoder(r.Body).Decode(&modif)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if modif.Article.Title == "" && modif.Article.Description == "" && modif.Article.Body == "" {
+	if modif.Article.Title == "" && modif.Article.Description == "" && modif.Article.Body == "" &&
+		modif.Article.CanonicalURL == "" && modif.Article.CoverImage == "" && modif.Article.License == "" &&
+		modif.Article.SEODescription == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	updatedArticle, err := a.ArticleDB.UpdateArticle(slug, modif)
+	metadata := articleEntity.Article{
+		CanonicalURL:   modif.Article.CanonicalURL,
+		CoverImage:     modif.Article.CoverImage,
+		SEODescription: modif.Article.SEODescription,
+	}
+	if err := metadata.ValidateMetadata(); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	article, err := a.ArticleDB.GetArticleBySlug(r.Context(), slug)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Article not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if article.AuthorID != authorId {
+		http.Error(w, "Only the author can edit this article", http.StatusForbidden)
+		return
+	}
+
+	updatedArticle, err := a.ArticleDB.UpdateArticle(r.Context(), slug, modif)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Article not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.Webhooks.Emit(r.Context(), webhookEntity.EventArticleUpdated, updatedArticle.AuthorID,
+		map[string]interface{}{"article": updatedArticle})
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -208,12 +332,60 @@ func (a *ArticleHandler) UpdateArticle(w http.ResponseWriter, r *http.Request) {
 func (a *ArticleHandler) DeleteArticle(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
-	err := a.ArticleDB.DeleteArticleDB(slug)
+	authorId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	article, err := a.ArticleDB.GetArticleBySlug(r.Context(), slug)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if article.AuthorID != authorId {
+		http.Error(w, "Only the author can delete this article", http.StatusForbidden)
+		return
+	}
+
+	_, err = a.ArticleDB.DeleteArticleDB(r.Context(), slug)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	a.Webhooks.Emit(r.Context(), webhookEntity.EventArticleDeleted, article.AuthorID,
+		map[string]interface{}{"article": articleReference(article)})
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Article deleted"))
 }
+
+// PreviewArticle renders arbitrary Markdown the same way article bodies are
+// rendered, so drafts can be previewed before they are saved.
+func (a *ArticleHandler) PreviewArticle(w http.ResponseWriter, r *http.Request) {
+	var input dto.PreviewInput
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPreviewSize)
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	html, err := markdown.Render(input.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(dto.PreviewOutput{HTML: html})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/helpers"
+)
+
+// Auth holds the middlewares that run after jwtauth.Authenticator and
+// check the account behind a valid token.
+type Auth struct {
+	UserDB database.UserInterface
+}
+
+func NewAuth(userDB database.UserInterface) *Auth {
+	return &Auth{UserDB: userDB}
+}
+
+// RejectBanned answers 403 to banned users, whose tokens stay valid until
+// they expire, and 401 when the account no longer exists.
+func (a *Auth) RejectBanned(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := helpers.GetMyOwnIdbyToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := a.UserDB.FindById(r.Context(), id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if user.Banned {
+			http.Error(w, "Account banned", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin lets only admins through. It must run after RejectBanned.
+func (a *Auth) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := helpers.GetMyOwnIdbyToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := a.UserDB.FindById(r.Context(), id)
+		if err != nil || user == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !user.IsAdmin() {
+			http.Error(w, "Admins only", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sallescosta/conduit-api/internal/infra/cache"
+)
+
+type CacheHandler struct {
+	Cache cache.Cache
+}
+
+func NewCacheHandler(c cache.Cache) *CacheHandler {
+	return &CacheHandler{Cache: c}
+}
+
+func (h *CacheHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Cache.Stats())
+}
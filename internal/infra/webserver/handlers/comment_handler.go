@@ -1,24 +1,49 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/sallescosta/conduit-api/internal/dto"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
 	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
 	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
 	"github.com/sallescosta/conduit-api/pkg/helpers"
+	"github.com/sallescosta/conduit-api/pkg/stream"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type CommentHandler struct {
 	CommentDB database.CommentInterface
+	ArticleDB database.ArticleInterface
+	UserDB    database.UserInterface
+	Notifier  *Notifier
+	Broker    stream.Broker
+	MaxDepth  int
+	SiteURL   string
 }
 
-func NewCommentHandler(commentDB database.CommentInterface) *CommentHandler {
-	return &CommentHandler{CommentDB: commentDB}
+func NewCommentHandler(commentDB database.CommentInterface, articleDB database.ArticleInterface, userDB database.UserInterface,
+	notifier *Notifier, broker stream.Broker, maxDepth int, siteURL string) *CommentHandler {
+	return &CommentHandler{
+		CommentDB: commentDB,
+		ArticleDB: articleDB,
+		UserDB:    userDB,
+		Notifier:  notifier,
+		Broker:    broker,
+		MaxDepth:  maxDepth,
+		SiteURL:   strings.TrimRight(siteURL, "/"),
+	}
 }
 
+// CreateComment adds a comment to the article at {slug}. An article_id in
+// the body is only accepted when it names that same article.
 func (c *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	var comment dto.AddCommentInput
 
@@ -35,22 +60,62 @@ func (c *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	articleId := comment.Comment.ArticleID
-	fmt.Println("authorID", authorId)
-	fmt.Println("AAAArticleID", articleId)
-	newComment := entityComment.NewComment(
-		comment.Comment.Body,
-		authorId,
-		articleId,
-	)
+	article, ok := c.articleFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	articleId := article.ID.String()
+	if comment.Comment.ArticleID != "" && comment.Comment.ArticleID != articleId {
+		http.Error(w, "article_id does not match the article in the URL", http.StatusBadRequest)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "creating comment", slog.String("article_id", articleId),
+		slog.String("parent_id", comment.Comment.ParentID))
 
-	err = c.CommentDB.CreateCommentDb(newComment)
+	var newComment *entityComment.Comment
+
+	if comment.Comment.ParentID == "" {
+		newComment = entityComment.NewComment(
+			comment.Comment.Body,
+			authorId,
+			articleId,
+		)
+	} else {
+		parent, err := c.CommentDB.GetCommentByIdDb(r.Context(), comment.Comment.ParentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Parent comment not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if parent.ArticleID != articleId {
+			http.Error(w, "Parent comment belongs to another article", http.StatusBadRequest)
+			return
+		}
+
+		newComment, err = entityComment.NewReply(comment.Comment.Body, authorId, parent, c.MaxDepth)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	err = c.CommentDB.CreateCommentDb(r.Context(), newComment)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Error creating comment entity\n"))
 		return
 	}
 
+	instrument.CommentsCreated.Inc()
+	c.Notifier.Commented(r.Context(), newComment, article)
+	publishCommentEvent(r.Context(), c.Broker, EventCommentCreated, articleId, newComment)
+
 	w.WriteHeader(http.StatusCreated)
 	err = json.NewEncoder(w).Encode(newComment)
 	if err != nil {
@@ -58,30 +123,226 @@ func (c *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetComments returns a page of comment threads, nested as a tree, or as a
+// flat list with their depth when called with ?view=flat. Pages are cut on
+// top-level comments and ordered with ?sort=oldest|newest.
 func (c *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
-	commentsList, err := c.CommentDB.GetCommentsDb(slug)
+	viewerId, err := helpers.GetMyOwnIdbyToken(r)
 	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort != "newest" {
+		sort = "oldest"
+	}
+
+	page, err := c.CommentDB.GetCommentsDb(r.Context(), slug, database.CommentsQuery{
+		ViewerID: viewerId,
+		Limit:    limit,
+		Cursor:   r.URL.Query().Get("cursor"),
+		Sort:     sort,
+	})
+	if err != nil {
+		if errors.Is(err, helpers.ErrInvalidCursor) {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	for id, author := range page.Authors {
+		author.Image = userEntity.AvatarURL(c.SiteURL, author.Username, author.Image)
+		page.Authors[id] = author
+	}
+
+	tree := entityComment.BuildTree(page.Comments)
+
+	response := dto.AllCommentsOutput{
+		Comments:      []dto.Comment{},
+		CommentsCount: page.Total,
+		NextCursor:    page.NextCursor,
+	}
+
+	if r.URL.Query().Get("view") == "flat" {
+		for _, comment := range entityComment.Flatten(tree) {
+			response.Comments = append(response.Comments, toCommentOutput(&comment, page.Authors))
+		}
+	} else {
+		for _, comment := range tree {
+			response.Comments = append(response.Comments, toCommentOutput(comment, page.Authors))
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	json.NewEncoder(w).Encode(commentsList)
+	json.NewEncoder(w).Encode(response)
+}
+
+func toCommentOutput(comment *entityComment.Comment, authors map[string]dto.AuthorOutput) dto.Comment {
+	output := dto.Comment{
+		ID:        comment.ID.String(),
+		ParentID:  comment.ParentID,
+		Depth:     comment.Depth,
+		Deleted:   comment.Deleted,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		Body:      comment.Body,
+	}
+
+	if !comment.Deleted {
+		output.Author = authors[comment.AuthorID]
+	}
+
+	for _, reply := range comment.Replies {
+		output.Replies = append(output.Replies, toCommentOutput(reply, authors))
+	}
+
+	return output
+}
+
+func (c *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var input dto.UpdateCommentInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil || input.Comment.Body == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	authorId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	comment, ok := c.commentFromURL(w, r, id)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != authorId {
+		http.Error(w, "Only the author can edit this comment", http.StatusForbidden)
+		return
+	}
+
+	if err := comment.Edit(input.Comment.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	err = c.CommentDB.UpdateCommentDb(r.Context(), comment)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	author, err := c.UserDB.FindById(r.Context(), authorId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// the editor is the author, so there is no following to report
+	authors := map[string]dto.AuthorOutput{
+		comment.AuthorID: {
+			Username: author.UserName,
+			Bio:      author.Bio,
+			Image:    userEntity.AvatarURL(c.SiteURL, author.UserName, author.Image),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toCommentOutput(comment, authors)); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 }
 
 func (c *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	err := c.CommentDB.DeleteCommentsDb(id)
+	authorId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	comment, ok := c.commentFromURL(w, r, id)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != authorId {
+		http.Error(w, "Only the author can delete this comment", http.StatusForbidden)
+		return
+	}
+
+	err = c.CommentDB.DeleteCommentsDb(r.Context(), id)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	publishCommentEvent(r.Context(), c.Broker, EventCommentDeleted, comment.ArticleID, commentDeletedEvent{ID: id})
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("comment removed."))
 }
+
+// articleFromURL loads the article at {slug}, answering 404 when there is
+// none.
+func (c *CommentHandler) articleFromURL(w http.ResponseWriter, r *http.Request) (*articleEntity.Article, bool) {
+	article, err := c.ArticleDB.GetArticleBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Article not found", http.StatusNotFound)
+			return nil, false
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	return article, true
+}
+
+// commentFromURL loads comment id of the article at {slug}. A comment of
+// another article is answered with 404, like one that does not exist.
+func (c *CommentHandler) commentFromURL(w http.ResponseWriter, r *http.Request, id string) (*entityComment.Comment, bool) {
+	article, ok := c.articleFromURL(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	comment, err := c.CommentDB.GetCommentByIdDb(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return nil, false
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if comment.ArticleID != article.ID.String() {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return nil, false
+	}
+	return comment, true
+}
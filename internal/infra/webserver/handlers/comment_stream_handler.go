@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/stream"
+)
+
+// Events pushed on the comment stream of an article.
+const (
+	EventCommentCreated = "comment-created"
+	EventCommentDeleted = "comment-deleted"
+)
+
+type CommentStreamHandler struct {
+	ArticleDB database.ArticleInterface
+	Broker    stream.Broker
+	Heartbeat time.Duration
+}
+
+func NewCommentStreamHandler(articleDB database.ArticleInterface, broker stream.Broker, heartbeat time.Duration) *CommentStreamHandler {
+	return &CommentStreamHandler{ArticleDB: articleDB, Broker: broker, Heartbeat: heartbeat}
+}
+
+// StreamComments pushes the comments created and deleted on an article as
+// Server-Sent Events.
+func (h *CommentStreamHandler) StreamComments(w http.ResponseWriter, r *http.Request) {
+	article, err := h.ArticleDB.GetArticleBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if err != nil {
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	stream.Serve(w, r, h.Broker, commentsTopic(article.ID.String()), h.Heartbeat)
+}
+
+// commentsTopic is keyed by article ID rather than slug, which changes
+// with the title.
+func commentsTopic(articleID string) string {
+	return "comments:" + articleID
+}
+
+type commentDeletedEvent struct {
+	ID string `json:"id"`
+}
+
+// publishCommentEvent sends v on the comment stream of articleID. Failures
+// are logged and never fail the request that triggered them.
+func publishCommentEvent(ctx context.Context, broker stream.Broker, eventType, articleID string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = broker.Publish(ctx, commentsTopic(articleID), eventType, data)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "comment event not published", slog.String("type", eventType),
+			slog.String("article_id", articleID), slog.String("error", err.Error()))
+	}
+}
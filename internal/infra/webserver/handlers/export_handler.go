@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sallescosta/conduit-api/internal/dto"
+	exportEntity "github.com/sallescosta/conduit-api/internal/entity/export"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/helpers"
+)
+
+// ExportHandler lets users download a copy of their data. Archives are
+// built by exports.Worker; the download link carries its own token so it
+// works without a session until it expires.
+type ExportHandler struct {
+	ExportDB database.ExportInterface
+	SiteURL  string
+}
+
+func NewExportHandler(exportDB database.ExportInterface, siteURL string) *ExportHandler {
+	return &ExportHandler{ExportDB: exportDB, SiteURL: strings.TrimRight(siteURL, "/")}
+}
+
+// RequestExport queues an export of the current user's data, or returns the
+// one already waiting to be built.
+func (h *ExportHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	export, err := h.ExportDB.PendingExport(r.Context(), myId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if export == nil {
+		export, err = exportEntity.NewExport(myId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := h.ExportDB.CreateExport(r.Context(), export); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/user/export/"+export.ID.String())
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(h.toExportOutput(export))
+}
+
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	export, err := h.ExportDB.GetExport(r.Context(), chi.URLParam(r, "id"))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if export == nil || export.UserID != myId {
+		http.Error(w, "Export not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.toExportOutput(export))
+}
+
+// DownloadExport serves the archive to whoever holds the link.
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	export, archive, err := h.ExportDB.GetExportArchive(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Export not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if export.Expired(time.Now()) {
+		http.Error(w, "Export expired", http.StatusGone)
+		return
+	}
+	if export.Status != exportEntity.StatusReady {
+		http.Error(w, "Export not ready", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="conduit-export-`+export.UpdatedAt.Format("2006-01-02")+`.zip"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+func (h *ExportHandler) toExportOutput(e *exportEntity.Export) dto.ExportOutput {
+	output := dto.ExportOutput{
+		ID:        e.ID.String(),
+		Status:    e.Status,
+		Size:      e.Size,
+		Error:     e.Error,
+		CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: e.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if e.Expired(time.Now()) {
+		output.Status = exportEntity.StatusExpired
+	} else if e.Status == exportEntity.StatusReady {
+		output.DownloadURL = h.SiteURL + "/api/exports/" + e.Token
+	}
+	if !e.ExpiresAt.IsZero() {
+		output.ExpiresAt = e.ExpiresAt.Format(time.RFC3339)
+	}
+
+	return output
+}
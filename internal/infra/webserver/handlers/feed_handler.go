@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/conditional"
+	"github.com/sallescosta/conduit-api/pkg/feed"
+)
+
+// FeedHandler serves public Atom and RSS feeds of the newest articles. Links
+// are made absolute with SiteURL, which is where the feeds are served.
+type FeedHandler struct {
+	ArticleDB database.ArticleInterface
+	UserDB    database.UserInterface
+	SiteURL   string
+	Items     int
+}
+
+func NewFeedHandler(articleDB database.ArticleInterface, userDB database.UserInterface, siteURL string, items int) *FeedHandler {
+	return &FeedHandler{ArticleDB: articleDB, UserDB: userDB, SiteURL: strings.TrimRight(siteURL, "/"), Items: items}
+}
+
+func (h *FeedHandler) GlobalFeed(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, path.Base(r.URL.Path), database.ArticleQuery{}, feed.Feed{
+		Title:       "Conduit",
+		Description: "Latest articles",
+		Link:        h.SiteURL + "/",
+	})
+}
+
+// AuthorFeed and TagFeed take the format from the extension of the last path
+// segment, so usernames and tags may themselves contain dots.
+func (h *FeedHandler) AuthorFeed(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "username")
+	username, _ := splitFeedName(name)
+
+	if _, err := h.UserDB.GetProfileDb(r.Context(), username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.serve(w, r, name, database.ArticleQuery{Author: username}, feed.Feed{
+		Title:       "Conduit: articles by " + username,
+		Description: "Latest articles by " + username,
+		Link:        profileURL(h.SiteURL, username),
+	})
+}
+
+func (h *FeedHandler) TagFeed(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "tag")
+	tag, _ := splitFeedName(name)
+
+	h.serve(w, r, name, database.ArticleQuery{Tag: tag}, feed.Feed{
+		Title:       "Conduit: " + tag,
+		Description: "Latest articles tagged " + tag,
+		Link:        h.SiteURL + "/?tag=" + url.QueryEscape(tag),
+	})
+}
+
+func (h *FeedHandler) serve(w http.ResponseWriter, r *http.Request, name string, query database.ArticleQuery, f feed.Feed) {
+	base, format := splitFeedName(name)
+	if base == "" || format == "" {
+		http.Error(w, "Feed not found", http.StatusNotFound)
+		return
+	}
+
+	query.Limit = h.Items
+	query.Sort = "desc"
+
+	page, err := h.ArticleDB.QueryArticles(r.Context(), query)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	f.Self = h.SiteURL + r.URL.EscapedPath()
+	f.ID = f.Self
+
+	authors := map[string]string{}
+	for i := range page.Articles {
+		item, err := h.feedItem(r, &page.Articles[i], authors)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+		f.Items = append(f.Items, item)
+	}
+
+	conditional.SetLastModified(w, f.Updated)
+	w.Header().Set("Cache-Control", "public, no-cache")
+
+	write, contentType := feed.WriteAtom, feed.AtomContentType
+	if format == "rss" {
+		write, contentType = feed.WriteRSS, feed.RSSContentType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if err := write(w, f); err != nil {
+		slog.WarnContext(r.Context(), "feed not written", slog.String("error", err.Error()))
+	}
+}
+
+// feedItem renders the article the way GetArticle does when the stored HTML
+// predates rendering, and resolves its author through the authors cache.
+func (h *FeedHandler) feedItem(r *http.Request, article *articleEntity.Article, authors map[string]string) (feed.Item, error) {
+	if article.BodyHTML == "" && article.Body != "" {
+		if err := article.RenderBody(); err != nil {
+			return feed.Item{}, err
+		}
+		article.ComputeReadingMetadata()
+	}
+
+	author, ok := authors[article.AuthorID]
+	if !ok {
+		user, err := h.UserDB.FindById(r.Context(), article.AuthorID)
+		if err != nil {
+			return feed.Item{}, err
+		}
+		if user != nil {
+			author = user.UserName
+		}
+		authors[article.AuthorID] = author
+	}
+
+	published, _ := time.Parse(time.RFC3339Nano, article.CreatedAt)
+	updated, err := time.Parse(time.RFC3339Nano, article.UpdatedAt)
+	if err != nil {
+		updated = published
+	}
+
+	summary := article.Description
+	if summary == "" {
+		summary = article.Excerpt
+	}
+
+	return feed.Item{
+		ID:         "urn:uuid:" + article.ID.String(),
+		Title:      article.Title,
+		Link:       articleURL(h.SiteURL, article.Slug),
+		Author:     author,
+		Summary:    summary,
+		Content:    article.BodyHTML,
+		Categories: article.TagList,
+		Published:  published,
+		Updated:    updated,
+	}, nil
+}
+
+// splitFeedName splits "jake.atom" into "jake" and "atom". The format is
+// empty when the name does not end in .atom or .rss.
+func splitFeedName(name string) (string, string) {
+	for _, format := range []string{"atom", "rss"} {
+		if base, ok := strings.CutSuffix(name, "."+format); ok {
+			return base, format
+		}
+	}
+	return name, ""
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sallescosta/conduit-api/internal/dto"
+	notificationEntity "github.com/sallescosta/conduit-api/internal/entity/notification"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/helpers"
+)
+
+type NotificationHandler struct {
+	NotificationDB database.NotificationInterface
+	SiteURL        string
+}
+
+func NewNotificationHandler(notificationDB database.NotificationInterface, siteURL string) *NotificationHandler {
+	return &NotificationHandler{NotificationDB: notificationDB, SiteURL: strings.TrimRight(siteURL, "/")}
+}
+
+// ListNotifications returns the notifications of the current user, paged
+// with ?limit and ?offset; ?unread=true leaves out the ones already read.
+func (h *NotificationHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	params := r.URL.Query()
+
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(params.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	unreadOnly, _ := strconv.ParseBool(params.Get("unread"))
+
+	page, err := h.NotificationDB.ListNotifications(r.Context(), myId, database.NotificationsQuery{
+		UnreadOnly: unreadOnly,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for i := range page.Notifications {
+		actors := page.Notifications[i].Actors
+		for j := range actors {
+			actors[j].Image = userEntity.AvatarURL(h.SiteURL, actors[j].Username, actors[j].Image)
+		}
+	}
+
+	response := dto.NotificationsOutput{
+		Notifications:      page.Notifications,
+		NotificationsCount: page.Total,
+		UnreadCount:        page.Unread,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	err = h.NotificationDB.MarkRead(r.Context(), myId, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	if err := h.NotificationDB.MarkAllRead(r.Context(), myId); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	prefs, err := h.NotificationDB.GetPreferences(r.Context(), myId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.NotificationPreferences{Preferences: prefs})
+}
+
+// UpdatePreferences changes the types listed in the body and keeps the
+// others as they were.
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	var input dto.NotificationPreferences
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || len(input.Preferences) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	prefs, err := h.NotificationDB.GetPreferences(r.Context(), myId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := prefs.Merge(input.Preferences); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := h.NotificationDB.SetPreferences(r.Context(), myId, notificationEntity.Preferences(input.Preferences)); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.NotificationPreferences{Preferences: prefs})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	entityComment "github.com/sallescosta/conduit-api/internal/entity/comment"
+	notificationEntity "github.com/sallescosta/conduit-api/internal/entity/notification"
+	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
+	"github.com/sallescosta/conduit-api/internal/infra/webhooks"
+)
+
+// Notifier turns follows, comments and favorites into notifications for
+// the user they concern, and follows and comments into events for their
+// webhooks. Failures are logged and never fail the request that triggered
+// them.
+type Notifier struct {
+	NotificationDB database.NotificationInterface
+	ArticleDB      database.ArticleInterface
+	Webhooks       *webhooks.Dispatcher
+}
+
+func NewNotifier(notificationDB database.NotificationInterface, articleDB database.ArticleInterface, dispatcher *webhooks.Dispatcher) *Notifier {
+	return &Notifier{NotificationDB: notificationDB, ArticleDB: articleDB, Webhooks: dispatcher}
+}
+
+// Followed notifies the user called username that follower started
+// following them.
+func (n *Notifier) Followed(ctx context.Context, follower *userEntity.User, userID, username string) {
+	n.notify(ctx, userID, notificationEntity.TypeFollow, follower.ID.String(), "")
+
+	n.Webhooks.Emit(ctx, webhookEntity.EventUserFollowed, userID, map[string]interface{}{
+		"follower": map[string]string{"username": follower.UserName},
+		"user":     map[string]string{"username": username},
+	})
+}
+
+// Commented notifies the author of article of comment.
+func (n *Notifier) Commented(ctx context.Context, comment *entityComment.Comment, article *articleEntity.Article) {
+	n.notify(ctx, article.AuthorID, notificationEntity.TypeComment, comment.AuthorID, article.ID.String())
+
+	n.Webhooks.Emit(ctx, webhookEntity.EventCommentCreated, article.AuthorID, map[string]interface{}{
+		"article": articleReference(article),
+		"comment": comment,
+	})
+}
+
+// Favorited notifies the author of the article at slug that actorID
+// favorited it.
+func (n *Notifier) Favorited(ctx context.Context, actorID, slug string) {
+	n.notifyAuthor(ctx, notificationEntity.TypeFavorite, actorID, slug)
+}
+
+func (n *Notifier) notifyAuthor(ctx context.Context, notificationType, actorID, slug string) {
+	article, err := n.ArticleDB.GetArticleBySlug(ctx, slug)
+	if err != nil {
+		slog.WarnContext(ctx, "notification not sent", slog.String("type", notificationType),
+			slog.String("slug", slug), slog.String("error", err.Error()))
+		return
+	}
+
+	n.notify(ctx, article.AuthorID, notificationType, actorID, article.ID.String())
+}
+
+func (n *Notifier) notify(ctx context.Context, recipientID, notificationType, actorID, articleID string) {
+	notification, err := notificationEntity.NewNotification(recipientID, notificationType, actorID, articleID)
+	if errors.Is(err, notificationEntity.ErrSelfNotification) {
+		return
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "notification not sent", slog.String("type", notificationType),
+			slog.String("error", err.Error()))
+		return
+	}
+
+	delivered, err := n.NotificationDB.Notify(ctx, notification)
+	if err != nil {
+		slog.WarnContext(ctx, "notification not sent", slog.String("type", notificationType),
+			slog.String("recipient_id", recipientID), slog.String("error", err.Error()))
+		return
+	}
+
+	if delivered {
+		instrument.Notifications.WithLabelValues(notificationType).Inc()
+	}
+}
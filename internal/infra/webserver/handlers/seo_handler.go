@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/sitemap"
+)
+
+// SEOHandler serves what crawlers and link previews of the public site need:
+// the sitemap and the meta tags of each article.
+type SEOHandler struct {
+	ArticleDB database.ArticleInterface
+	UserDB    database.UserInterface
+	SitemapDB database.SitemapInterface
+	SiteURL   string
+	PageSize  int
+}
+
+func NewSEOHandler(articleDB database.ArticleInterface, userDB database.UserInterface, sitemapDB database.SitemapInterface, siteURL string) *SEOHandler {
+	return &SEOHandler{
+		ArticleDB: articleDB,
+		UserDB:    userDB,
+		SitemapDB: sitemapDB,
+		SiteURL:   strings.TrimRight(siteURL, "/"),
+		PageSize:  sitemap.MaxURLs,
+	}
+}
+
+// Sitemap lists every page when they fit in one sitemap, and otherwise
+// returns an index of the /sitemaps/{n}.xml files that split them.
+func (h *SEOHandler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	count, err := h.SitemapDB.CountSitemapEntries(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if count <= h.PageSize {
+		h.writeEntries(w, r, 0)
+		return
+	}
+
+	var sitemaps []sitemap.URL
+	for page := 1; (page-1)*h.PageSize < count; page++ {
+		sitemaps = append(sitemaps, sitemap.URL{Loc: h.SiteURL + "/sitemaps/" + strconv.Itoa(page) + ".xml"})
+	}
+
+	w.Header().Set("Content-Type", sitemap.ContentType)
+	w.WriteHeader(http.StatusOK)
+	if err := sitemap.WriteIndex(w, sitemaps); err != nil {
+		slog.WarnContext(r.Context(), "sitemap not written", slog.String("error", err.Error()))
+	}
+}
+
+func (h *SEOHandler) SitemapPage(w http.ResponseWriter, r *http.Request) {
+	number, ok := strings.CutSuffix(chi.URLParam(r, "page"), ".xml")
+	page, err := strconv.Atoi(number)
+	if !ok || err != nil || page < 1 {
+		http.Error(w, "Sitemap not found", http.StatusNotFound)
+		return
+	}
+
+	count, err := h.SitemapDB.CountSitemapEntries(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if (page-1)*h.PageSize >= count {
+		http.Error(w, "Sitemap not found", http.StatusNotFound)
+		return
+	}
+
+	h.writeEntries(w, r, (page-1)*h.PageSize)
+}
+
+func (h *SEOHandler) writeEntries(w http.ResponseWriter, r *http.Request, offset int) {
+	entries, err := h.SitemapDB.ListSitemapEntries(r.Context(), offset, h.PageSize)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	urls := make([]sitemap.URL, len(entries))
+	for i, e := range entries {
+		urls[i].LastMod = e.LastMod
+		if e.Kind == database.SitemapArticle {
+			urls[i].Loc = articleURL(h.SiteURL, e.Name)
+		} else {
+			urls[i].Loc = profileURL(h.SiteURL, e.Name)
+		}
+	}
+
+	w.Header().Set("Content-Type", sitemap.ContentType)
+	w.WriteHeader(http.StatusOK)
+	if err := sitemap.WriteURLSet(w, urls); err != nil {
+		slog.WarnContext(r.Context(), "sitemap not written", slog.String("error", err.Error()))
+	}
+}
+
+func (h *SEOHandler) ArticleMeta(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	article, err := h.ArticleDB.GetArticleBySlug(r.Context(), slug)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Article not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if article.Excerpt == "" && article.Body != "" {
+		article.ComputeReadingMetadata()
+	}
+
+	author := ""
+	user, err := h.UserDB.FindById(r.Context(), article.AuthorID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user != nil {
+		author = user.UserName
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(article.Meta(articleURL(h.SiteURL, article.Slug), author))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// articleURL and profileURL are the pages of the public site at siteURL.
+func articleURL(siteURL, slug string) string {
+	return siteURL + "/article/" + url.PathEscape(slug)
+}
+
+func profileURL(siteURL, username string) string {
+	return siteURL + "/profile/" + url.PathEscape(username)
+}
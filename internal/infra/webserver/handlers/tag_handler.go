@@ -16,7 +16,7 @@ func NewTagHandler(tagDB database.TagsInterface) *TagHandler {
 }
 
 func (h *TagHandler) ListTags(w http.ResponseWriter, r *http.Request) {
-	tags, err := h.TagDB.ListTags()
+	tags, err := h.TagDB.ListTags(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
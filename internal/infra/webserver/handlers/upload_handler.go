@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sallescosta/conduit-api/internal/dto"
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
+	"github.com/sallescosta/conduit-api/internal/infra/uploads"
+	"github.com/sallescosta/conduit-api/internal/infra/webhooks"
+	"github.com/sallescosta/conduit-api/pkg/blob"
+	"github.com/sallescosta/conduit-api/pkg/entity"
+	"github.com/sallescosta/conduit-api/pkg/helpers"
+	"github.com/sallescosta/conduit-api/pkg/imaging"
+)
+
+const (
+	avatarUploads = uploads.Avatars
+	coverUploads  = uploads.Covers
+)
+
+// uploadVariants are rendered for every upload. The first one is the URL
+// stored on the user or article.
+var uploadVariants = map[string][]imaging.Variant{
+	avatarUploads: {
+		{Name: "large", Width: 256, Height: 256, Mode: imaging.Cover},
+		{Name: "small", Width: 64, Height: 64, Mode: imaging.Cover},
+	},
+	coverUploads: {
+		{Name: "large", Width: 1600, Mode: imaging.Fit},
+		{Name: "medium", Width: 800, Mode: imaging.Fit},
+		{Name: "small", Width: 400, Mode: imaging.Fit},
+	},
+}
+
+// UploadHandler receives avatar and cover images as multipart forms with an
+// "image" field. Every upload gets a new ID, so its files never change and
+// are served as immutable; the previous upload is deleted once replaced.
+type UploadHandler struct {
+	UserDB    database.UserInterface
+	ArticleDB database.ArticleInterface
+	Store     blob.Store
+	Webhooks  *webhooks.Dispatcher
+	SiteURL   string
+	MaxBytes  int64
+}
+
+func NewUploadHandler(userDB database.UserInterface, articleDB database.ArticleInterface, store blob.Store,
+	dispatcher *webhooks.Dispatcher, siteURL string, maxBytes int64) *UploadHandler {
+	return &UploadHandler{
+		UserDB:    userDB,
+		ArticleDB: articleDB,
+		Store:     store,
+		Webhooks:  dispatcher,
+		SiteURL:   strings.TrimRight(siteURL, "/"),
+		MaxBytes:  maxBytes,
+	}
+}
+
+// UploadAvatar replaces the current user's image.
+func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	user, err := h.UserDB.FindById(r.Context(), myId)
+	if err != nil || user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	previous := user.Image
+	output, ok := h.store(w, r, avatarUploads, myId)
+	if !ok {
+		return
+	}
+
+	if _, err := h.UserDB.UpdateUserDb(r.Context(), myId, "", "", output.URL, ""); err != nil {
+		h.discard(r.Context(), avatarUploads, myId, output.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	h.discard(r.Context(), avatarUploads, myId, previous)
+
+	instrument.Uploads.WithLabelValues(avatarUploads, "ok").Inc()
+	writeUpload(w, output)
+}
+
+// UploadCover replaces the cover image of an article. Only its author can.
+func (h *UploadHandler) UploadCover(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	slug := chi.URLParam(r, "slug")
+	article, err := h.ArticleDB.GetArticleBySlug(r.Context(), slug)
+	if err != nil {
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+	if article.AuthorID != myId {
+		http.Error(w, "Only the author can change the cover", http.StatusForbidden)
+		return
+	}
+
+	articleID, previous := article.ID.String(), article.CoverImage
+	output, ok := h.store(w, r, coverUploads, articleID)
+	if !ok {
+		return
+	}
+
+	var modif dto.ArticleUpdateInput
+	modif.Article.CoverImage = output.URL
+	updated, err := h.ArticleDB.UpdateArticle(r.Context(), slug, modif)
+	if err != nil {
+		h.discard(r.Context(), coverUploads, articleID, output.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	h.discard(r.Context(), coverUploads, articleID, previous)
+
+	h.Webhooks.Emit(r.Context(), webhookEntity.EventArticleUpdated, updated.AuthorID,
+		map[string]interface{}{"article": updated})
+
+	instrument.Uploads.WithLabelValues(coverUploads, "ok").Inc()
+	writeUpload(w, output)
+}
+
+// ServeUpload serves a stored variant. Their keys are never reused, so
+// they can be cached for good.
+func (h *UploadHandler) ServeUpload(w http.ResponseWriter, r *http.Request) {
+	kind := chi.URLParam(r, "kind")
+	if _, ok := uploadVariants[kind]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	key := kind + "/" + chi.URLParam(r, "owner") + "/" + chi.URLParam(r, "name")
+
+	f, info, err := h.Store.Open(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if seeker, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", info.ModTime, seeker)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.Copy(w, f)
+	}
+}
+
+// store reads the "image" field of the form and stores its variants under
+// kind/owner. It answers the request itself when it fails.
+func (h *UploadHandler) store(w http.ResponseWriter, r *http.Request, kind, owner string) (dto.ImageUploadOutput, bool) {
+	// leave room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxBytes+64<<10)
+
+	img, err := h.readImage(r)
+	if err != nil {
+		instrument.Uploads.WithLabelValues(kind, "rejected").Inc()
+
+		var maxBytes *http.MaxBytesError
+		switch {
+		case errors.Is(err, imaging.ErrTooLarge), errors.As(err, &maxBytes):
+			http.Error(w, imaging.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, imaging.ErrUnsupportedType):
+			http.Error(w, imaging.ErrUnsupportedType.Error(), http.StatusUnsupportedMediaType)
+		case errors.Is(err, imaging.ErrTooManyPixels):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return dto.ImageUploadOutput{}, false
+	}
+
+	uploadID := entity.NewID().String()
+	output := dto.ImageUploadOutput{Variants: map[string]string{}}
+
+	for i, v := range uploadVariants[kind] {
+		data, err := imaging.Render(img, v)
+		if err == nil {
+			err = h.Store.Put(r.Context(), uploadKey(kind, owner, uploadID, v.Name), bytes.NewReader(data), "image/jpeg")
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error storing upload",
+				slog.String("kind", kind), slog.String("error", err.Error()))
+			instrument.Uploads.WithLabelValues(kind, "error").Inc()
+			h.deleteUpload(r.Context(), kind, owner, uploadID)
+			w.WriteHeader(http.StatusInternalServerError)
+			return dto.ImageUploadOutput{}, false
+		}
+
+		url := h.SiteURL + "/uploads/" + uploadKey(kind, owner, uploadID, v.Name)
+		output.Variants[v.Name] = url
+		if i == 0 {
+			output.URL = url
+		}
+	}
+	return output, true
+}
+
+func (h *UploadHandler) readImage(r *http.Request) (*imaging.Image, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("body must be multipart/form-data")
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New(`the "image" field is required`)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "image" {
+			return imaging.Decode(part, h.MaxBytes)
+		}
+	}
+}
+
+// discard deletes the upload a URL points to, when it is one of ours.
+// Images set by URL before uploads existed are left alone.
+func (h *UploadHandler) discard(ctx context.Context, kind, owner, url string) {
+	prefix := h.SiteURL + "/uploads/" + kind + "/" + owner + "/"
+	name, ok := strings.CutPrefix(url, prefix)
+	if !ok {
+		return
+	}
+	// the upload ID is a UUID, which has dashes of its own
+	i := strings.LastIndex(name, "-")
+	if i <= 0 {
+		return
+	}
+	h.deleteUpload(ctx, kind, owner, name[:i])
+}
+
+func (h *UploadHandler) deleteUpload(ctx context.Context, kind, owner, uploadID string) {
+	for _, v := range uploadVariants[kind] {
+		if err := h.Store.Delete(ctx, uploadKey(kind, owner, uploadID, v.Name)); err != nil {
+			slog.WarnContext(ctx, "error deleting upload",
+				slog.String("kind", kind), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func uploadKey(kind, owner, uploadID, variant string) string {
+	return uploads.Prefix(kind, owner) + "/" + uploadID + "-" + variant + ".jpg"
+}
+
+func writeUpload(w http.ResponseWriter, output dto.ImageUploadOutput) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
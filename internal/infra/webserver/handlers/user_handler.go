@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -12,15 +15,20 @@ import (
 	"github.com/sallescosta/conduit-api/internal/dto"
 	userEntity "github.com/sallescosta/conduit-api/internal/entity/user"
 	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/internal/infra/instrument"
 	"github.com/sallescosta/conduit-api/pkg/helpers"
+	"github.com/sallescosta/conduit-api/pkg/identicon"
+	"github.com/sallescosta/conduit-api/pkg/tracing"
 )
 
 type UserHandler struct {
-	UserDB database.UserInterface
+	UserDB   database.UserInterface
+	Notifier *Notifier
+	SiteURL  string
 }
 
-func NewUserHandler(userDB database.UserInterface) *UserHandler {
-	return &UserHandler{UserDB: userDB}
+func NewUserHandler(userDB database.UserInterface, notifier *Notifier, siteURL string) *UserHandler {
+	return &UserHandler{UserDB: userDB, Notifier: notifier, SiteURL: strings.TrimRight(siteURL, "/")}
 }
 
 type RegistrationInput struct {
@@ -47,8 +55,13 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.User.UserName == userEntity.DeletedUserName {
+		http.Error(w, "Username is reserved", http.StatusConflict)
+		return
+	}
+
 	email := user.User.Email
-	userFound, err := h.UserDB.FindByEmail(email)
+	userFound, err := h.UserDB.FindByEmail(r.Context(), email)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -59,18 +72,22 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, span := tracing.Start(r.Context(), "bcrypt.hash")
 	u, err := userEntity.NewUser(user.User.UserName, user.User.Email, user.User.Password)
+	tracing.SetError(span, err)
+	span.End()
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	err = h.UserDB.CreateUser(u)
+	err = h.UserDB.CreateUser(r.Context(), u)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	instrument.Registrations.Inc()
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -84,16 +101,34 @@ func (h *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := h.UserDB.FindByEmail(user.User.Email)
+	u, err := h.UserDB.FindByEmail(r.Context(), user.User.Email)
 	if err != nil {
+		instrument.Logins.WithLabelValues("error").Inc()
 		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	valid := false
+	if u != nil {
+		_, span := tracing.Start(r.Context(), "bcrypt.compare")
+		valid = u.ValidatePassword(user.User.Password)
+		span.End()
 	}
 
-	if !u.ValidatePassword(user.User.Password) {
+	if !valid {
+		instrument.Logins.WithLabelValues("failure").Inc()
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
+	if u.Banned {
+		instrument.Logins.WithLabelValues("banned").Inc()
+		http.Error(w, "Account banned", http.StatusForbidden)
+		return
+	}
+
+	instrument.Logins.WithLabelValues("success").Inc()
+
 	m := map[string]interface{}{
 		"sub": u.ID.String(),
 		"exp": time.Now().Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
@@ -111,22 +146,16 @@ func (h *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *UserHandler) ListAllUsers(w http.ResponseWriter, r *http.Request) {
-	list, err := h.UserDB.GetAllUsers()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	err = json.NewEncoder(w).Encode(list)
+// UpdateUser changes the current user's profile. The account is the one of
+// the token; the email cannot be changed, so a different one is rejected.
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.GetMyOwnIdbyToken(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
 	}
-}
 
-func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	var user dto.UserDTO
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
 
@@ -134,11 +163,31 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedUser, err := h.UserDB.UpdateUserDb(user.User.Email, user.User.UserName, user.User.Password, user.User.Image, user.User.Bio)
+	if user.User.UserName == userEntity.DeletedUserName {
+		http.Error(w, "Username is reserved", http.StatusConflict)
+		return
+	}
+
+	current, err := h.UserDB.FindById(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if user.User.Email != "" && user.User.Email != current.Email {
+		http.Error(w, "Email cannot be changed", http.StatusUnprocessableEntity)
+		return
+	}
+
+	updatedUser, err := h.UserDB.UpdateUserDb(r.Context(), id, user.User.UserName, user.User.Password, user.User.Image, user.User.Bio)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	updatedUser.Image = userEntity.AvatarURL(h.SiteURL, updatedUser.UserName, updatedUser.Image)
 	err = json.NewEncoder(w).Encode(updatedUser)
 	if err != nil {
 		return
@@ -153,11 +202,14 @@ func (h *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.UserDB.FindById(id)
+	user, err := h.UserDB.FindById(r.Context(), id)
 
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 	}
+	if user != nil {
+		user.Image = userEntity.AvatarURL(h.SiteURL, user.UserName, user.Image)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -167,6 +219,65 @@ func (h *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// DeleteAccount deletes the caller's account once they confirm their
+// password. Their articles and comments are deleted or anonymized, as they
+// choose.
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var input dto.DeleteAccountInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !userEntity.ValidContentMode(input.User.Content) {
+		http.Error(w, fmt.Sprintf("content must be %q or %q", userEntity.DeleteContent, userEntity.AnonymizeContent),
+			http.StatusUnprocessableEntity)
+		return
+	}
+
+	u, err := h.UserDB.FindById(r.Context(), id)
+	if err == nil && u != nil {
+		// the cached user has no password hash, so check it against the database
+		u, err = h.UserDB.FindByEmail(r.Context(), u.Email)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if u == nil || u.ID.String() != id {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "bcrypt.compare")
+	valid := u.ValidatePassword(input.User.Password)
+	span.End()
+
+	if !valid {
+		http.Error(w, "Invalid password", http.StatusForbidden)
+		return
+	}
+
+	if _, err := h.UserDB.DeleteUser(r.Context(), id, input.User.Content); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		slog.ErrorContext(r.Context(), "error deleting account", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	instrument.AccountDeletions.WithLabelValues(input.User.Content).Inc()
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *UserHandler) GetProfileUser(w http.ResponseWriter, r *http.Request) {
 	userName := chi.URLParam(r, "username")
 
@@ -175,7 +286,7 @@ func (h *UserHandler) GetProfileUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := h.UserDB.GetProfileDb(userName)
+	p, err := h.UserDB.GetProfileDb(r.Context(), userName)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "no rows in result set") {
@@ -193,7 +304,7 @@ func (h *UserHandler) GetProfileUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.UserDB.FindById(id)
+	user, err := h.UserDB.FindById(r.Context(), id)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -204,7 +315,7 @@ func (h *UserHandler) GetProfileUser(w http.ResponseWriter, r *http.Request) {
 		Profile: dto.Profile{
 			UserName:  userName,
 			Bio:       p.Profile.Bio,
-			Image:     p.Profile.Image,
+			Image:     userEntity.AvatarURL(h.SiteURL, userName, p.Profile.Image),
 			Following: isFollowing,
 		},
 	}
@@ -216,6 +327,28 @@ func (h *UserHandler) GetProfileUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// DefaultAvatar serves the identicon of a user, generated from their ID so
+// it survives a change of username. It is public, since images are loaded
+// without the API token.
+func (h *UserHandler) DefaultAvatar(w http.ResponseWriter, r *http.Request) {
+	p, err := h.UserDB.GetProfileDb(r.Context(), chi.URLParam(r, "username"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(identicon.SVG(p.Profile.ID.String()))
+}
+
 func (h *UserHandler) FollowUser(w http.ResponseWriter, r *http.Request) {
 	myId, err := helpers.GetMyOwnIdbyToken(r)
 	if err != nil {
@@ -224,14 +357,14 @@ func (h *UserHandler) FollowUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mySelf, err := h.UserDB.FindById(myId)
+	mySelf, err := h.UserDB.FindById(r.Context(), myId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 
 	userName := chi.URLParam(r, "username")
 
-	p, err := h.UserDB.GetProfileDb(userName)
+	p, err := h.UserDB.GetProfileDb(r.Context(), userName)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -263,17 +396,21 @@ func (h *UserHandler) FollowUser(w http.ResponseWriter, r *http.Request) {
 		isFollowing = false
 	}
 
-	err = h.UserDB.UpdateFollowingUserDb(mySelf.ID.String(), mySelf.Following)
+	err = h.UserDB.UpdateFollowingUserDb(r.Context(), mySelf.ID.String(), mySelf.Following)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	if isFollowing {
+		h.Notifier.Followed(r.Context(), mySelf, p.Profile.ID.String(), userName)
+	}
+
 	profile := dto.ProfileDTO{
 		Profile: dto.Profile{
 			UserName:  userName,
 			Bio:       p.Profile.Bio,
-			Image:     p.Profile.Image,
+			Image:     userEntity.AvatarURL(h.SiteURL, userName, p.Profile.Image),
 			Following: isFollowing,
 		},
 	}
@@ -295,13 +432,18 @@ func (h *UserHandler) FavoriteArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.UserDB.FavoriteArticleDB(slug, isAddToFavorite, id)
+	err = h.UserDB.FavoriteArticleDB(r.Context(), slug, isAddToFavorite, id)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(fmt.Sprintf("Error: %v", err)))
 		return
 	}
 
+	instrument.Favorites.WithLabelValues(helpers.Ternary(isAddToFavorite, "add", "remove").(string)).Inc()
+	if isAddToFavorite {
+		h.Notifier.Favorited(r.Context(), id, slug)
+	}
+
 	w.WriteHeader(http.StatusOK)
 	addMessage := "Article added to favorites"
 	removedMessage := "Article removed from favorites"
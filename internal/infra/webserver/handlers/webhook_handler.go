@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sallescosta/conduit-api/internal/dto"
+	articleEntity "github.com/sallescosta/conduit-api/internal/entity/article"
+	webhookEntity "github.com/sallescosta/conduit-api/internal/entity/webhook"
+	"github.com/sallescosta/conduit-api/internal/infra/database"
+	"github.com/sallescosta/conduit-api/pkg/helpers"
+)
+
+type WebhookHandler struct {
+	WebhookDB database.WebhookInterface
+	UserDB    database.UserInterface
+}
+
+func NewWebhookHandler(webhookDB database.WebhookInterface, userDB database.UserInterface) *WebhookHandler {
+	return &WebhookHandler{WebhookDB: webhookDB, UserDB: userDB}
+}
+
+// CreateWebhook registers a webhook for the current user. Only admins can
+// register global webhooks. The secret is only ever returned here.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	var input dto.WebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if input.Webhook.Global {
+		me, err := h.UserDB.FindById(r.Context(), myId)
+		if err != nil || me == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !me.IsAdmin() {
+			http.Error(w, "Only admins can register global webhooks", http.StatusForbidden)
+			return
+		}
+	}
+
+	hook, err := webhookEntity.NewWebhook(myId, input.Webhook.URL, input.Webhook.Events, input.Webhook.Global)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := h.WebhookDB.CreateWebhook(r.Context(), hook); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	output := toWebhookOutput(hook)
+	output.Secret = hook.Secret
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(output)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+
+	hooks, err := h.WebhookDB.ListWebhooks(r.Context(), myId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.WebhooksOutput{Webhooks: []dto.WebhookOutput{}, WebhooksCount: len(hooks)}
+	for i := range hooks {
+		response.Webhooks = append(response.Webhooks, toWebhookOutput(&hooks[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.ownWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.WebhookDB.DeleteWebhook(r.Context(), hook.ID.String()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns the delivery log of a webhook, the newest first,
+// paged with ?limit and ?offset.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.ownWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(params.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	page, err := h.WebhookDB.ListDeliveries(r.Context(), hook.ID.String(), database.DeliveriesQuery{Limit: limit, Offset: offset})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.DeliveriesOutput{Deliveries: []dto.DeliveryOutput{}, DeliveriesCount: page.Total}
+	for i := range page.Deliveries {
+		response.Deliveries = append(response.Deliveries, toDeliveryOutput(&page.Deliveries[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Redeliver queues the payload of a past delivery again, as a new delivery.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.ownWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.WebhookDB.GetDelivery(r.Context(), chi.URLParam(r, "deliveryId"))
+	if err != nil || delivery.WebhookID != hook.ID.String() {
+		if err == nil || errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	again := delivery.Redeliver()
+	if err := h.WebhookDB.CreateDelivery(r.Context(), again); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(toDeliveryOutput(again))
+}
+
+// ownWebhook loads the webhook named in the URL and checks the current
+// user owns it or is an admin. It answers the request itself when not.
+func (h *WebhookHandler) ownWebhook(w http.ResponseWriter, r *http.Request) (*webhookEntity.Webhook, bool) {
+	myId, err := helpers.GetMyOwnIdbyToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return nil, false
+	}
+
+	hook, err := h.WebhookDB.GetWebhook(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return nil, false
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if hook.OwnerID != myId {
+		me, err := h.UserDB.FindById(r.Context(), myId)
+		if err != nil || me == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return nil, false
+		}
+		if !me.IsAdmin() {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return nil, false
+		}
+	}
+
+	return hook, true
+}
+
+func toWebhookOutput(hook *webhookEntity.Webhook) dto.WebhookOutput {
+	return dto.WebhookOutput{
+		ID:        hook.ID.String(),
+		URL:       hook.URL,
+		Events:    hook.Events,
+		Global:    hook.Global,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+func toDeliveryOutput(d *webhookEntity.Delivery) dto.DeliveryOutput {
+	return dto.DeliveryOutput{
+		ID:             d.ID.String(),
+		Event:          d.Event,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		Error:          d.Error,
+		NextAttemptAt:  d.NextAttemptAt.Format(time.RFC3339),
+		CreatedAt:      d.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      d.UpdatedAt.Format(time.RFC3339),
+		Payload:        d.Payload,
+	}
+}
+
+// articleReference identifies an article in webhook payloads that do not
+// carry the whole article.
+func articleReference(article *articleEntity.Article) map[string]string {
+	return map[string]string{
+		"id":    article.ID.String(),
+		"slug":  article.Slug,
+		"title": article.Title,
+	}
+}
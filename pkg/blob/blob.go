@@ -0,0 +1,142 @@
+// Package blob stores files under slash-separated keys, such as
+// "avatars/<user>/<id>-small.jpg", behind an interface so the local
+// filesystem can later be swapped for object storage.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob: not found")
+	ErrInvalidKey = errors.New("blob: invalid key")
+)
+
+// Info describes a stored file.
+type Info struct {
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+}
+
+type Store interface {
+	// Put stores the content of r under key, replacing any previous file.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Open returns the file under key, or ErrNotFound. The caller closes it.
+	Open(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	// Delete removes the file under key. Deleting a missing key is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// DeleteAll removes every file whose key starts with prefix followed by
+	// a slash, e.g. all the files of "avatars/<user>".
+	DeleteAll(ctx context.Context, prefix string) error
+}
+
+// Local stores files in a directory. The content type is derived from the
+// key's extension, so keys should carry one.
+type Local struct {
+	Dir string
+}
+
+// NewLocal stores files under dir, which is created on the first Put.
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// write next to the destination and rename, so readers never see a
+	// partial file
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, Info{}, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, Info{}, ErrNotFound
+		}
+		return nil, Info{}, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Info{}, err
+	}
+	if stat.IsDir() {
+		f.Close()
+		return nil, Info{}, ErrNotFound
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return f, Info{Size: stat.Size(), ModTime: stat.ModTime(), ContentType: contentType}, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) DeleteAll(ctx context.Context, prefix string) error {
+	name, err := l.path(prefix)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(name)
+}
+
+// path maps key inside Dir, refusing keys that would escape it.
+func (l *Local) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
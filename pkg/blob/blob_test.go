@@ -0,0 +1,89 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_PutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocal(t.TempDir())
+
+	require.NoError(t, store.Put(ctx, "avatars/u1/a-small.jpg", strings.NewReader("first"), "image/jpeg"))
+	require.NoError(t, store.Put(ctx, "avatars/u1/a-small.jpg", strings.NewReader("second"), "image/jpeg"))
+
+	f, info, err := store.Open(ctx, "avatars/u1/a-small.jpg")
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, "second", string(content))
+	assert.Equal(t, int64(6), info.Size)
+	assert.Equal(t, "image/jpeg", info.ContentType)
+	assert.False(t, info.ModTime.IsZero())
+
+	require.NoError(t, store.Delete(ctx, "avatars/u1/a-small.jpg"))
+	require.NoError(t, store.Delete(ctx, "avatars/u1/a-small.jpg"), "deleting twice is fine")
+
+	_, _, err = store.Open(ctx, "avatars/u1/a-small.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_NoTemporaryFilesLeft(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocal(dir)
+	require.NoError(t, store.Put(context.Background(), "covers/a/b.jpg", strings.NewReader("x"), "image/jpeg"))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "covers", "a"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "b.jpg", entries[0].Name())
+}
+
+func TestLocal_InvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocal(t.TempDir())
+
+	for _, key := range []string{"", ".", "../secret", "/etc/passwd", "a/../../b", "a//b"} {
+		assert.ErrorIs(t, store.Put(ctx, key, strings.NewReader("x"), ""), ErrInvalidKey, key)
+		_, _, err := store.Open(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
+
+func TestLocal_OpenDirectory(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocal(t.TempDir())
+	require.NoError(t, store.Put(ctx, "avatars/u1/a.jpg", strings.NewReader("x"), "image/jpeg"))
+
+	_, _, err := store.Open(ctx, "avatars/u1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_DeleteAll(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocal(t.TempDir())
+	for _, key := range []string{"covers/a/1-small.jpg", "covers/a/1-large.jpg", "covers/ab/1-small.jpg"} {
+		require.NoError(t, store.Put(ctx, key, strings.NewReader("x"), "image/jpeg"))
+	}
+
+	require.NoError(t, store.DeleteAll(ctx, "covers/a"))
+	require.NoError(t, store.DeleteAll(ctx, "covers/a"), "deleting twice is fine")
+
+	_, _, err := store.Open(ctx, "covers/a/1-small.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+	f, _, err := store.Open(ctx, "covers/ab/1-small.jpg")
+	require.NoError(t, err)
+	f.Close()
+
+	assert.ErrorIs(t, store.DeleteAll(ctx, "../covers"), ErrInvalidKey)
+	assert.ErrorIs(t, store.DeleteAll(ctx, "."), ErrInvalidKey)
+}
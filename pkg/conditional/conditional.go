@@ -0,0 +1,132 @@
+package conditional
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Middleware answers conditional GET and HEAD requests. The response is
+// buffered and, unless the handler already set one, given a strong ETag
+// computed from its body. Requests whose If-None-Match or If-Modified-Since
+// still match get a 304 without a body.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status != http.StatusOK {
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		if w.Header().Get("ETag") == "" {
+			sum := sha256.Sum256(rec.body.Bytes())
+			w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		}
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", "private, no-cache")
+		}
+
+		if NotModified(w, r) {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(rec.body.Bytes())
+	})
+}
+
+// SetWeakETag sets a weak validator built from values that change whenever
+// the resource does, such as its updatedAt and a version counter.
+func SetWeakETag(w http.ResponseWriter, parts ...string) {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	w.Header().Set("ETag", `W/"`+hex.EncodeToString(sum[:16])+`"`)
+}
+
+func SetLastModified(w http.ResponseWriter, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	w.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// NotModified compares the request preconditions with the ETag and
+// Last-Modified headers already set on w. When the client copy is still
+// fresh it writes a 304 and returns true. If-Modified-Since is only used
+// when the request has no If-None-Match.
+func NotModified(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !etagMatches(inm, w.Header().Get("ETag")) {
+			return false
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		modified, err := http.ParseTime(w.Header().Get("Last-Modified"))
+		if err != nil || modified.After(since) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches uses the weak comparison that RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	return r.body.Write(b)
+}
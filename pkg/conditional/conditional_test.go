@@ -0,0 +1,90 @@
+package conditional
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var body = []byte(`{"tags":["go"]}`)
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func TestMiddleware_StrongETag(t *testing.T) {
+	h := Middleware(http.HandlerFunc(handler))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tags", nil))
+
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, body, rec.Body.Bytes())
+	assert.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+
+	req.Header.Set("If-None-Match", `"other"`)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMiddleware_PassesErrorsThrough(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/articles/nope", nil)
+	req.Header.Set("If-None-Match", "*")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestNotModified_WeakETagAndLastModified(t *testing.T) {
+	updatedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetWeakETag(w, "article-id", updatedAt.String(), "3")
+		SetLastModified(w, updatedAt)
+		if NotModified(w, r) {
+			return
+		}
+		handler(w, r)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles/a", nil))
+	etag := rec.Header().Get("ETag")
+	assert.Contains(t, etag, `W/"`)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/articles/a", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/articles/a", nil)
+	req.Header.Set("If-Modified-Since", updatedAt.Add(time.Minute).Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	req.Header.Set("If-Modified-Since", updatedAt.Add(-time.Minute).Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,199 @@
+// Package feed writes Atom 1.0 and RSS 2.0 documents from a format neutral
+// description of a feed.
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const (
+	AtomContentType = "application/atom+xml; charset=utf-8"
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+)
+
+type Feed struct {
+	// ID identifies the feed permanently, Self is the URL it is served at
+	// and Link the HTML page it mirrors.
+	ID          string
+	Title       string
+	Description string
+	Link        string
+	Self        string
+	Updated     time.Time
+	Items       []Item
+}
+
+type Item struct {
+	ID         string
+	Title      string
+	Link       string
+	Author     string
+	Summary    string
+	Content    string // HTML
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// WriteAtom writes f as an Atom feed whose entries carry their content as
+// escaped HTML.
+func WriteAtom(w io.Writer, f Feed) error {
+	doc := atomFeed{
+		ID:       f.ID,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, item := range f.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Link:      atomLink{Href: item.Link, Rel: "alternate", Type: "text/html"},
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Author:    atomAuthor{Name: item.Author},
+			Summary:   item.Summary,
+			Content:   atomContent{Type: "html", Body: item.Content},
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return write(w, doc)
+}
+
+type rssDocument struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	GUID        rssGUID    `xml:"guid"`
+	Creator     string     `xml:"dc:creator,omitempty"`
+	Categories  []string   `xml:"category"`
+	PubDate     string     `xml:"pubDate"`
+	Description string     `xml:"description"`
+	Content     rssContent `xml:"content:encoded"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssContent struct {
+	Body string `xml:",cdata"`
+}
+
+// WriteRSS writes f as an RSS 2.0 channel. RSS has no updated date per item,
+// so items are dated by their publication, and the HTML goes in
+// content:encoded with the summary as the description.
+func WriteRSS(w io.Writer, f Feed) error {
+	doc := rssDocument{
+		Version:   "2.0",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Description,
+			Self:          atomLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, item := range f.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.ID},
+			Creator:     item.Author,
+			Categories:  item.Categories,
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Description: item.Summary,
+			Content:     rssContent{Body: item.Content},
+		})
+	}
+
+	return write(w, doc)
+}
+
+func write(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
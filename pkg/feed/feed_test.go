@@ -0,0 +1,88 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var published = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+var sample = Feed{
+	ID:          "https://conduit.example/feeds/articles.atom",
+	Title:       "Conduit",
+	Description: "Latest articles",
+	Link:        "https://conduit.example/",
+	Self:        "https://conduit.example/feeds/articles.atom",
+	Updated:     published.Add(time.Hour),
+	Items: []Item{{
+		ID:         "urn:uuid:0b4f9f4e-4d2c-4d43-9d5e-2b0f6a1c7c11",
+		Title:      "Fish & chips",
+		Link:       "https://conduit.example/article/fish-chips",
+		Author:     "jake",
+		Summary:    "A recipe",
+		Content:    "<p>Batter <em>first</em></p>",
+		Categories: []string{"food", "uk"},
+		Published:  published,
+		Updated:    published.Add(time.Hour),
+	}},
+}
+
+func TestWriteAtom(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteAtom(&buf, sample))
+
+	var doc atomFeed
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, "http://www.w3.org/2005/Atom", doc.XMLName.Space)
+	assert.Equal(t, "2024-03-01T11:00:00Z", doc.Updated)
+	assert.Equal(t, atomLink{Href: sample.Self, Rel: "self", Type: "application/atom+xml"}, doc.Links[0])
+	require.Len(t, doc.Entries, 1)
+
+	entry := doc.Entries[0]
+	assert.Equal(t, "Fish & chips", entry.Title)
+	assert.Equal(t, "2024-03-01T10:00:00Z", entry.Published)
+	assert.Equal(t, "jake", entry.Author.Name)
+	assert.Equal(t, []atomCategory{{Term: "food"}, {Term: "uk"}}, entry.Categories)
+	assert.Equal(t, atomContent{Type: "html", Body: "<p>Batter <em>first</em></p>"}, entry.Content)
+	assert.Contains(t, buf.String(), "&lt;p&gt;Batter")
+}
+
+func TestWriteRSS(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteRSS(&buf, sample))
+
+	out := buf.String()
+	assert.Contains(t, out, `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"`)
+	assert.Contains(t, out, `<atom:link href="https://conduit.example/feeds/articles.atom" rel="self" type="application/rss+xml"></atom:link>`)
+	assert.Contains(t, out, `<guid isPermaLink="false">urn:uuid:0b4f9f4e-4d2c-4d43-9d5e-2b0f6a1c7c11</guid>`)
+	assert.Contains(t, out, `<dc:creator>jake</dc:creator>`)
+	assert.Contains(t, out, `<pubDate>Fri, 01 Mar 2024 10:00:00 +0000</pubDate>`)
+	assert.Contains(t, out, `<content:encoded><![CDATA[<p>Batter <em>first</em></p>]]></content:encoded>`)
+
+	var doc struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title      string   `xml:"title"`
+				Categories []string `xml:"category"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "Conduit", doc.Channel.Title)
+	require.Len(t, doc.Channel.Items, 1)
+	assert.Equal(t, "Fish & chips", doc.Channel.Items[0].Title)
+	assert.Equal(t, []string{"food", "uk"}, doc.Channel.Items[0].Categories)
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteAtom(&buf, Feed{ID: "id", Title: "Empty"}))
+	assert.NotContains(t, buf.String(), "<entry>")
+}
@@ -0,0 +1,125 @@
+// Package health serves liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check returns nil when the dependency it probes is usable.
+type Check func(ctx context.Context) error
+
+type Result struct {
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Checker runs named checks concurrently, each bounded by timeout.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]Check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: map[string]Check{}}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	names := append([]string(nil), c.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := Result{Status: StatusOK, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Ready answers 200 when every check passes and 503 otherwise, with the
+// result of each check in the body.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, report)
+}
+
+// Live answers 200 as long as the process can serve HTTP. It checks no
+// dependency, so an outage of the database does not get the process
+// restarted.
+func Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	c := NewChecker(50 * time.Millisecond)
+	c.Add("database", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	c.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report Report
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+}
+
+func TestChecker_Unavailable(t *testing.T) {
+	c := NewChecker(20 * time.Millisecond)
+	c.Add("database", func(ctx context.Context) error { return nil })
+	c.Add("migrations", func(ctx context.Context) error { return errors.New("missing tables: comments") })
+	c.Add("cache", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	rec := httptest.NewRecorder()
+	c.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var report Report
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, "missing tables: comments", report.Checks["migrations"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["cache"].Error)
+}
+
+func TestLive(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Live(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor EncodeCursor did not produce.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor builds an opaque keyset cursor from the position of the last
+// item of a page.
+func EncodeCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found || id == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return t, id, nil
+}
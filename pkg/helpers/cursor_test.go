@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 10, 30, 0, 123456000, time.UTC)
+
+	cursor := EncodeCursor(createdAt, "some-id")
+	gotTime, gotID, err := DecodeCursor(cursor)
+
+	assert.Nil(t, err)
+	assert.True(t, createdAt.Equal(gotTime))
+	assert.Equal(t, "some-id", gotID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	_, _, err := DecodeCursor("not base64!")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, _, err = DecodeCursor(EncodeCursor(time.Now(), "")[:4])
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, _, err = DecodeCursor(EncodeCursor(time.Now(), ""))
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
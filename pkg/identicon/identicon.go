@@ -0,0 +1,88 @@
+// Package identicon draws the default avatar of users without an image: a
+// 5x5 horizontally symmetric pattern in one color, both derived from a hash
+// of a seed, so the same seed always gets the same picture.
+package identicon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math"
+)
+
+const (
+	cellsPerSide = 5
+	// Size is the width and height of the SVG in pixels; it scales freely.
+	Size       = 128
+	background = "#f0f0f0"
+)
+
+// SVG returns the identicon of seed.
+func SVG(seed string) []byte {
+	sum := sha256.Sum256([]byte(seed))
+	grid := cells(sum)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="-0.5 -0.5 %d %d" shape-rendering="crispEdges">`,
+		Size, Size, cellsPerSide+1, cellsPerSide+1)
+	fmt.Fprintf(&buf, `<rect x="-0.5" y="-0.5" width="%d" height="%d" fill="%s"/>`, cellsPerSide+1, cellsPerSide+1, background)
+	fmt.Fprintf(&buf, `<g fill="%s">`, color(sum))
+	for y := 0; y < cellsPerSide; y++ {
+		for x := 0; x < cellsPerSide; x++ {
+			if grid[y][x] {
+				fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="1" height="1"/>`, x, y)
+			}
+		}
+	}
+	buf.WriteString(`</g></svg>`)
+	return buf.Bytes()
+}
+
+// cells fills the left half and the middle column from the hash and mirrors
+// them. An empty pattern gets its center cell, so no identicon is blank.
+func cells(sum [32]byte) [cellsPerSide][cellsPerSide]bool {
+	var grid [cellsPerSide][cellsPerSide]bool
+	filled := false
+	for y := 0; y < cellsPerSide; y++ {
+		for x := 0; x < (cellsPerSide+1)/2; x++ {
+			on := sum[y*3+x]&1 == 1
+			grid[y][x], grid[y][cellsPerSide-1-x] = on, on
+			filled = filled || on
+		}
+	}
+	if !filled {
+		grid[cellsPerSide/2][cellsPerSide/2] = true
+	}
+	return grid
+}
+
+// color picks a hue from the hash at a fixed saturation and lightness, which
+// keeps every identicon readable on the light background.
+func color(sum [32]byte) string {
+	hue := float64(uint16(sum[30])<<8|uint16(sum[31])) / 65536 * 360
+	r, g, b := hslToRGB(hue, 0.55, 0.5)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return uint8((r+m)*255 + 0.5), uint8((g+m)*255 + 0.5), uint8((b+m)*255 + 0.5)
+}
@@ -0,0 +1,55 @@
+package identicon
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSVG_Deterministic(t *testing.T) {
+	assert.Equal(t, SVG("ana.silva"), SVG("ana.silva"))
+	assert.NotEqual(t, SVG("ana.silva"), SVG("bruno.costa"))
+}
+
+func TestSVG_WellFormed(t *testing.T) {
+	var doc struct {
+		XMLName xml.Name
+		Width   int `xml:"width,attr"`
+	}
+	require.NoError(t, xml.Unmarshal(SVG("ana.silva"), &doc))
+	assert.Equal(t, "svg", doc.XMLName.Local)
+	assert.Equal(t, Size, doc.Width)
+}
+
+func TestSVG_Color(t *testing.T) {
+	fill := regexp.MustCompile(`<g fill="(#[0-9a-f]{6})">`)
+	require.Regexp(t, fill, string(SVG("ana.silva")))
+	assert.NotEqual(t, fill.FindStringSubmatch(string(SVG("ana.silva")))[1], background)
+}
+
+func TestCells_Symmetric(t *testing.T) {
+	for _, seed := range []string{"a", "b", "c", "deleted-user"} {
+		grid := cells(sha256.Sum256([]byte(seed)))
+		for y := range grid {
+			for x := range grid[y] {
+				assert.Equal(t, grid[y][x], grid[y][cellsPerSide-1-x], seed)
+			}
+		}
+	}
+}
+
+func TestCells_NeverBlank(t *testing.T) {
+	grid := cells([32]byte{})
+	assert.True(t, grid[cellsPerSide/2][cellsPerSide/2])
+}
+
+func TestHSLToRGB(t *testing.T) {
+	r, g, b := hslToRGB(0, 1, 0.5)
+	assert.Equal(t, [3]uint8{255, 0, 0}, [3]uint8{r, g, b})
+	r, g, b = hslToRGB(240, 1, 0.5)
+	assert.Equal(t, [3]uint8{0, 0, 255}, [3]uint8{r, g, b})
+}
@@ -0,0 +1,187 @@
+// Package imaging validates uploaded images and renders resized JPEG
+// variants of them. Only the standard library decoders are used, so JPEG,
+// PNG and GIF are accepted. The EXIF orientation of JPEGs is applied to the
+// pixels, and variants are re-encoded from them, which drops EXIF and every
+// other kind of embedded metadata.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"net/http"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+var (
+	ErrTooLarge        = errors.New("image is too large")
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// MaxPixels bounds the decoded size, so a small file declaring huge
+// dimensions cannot exhaust memory.
+const MaxPixels = 40_000_000
+
+// Quality of the JPEG variants.
+const Quality = 85
+
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Mode is how an image is fitted into a variant's box.
+type Mode int
+
+const (
+	// Fit scales the image down to fit inside the box, keeping its aspect
+	// ratio. A zero Height only bounds the width.
+	Fit Mode = iota
+	// Cover scales and center-crops the image to fill the box exactly.
+	Cover
+)
+
+// Variant is one rendition of an upload.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Mode   Mode
+}
+
+// Image is a decoded upload.
+type Image struct {
+	image.Image
+	// ContentType is the sniffed type of the original file.
+	ContentType string
+}
+
+// Decode reads at most maxBytes from r and decodes them, after checking by
+// sniffing, not by trusting the client, that they are an accepted format.
+func Decode(r io.Reader, maxBytes int64) (*Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if contentType == "image/jpeg" {
+		img = Orient(img, Orientation(data))
+	}
+	return &Image{Image: img, ContentType: contentType}, nil
+}
+
+// Render resizes img for v and encodes it as a JPEG. Images are never
+// scaled up, and transparent areas are flattened on white.
+func Render(img image.Image, v Variant) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, Resize(img, v), &jpeg.Options{Quality: Quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Resize returns img scaled into the box of v.
+func Resize(img image.Image, v Variant) *image.RGBA {
+	src := img.Bounds()
+	if v.Mode == Cover && v.Height > 0 {
+		src = cropToRatio(src, v.Width, v.Height)
+	}
+
+	w, h := target(src.Dx(), src.Dy(), v)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	scaled := image.NewRGBA(dst.Bounds())
+	boxResize(scaled, img, src)
+	draw.Draw(dst, dst.Bounds(), scaled, image.Point{}, draw.Over)
+	return dst
+}
+
+// target picks the output size of a w×h source.
+func target(w, h int, v Variant) (int, int) {
+	if v.Mode == Cover && v.Height > 0 {
+		if w <= v.Width {
+			return w, h
+		}
+		return v.Width, v.Height
+	}
+
+	scale := 1.0
+	if v.Width > 0 && w > v.Width {
+		scale = float64(v.Width) / float64(w)
+	}
+	if v.Height > 0 && float64(h)*scale > float64(v.Height) {
+		scale = float64(v.Height) / float64(h)
+	}
+	return max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+}
+
+// cropToRatio returns the largest centered rectangle of r with the
+// proportions of w×h.
+func cropToRatio(r image.Rectangle, w, h int) image.Rectangle {
+	dx, dy := r.Dx(), r.Dy()
+	if dx*h > dy*w {
+		cw := dy * w / h
+		x := r.Min.X + (dx-cw)/2
+		return image.Rect(x, r.Min.Y, x+cw, r.Max.Y)
+	}
+	ch := dx * h / w
+	y := r.Min.Y + (dy-ch)/2
+	return image.Rect(r.Min.X, y, r.Max.X, y+ch)
+}
+
+// boxResize averages the pixels of src that fall under each pixel of dst,
+// which is enough quality for downscaling thumbnails.
+func boxResize(dst *image.RGBA, img image.Image, src image.Rectangle) {
+	b := dst.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		y0 := src.Min.Y + y*src.Dy()/b.Dy()
+		y1 := max(y0+1, src.Min.Y+(y+1)*src.Dy()/b.Dy())
+
+		for x := 0; x < b.Dx(); x++ {
+			x0 := src.Min.X + x*src.Dx()/b.Dx()
+			x1 := max(x0+1, src.Min.X+(x+1)*src.Dx()/b.Dx())
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n),
+			})
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// withEXIF inserts an APP1 Exif segment right after the SOI marker.
+func withEXIF(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	data := buf.Bytes()
+
+	payload := append([]byte("Exif\x00\x00"), []byte("MM\x00\x2a\x00\x00\x00\x08GPS-SECRET")...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestDecode(t *testing.T) {
+	img, err := Decode(bytes.NewReader(encodePNG(t, solid(10, 5, color.Black))), 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.Equal(t, image.Rect(0, 0, 10, 5), img.Bounds())
+}
+
+func TestDecode_Rejects(t *testing.T) {
+	_, err := Decode(bytes.NewReader([]byte("<svg xmlns='http://www.w3.org/2000/svg'/>")), 1<<20)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, err = Decode(bytes.NewReader([]byte("\x89PNG\r\n\x1a\nnot really")), 1<<20)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	data := encodePNG(t, solid(100, 100, color.Black))
+	_, err = Decode(bytes.NewReader(data), int64(len(data)-1))
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestRender_Fit(t *testing.T) {
+	img := solid(1000, 500, color.RGBA{R: 200, A: 255})
+
+	out, err := Render(img, Variant{Width: 400, Mode: Fit})
+	require.NoError(t, err)
+	decoded, err := jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 400, 200), decoded.Bounds())
+
+	out, err = Render(img, Variant{Width: 4000, Mode: Fit})
+	require.NoError(t, err)
+	decoded, err = jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 1000, 500), decoded.Bounds(), "never scaled up")
+}
+
+func TestRender_Cover(t *testing.T) {
+	out, err := Render(solid(300, 100, color.Black), Variant{Width: 64, Height: 64, Mode: Cover})
+	require.NoError(t, err)
+	decoded, err := jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 64, 64), decoded.Bounds())
+
+	out, err = Render(solid(30, 10, color.Black), Variant{Width: 64, Height: 64, Mode: Cover})
+	require.NoError(t, err)
+	decoded, err = jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 10, 10), decoded.Bounds(), "cropped but not scaled up")
+}
+
+func TestRender_StripsEXIF(t *testing.T) {
+	data := withEXIF(t, solid(50, 50, color.White))
+	require.Contains(t, string(data), "GPS-SECRET")
+
+	img, err := Decode(bytes.NewReader(data), 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", img.ContentType)
+
+	out, err := Render(img, Variant{Width: 50, Mode: Fit})
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "Exif")
+	assert.NotContains(t, string(out), "GPS-SECRET")
+}
+
+func TestRender_FlattensTransparency(t *testing.T) {
+	out, err := Render(solid(4, 4, color.Transparent), Variant{Width: 4, Mode: Fit})
+	require.NoError(t, err)
+	decoded, err := jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+
+	r, g, b, _ := decoded.At(1, 1).RGBA()
+	assert.Greater(t, r, uint32(0xf000))
+	assert.Greater(t, g, uint32(0xf000))
+	assert.Greater(t, b, uint32(0xf000))
+}
+
+// testdata/orientation-6.jpg is stored 40×20, red on the left and blue on
+// the right, with an EXIF Orientation of 6: it is meant to be shown turned
+// 90° clockwise, 20×40 with red on top.
+func TestDecode_AppliesEXIFOrientation(t *testing.T) {
+	data, err := os.ReadFile("testdata/orientation-6.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, 6, Orientation(data))
+
+	img, err := Decode(bytes.NewReader(data), 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+
+	r, _, b, _ := img.At(10, 5).RGBA()
+	assert.Greater(t, r, b, "red on top")
+	r, _, b, _ = img.At(10, 35).RGBA()
+	assert.Greater(t, b, r, "blue at the bottom")
+}
+
+func TestOrientation_Missing(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, solid(4, 4, color.White), nil))
+	assert.Equal(t, 1, Orientation(buf.Bytes()))
+	assert.Equal(t, 1, Orientation(withEXIF(t, solid(4, 4, color.White))), "EXIF without orientation")
+	assert.Equal(t, 1, Orientation([]byte("not a jpeg")))
+}
+
+func TestOrient(t *testing.T) {
+	// 3×2, with the only red pixel at the top-left corner
+	img := solid(3, 2, color.White)
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	for orientation, want := range map[int]struct {
+		bounds image.Rectangle
+		red    image.Point
+	}{
+		1: {image.Rect(0, 0, 3, 2), image.Pt(0, 0)},
+		2: {image.Rect(0, 0, 3, 2), image.Pt(2, 0)},
+		3: {image.Rect(0, 0, 3, 2), image.Pt(2, 1)},
+		4: {image.Rect(0, 0, 3, 2), image.Pt(0, 1)},
+		5: {image.Rect(0, 0, 2, 3), image.Pt(0, 0)},
+		6: {image.Rect(0, 0, 2, 3), image.Pt(1, 0)},
+		7: {image.Rect(0, 0, 2, 3), image.Pt(1, 2)},
+		8: {image.Rect(0, 0, 2, 3), image.Pt(0, 2)},
+	} {
+		out := Orient(img, orientation)
+		assert.Equal(t, want.bounds, out.Bounds(), orientation)
+		_, g, _, _ := out.At(want.red.X, want.red.Y).RGBA()
+		assert.Zero(t, g, orientation)
+	}
+}
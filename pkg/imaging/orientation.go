@@ -0,0 +1,131 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// orientationTag is the EXIF tag of the Orientation field in IFD0.
+const orientationTag = 0x0112
+
+// Orientation reads the EXIF Orientation of a JPEG from its APP1 segment:
+// 1 is upright, 2 to 8 are the mirrorings and rotations of the TIFF
+// specification. It returns 1 when the file has none or it cannot be read.
+func Orientation(jpeg []byte) int {
+	if len(jpeg) < 2 || jpeg[0] != 0xFF || jpeg[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(jpeg); {
+		if jpeg[i] != 0xFF {
+			return 1
+		}
+		marker := jpeg[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// the image data starts, and no metadata comes after it
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(jpeg[i+2:]))
+		if length < 2 || i+2+length > len(jpeg) {
+			return 1
+		}
+
+		segment := jpeg[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation looks for the Orientation entry in IFD0 of an EXIF TIFF
+// structure.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 0x2A {
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// a SHORT, stored at the start of the value field
+		if order.Uint16(tiff[entry:]) == orientationTag && order.Uint16(tiff[entry+2:]) == 3 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// Orient returns img turned upright according to an EXIF orientation. An
+// upright or unknown orientation returns img as is.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// source returns the pixel of img shown at x, y once upright
+	var source func(x, y int) (int, int)
+	dw, dh := w, h
+	switch orientation {
+	case 2: // mirrored horizontally
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3: // rotated 180°
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4: // mirrored vertically
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5: // mirrored along the top-left diagonal
+		dw, dh = h, w
+		source = func(x, y int) (int, int) { return y, x }
+	case 6: // needs a 90° clockwise turn
+		dw, dh = h, w
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7: // mirrored along the top-right diagonal
+		dw, dh = h, w
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8: // needs a 90° counterclockwise turn
+		dw, dh = h, w
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute names whose values never reach the output.
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"authorization": true,
+	"jwt":           true,
+	"secret":        true,
+	"cookie":        true,
+}
+
+// New builds the application logger. format is "json" or "text" and level
+// one of debug, info, warn or error. Every record logged with a request
+// context carries its request ID, user ID, route pattern and, when the
+// request is traced, its trace and span IDs.
+func New(w io.Writer, format, level string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       parseLevel(level),
+		ReplaceAttr: redact,
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "json") {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	return slog.New(contextHandler{handler})
+}
+
+func parseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info := infoFrom(ctx); info != nil {
+		record.AddAttrs(slog.String("request_id", info.requestID))
+		if userID := info.UserID(); userID != "" {
+			record.AddAttrs(slog.String("user_id", userID))
+		}
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			record.AddAttrs(slog.String("route", pattern))
+		}
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestNew_RedactsSensitiveKeys(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, "json", "info")
+
+	log.Info("login", slog.String("password", "hunter2"), slog.String("Token", "abc"), slog.String("email", "a@a.com"))
+
+	out := buf.String()
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "abc")
+	assert.Contains(t, out, "a@a.com")
+}
+
+func TestMiddleware_RequestContext(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, "json", "debug")
+
+	r := chi.NewRouter()
+	r.Use(Middleware(log))
+	r.Get("/api/articles/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		log.DebugContext(r.Context(), "loading article")
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/articles/hello?token=secret", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, "abc-123", rec.Header().Get(RequestIDHeader))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.NotContains(t, buf.String(), "secret")
+
+	for _, line := range lines {
+		var record map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, "abc-123", record["request_id"])
+		assert.Equal(t, "/api/articles/{slug}", record["route"])
+	}
+}
+
+func TestMiddleware_GeneratesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	h := Middleware(New(&buf, "text", "info"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, RequestID(r.Context()))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.NotEqual(t, "bad id\nwith newline", rec.Header().Get(RequestIDHeader))
+	assert.Len(t, rec.Header().Get(RequestIDHeader), 36)
+}
+
+func TestNew_AddsTraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, "json", "info")
+
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "work")
+	log.InfoContext(ctx, "traced")
+	span.End()
+
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, span.SpanContext().TraceID().String(), record["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), record["span_id"])
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[\w\-.:]{1,128}$`)
+
+type ctxKey struct{}
+
+// requestInfo is shared by pointer so the user ID found by the auth
+// middleware of a route group is also seen by the outer access log.
+type requestInfo struct {
+	requestID string
+
+	mu     sync.Mutex
+	userID string
+}
+
+func (i *requestInfo) UserID() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.userID
+}
+
+func infoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(ctxKey{}).(*requestInfo)
+	return info
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "".
+func RequestID(ctx context.Context) string {
+	if info := infoFrom(ctx); info != nil {
+		return info.requestID
+	}
+	return ""
+}
+
+// WithRequestID attaches a request ID to ctx, for work that does not start
+// from an HTTP request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &requestInfo{requestID: id})
+}
+
+// Middleware reuses a well formed X-Request-ID or generates one, echoes it
+// back, and writes one access log line per request when it completes.
+func Middleware(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID.MatchString(id) {
+				id = uuid.NewString()
+			}
+
+			ctx := WithRequestID(r.Context(), id)
+			w.Header().Set(RequestIDHeader, id)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			log.LogAttrs(ctx, level, "request completed",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+		})
+	}
+}
+
+// CaptureUser records the authenticated user for the log lines of the
+// request. It belongs right after jwtauth.Authenticator.
+func CaptureUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info := infoFrom(r.Context()); info != nil {
+			if _, claims, err := jwtauth.FromContext(r.Context()); err == nil {
+				if sub, ok := claims["sub"].(string); ok {
+					info.mu.Lock()
+					info.userID = sub
+					info.mu.Unlock()
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
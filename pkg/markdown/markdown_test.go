@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	html, err := Render("# Title\n\nSome **bold** and ~~gone~~ text.\n\n- [x] done\n")
+
+	assert.Nil(t, err)
+	assert.Contains(t, html, `<h1 id="title">Title</h1>`)
+	assert.Contains(t, html, "<strong>bold</strong>")
+	assert.Contains(t, html, "<del>gone</del>")
+	assert.Contains(t, html, `type="checkbox"`)
+}
+
+func TestRender_Sanitizes(t *testing.T) {
+	html, err := Render("<script>alert(1)</script>\n\n[link](javascript:alert(1))\n\n<img src=x onerror=alert(1)>")
+
+	assert.Nil(t, err)
+	assert.NotContains(t, html, "<script")
+	assert.NotContains(t, html, "javascript:")
+	assert.NotContains(t, html, "onerror")
+}
+
+func TestRender_KeepsCodeLanguage(t *testing.T) {
+	html, err := Render("```go\nfmt.Println(1)\n```\n")
+
+	assert.Nil(t, err)
+	assert.Contains(t, html, `<code class="language-go">`)
+}
+
+func TestPlainText(t *testing.T) {
+	plain := PlainText("# Title\n\nSome **bold**\nand `code` text.\n\n```go\nfmt.Println(1)\n```\n\n- one\n- two\n")
+
+	assert.Equal(t, "Title\nSome bold and code text.\none\ntwo", plain)
+}
+
+func TestOutline(t *testing.T) {
+	source := "# Intro\n\ntext\n\n## Getting *started*\n\n## Intro\n"
+
+	assert.Equal(t, []Heading{
+		{Level: 1, Text: "Intro", Anchor: "intro"},
+		{Level: 2, Text: "Getting started", Anchor: "getting-started"},
+		{Level: 2, Text: "Intro", Anchor: "intro-1"},
+	}, Outline(source))
+
+	html, _ := Render(source)
+	assert.Contains(t, html, `<h2 id="intro-1">`)
+}
@@ -0,0 +1,99 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+type Heading struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+}
+
+func parse(source []byte) ast.Node {
+	return md.Parser().Parse(text.NewReader(source))
+}
+
+// PlainText strips the Markdown syntax and returns the readable text, one
+// block per line. Code blocks and raw HTML are left out.
+func PlainText(source string) string {
+	src := []byte(source)
+	var sb strings.Builder
+
+	ast.Walk(parse(src), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch node := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering {
+				sb.Write(node.Segment.Value(src))
+				if node.SoftLineBreak() || node.HardLineBreak() {
+					sb.WriteByte(' ')
+				}
+			}
+		case *ast.String:
+			if entering {
+				sb.Write(node.Value)
+			}
+		default:
+			if !entering && n.Type() == ast.TypeBlock && !strings.HasSuffix(sb.String(), "\n") {
+				sb.WriteByte('\n')
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return strings.TrimSpace(sb.String())
+}
+
+// Outline lists the headings of the document with the same anchors that
+// Render puts on them.
+func Outline(source string) []Heading {
+	src := []byte(source)
+	headings := []Heading{}
+
+	ast.Walk(parse(src), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		anchor := ""
+		if id, found := heading.AttributeString("id"); found {
+			if b, ok := id.([]byte); ok {
+				anchor = string(b)
+			}
+		}
+
+		headings = append(headings, Heading{
+			Level:  heading.Level,
+			Text:   inlineText(heading, src),
+			Anchor: anchor,
+		})
+		return ast.WalkSkipChildren, nil
+	})
+
+	return headings
+}
+
+func inlineText(n ast.Node, src []byte) string {
+	var sb strings.Builder
+
+	ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := child.(type) {
+		case *ast.Text:
+			sb.Write(node.Segment.Value(src))
+		case *ast.String:
+			sb.Write(node.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return strings.TrimSpace(sb.String())
+}
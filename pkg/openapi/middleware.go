@@ -0,0 +1,169 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Validation modes for Middleware.
+const (
+	ValidateOff       = "off"
+	ValidateRequests  = "requests"
+	ValidateResponses = "all"
+)
+
+const maxValidatedBody = 1 << 20
+
+// Middleware rejects requests that do not match the document with 400 and
+// the list of violations. In "all" mode it also checks every response and
+// logs the ones that drift from the document; responses are never altered.
+// Routes missing from the document are passed through untouched.
+func Middleware(doc *Document, mode string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if mode != ValidateRequests && mode != ValidateResponses {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			input, ok := doc.requestInput(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				writeErrors(w, violations("body", err))
+				return
+			}
+
+			if mode != ValidateResponses || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			capture := &limitedBuffer{max: maxValidatedBody}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(capture)
+
+			next.ServeHTTP(ww, r)
+
+			if errs := validateResponse(r.Context(), input, ww, capture); len(errs) > 0 {
+				slog.WarnContext(r.Context(), "response does not match the openapi document",
+					slog.String("method", r.Method), slog.String("path", r.URL.Path),
+					slog.Int("status", ww.Status()), slog.Any("violations", errs))
+			}
+		})
+	}
+}
+
+// requestInput matches r against the document. Validation runs on a copy:
+// the handler still reads the original body, and a missing Content-Type is
+// validated as JSON since handlers decode JSON whatever the header says.
+func (d *Document) requestInput(r *http.Request) (*openapi3filter.RequestValidationInput, bool) {
+	req := r.Clone(r.Context())
+	req.URL.Path = NormalizePath(req.URL.Path)
+	req.URL.RawPath = ""
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	route, params, err := d.router.FindRoute(req)
+	if err != nil {
+		return nil, false
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:          true,
+		SkipSettingDefaults: true,
+		// authentication is enforced by the jwtauth middleware
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	if route.Operation.RequestBody != nil {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		if err != nil || len(body) > maxValidatedBody {
+			options.ExcludeRequestBody = true
+		}
+
+		// uploads and other documented non-JSON bodies are left to their
+		// handlers
+		contentType := req.Header.Get("Content-Type")
+		if !isJSON(contentType) && route.Operation.RequestBody.Value.Content.Get(contentType) != nil {
+			options.ExcludeRequestBody = true
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: params,
+		Route:      route,
+		Options:    options,
+	}, true
+}
+
+func validateResponse(ctx context.Context, input *openapi3filter.RequestValidationInput, ww middleware.WrapResponseWriter, body *limitedBuffer) []string {
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// bodies that were cut short, empty or not JSON (feeds, images, the
+	// docs page) are only checked for their status
+	contentType := ww.Header().Get("Content-Type")
+	skipBody := body.Len() == 0 || body.truncated || !isJSON(contentType)
+
+	err := openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 status,
+		Header:                 ww.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(body.Bytes())),
+		Options: &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+			ExcludeResponseBody:   skipBody,
+		},
+	})
+	if err != nil {
+		return violations("body", err)
+	}
+	return nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// writeErrors answers with the RealWorld error envelope.
+func writeErrors(w http.ResponseWriter, errs []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]map[string][]string{"errors": {"body": errs}})
+}
+
+// limitedBuffer keeps the first max bytes written to it and remembers
+// whether anything was dropped.
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); len(p) > room {
+		b.truncated = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,207 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDoc = `{
+  "openapi": "3.0.3",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/api/articles": {
+      "get": {
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1}},
+          {"name": "sort", "in": "query", "schema": {"type": "string", "enum": ["asc", "desc"]}}
+        ],
+        "responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/List"}}}}}
+      },
+      "post": {
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/New"}}}},
+        "responses": {"201": {"description": "Created"}}
+      }
+    },
+    "/api/articles/feed": {"get": {"responses": {"200": {"description": "OK"}}}},
+    "/api/articles/{slug}": {
+      "get": {
+        "parameters": [{"name": "slug", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      },
+      "put": {
+        "parameters": [{"name": "slug", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {"required": true, "content": {"multipart/form-data": {"schema": {"type": "object", "required": ["image"]}}}},
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "New": {
+        "type": "object",
+        "required": ["article"],
+        "properties": {
+          "article": {
+            "type": "object",
+            "required": ["title"],
+            "properties": {
+              "title": {"type": "string", "minLength": 1},
+              "tag_list": {"type": "array", "items": {"type": "string"}, "nullable": true}
+            }
+          }
+        }
+      },
+      "List": {
+        "type": "object",
+        "required": ["articles", "articlesCount"],
+        "properties": {
+          "articles": {"type": "array", "items": {"$ref": "#/components/schemas/Article"}},
+          "articlesCount": {"type": "integer"}
+        }
+      },
+      "Article": {"type": "object", "properties": {"slug": {"type": "string"}, "favorited": {"type": "boolean"}}}
+    }
+  }
+}`
+
+func load(t *testing.T) *Document {
+	doc, err := Load([]byte(testDoc))
+	require.NoError(t, err)
+	return doc
+}
+
+func TestLoad_UnresolvedRef(t *testing.T) {
+	_, err := Load([]byte(strings.Replace(testDoc, `"$ref": "#/components/schemas/Article"`, `"$ref": "#/components/schemas/Missing"`, 1)))
+	assert.ErrorContains(t, err, "#/components/schemas/Missing")
+}
+
+func TestOperations(t *testing.T) {
+	assert.Equal(t, []string{
+		"GET /api/articles",
+		"GET /api/articles/feed",
+		"GET /api/articles/{slug}",
+		"POST /api/articles",
+		"PUT /api/articles/{slug}",
+	}, load(t).Operations())
+}
+
+func TestValidateSchema(t *testing.T) {
+	doc := load(t)
+
+	assert.Empty(t, doc.ValidateSchema("New", []byte(`{"article":{"title":"Hi","tag_list":null}}`)))
+	assert.ElementsMatch(t, []string{
+		"body.article: property \"title\" is missing",
+		"body.article.tag_list[1]: value must be a string",
+	}, doc.ValidateSchema("New", []byte(`{"article":{"tag_list":["go",1]}}`)))
+	assert.Equal(t, []string{"body.article.title: minimum string length is 1"},
+		doc.ValidateSchema("New", []byte(`{"article":{"title":""}}`)))
+	assert.Len(t, doc.ValidateSchema("New", []byte(`{"article":`)), 1)
+	assert.Equal(t, []string{"unknown schema Missing"}, doc.ValidateSchema("Missing", []byte(`{}`)))
+}
+
+func TestMiddleware_Parameters(t *testing.T) {
+	doc := load(t)
+	h := Middleware(doc, ValidateRequests)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, target := range []string{"/api/articles?limit=10&sort=desc", "/api/articles/?limit=1", "/api/articles/feed", "/api/articles/hello-world"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, target)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles?limit=0&sort=up", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"errors":{"body":[
+		"query parameter limit: number must be at least 1",
+		"query parameter sort: value is not one of the allowed values [\"asc\",\"desc\"]"
+	]}}`, rec.Body.String())
+}
+
+func TestMiddleware_Requests(t *testing.T) {
+	doc := load(t)
+	var got string
+	h := Middleware(doc, ValidateRequests)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		buf.ReadFrom(r.Body)
+		got = buf.String()
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/articles", strings.NewReader(`{"article":{"title":"Hi"}}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"article":{"title":"Hi"}}`, got, "the handler still reads the body")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/articles", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"errors":{"body":["body: property \"article\" is missing"]}}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/undocumented", nil))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestMiddleware_MultipartBody(t *testing.T) {
+	doc := load(t)
+	h := Middleware(doc, ValidateRequests)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	body := "--b\r\nContent-Disposition: form-data; name=\"image\"; filename=\"a.png\"\r\n\r\npng\r\n--b--\r\n"
+	req := httptest.NewRequest(http.MethodPut, "/api/articles/hi", strings.NewReader(body))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=b")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "multipart bodies are not validated as JSON")
+
+	req = httptest.NewRequest(http.MethodPut, "/api/articles/hi", strings.NewReader(`{"image":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `unexpected value \"application/json\"`)
+}
+
+func TestMiddleware_Responses(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	doc := load(t)
+	body := `{"articles":[{"slug":"a","favorited":"no"}],"articlesCount":1}`
+	h := Middleware(doc, ValidateResponses)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles", nil))
+	assert.Equal(t, body, rec.Body.String(), "responses are not altered")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.Equal(t, []interface{}{"body.articles[0].favorited: value must be a boolean"}, record["violations"])
+}
+
+func TestMiddleware_Off(t *testing.T) {
+	doc := load(t)
+	h := Middleware(doc, ValidateOff)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/articles", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
@@ -0,0 +1,84 @@
+package openapi
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// violations flattens a kin-openapi validation error into one message per
+// problem, each prefixed with the location of the offending value, such as
+// "body.article.tagList[1]: value must be a string".
+func violations(at string, err error) []string {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var errs []string
+		for _, inner := range e {
+			errs = append(errs, violations(at, inner)...)
+		}
+		return errs
+
+	case *openapi3.SchemaError:
+		reason := e.Reason
+		if reason == "" {
+			reason = "does not match " + e.SchemaField
+		}
+		path := e.JSONPointer()
+		// a missing property is reported at the object that lacks it
+		if e.SchemaField == "required" && len(path) > 0 {
+			path = path[:len(path)-1]
+		}
+		return []string{at + pointer(path) + ": " + reason}
+
+	case *openapi3filter.RequestError:
+		if e.Parameter != nil {
+			at = e.Parameter.In + " parameter " + e.Parameter.Name
+		}
+		if isSchemaError(e.Err) {
+			return violations(at, e.Err)
+		}
+		return []string{at + ": " + describe(e.Reason, e.Err)}
+
+	case *openapi3filter.ResponseError:
+		if isSchemaError(e.Err) {
+			return violations(at, e.Err)
+		}
+		return []string{at + ": " + describe(e.Reason, e.Err)}
+	}
+
+	return []string{at + ": " + err.Error()}
+}
+
+func isSchemaError(err error) bool {
+	switch err.(type) {
+	case *openapi3.SchemaError, openapi3.MultiError:
+		return true
+	}
+	return false
+}
+
+func describe(reason string, err error) string {
+	switch {
+	case err == nil:
+		return reason
+	case reason == "" || reason == err.Error():
+		return err.Error()
+	}
+	return reason + ": " + err.Error()
+}
+
+// pointer renders a JSON pointer the way the value would be addressed in
+// code: .field for properties and [i] for array items.
+func pointer(path []string) string {
+	var b strings.Builder
+	for _, segment := range path {
+		if _, err := strconv.Atoi(segment); err == nil {
+			b.WriteString("[" + segment + "]")
+			continue
+		}
+		b.WriteString("." + segment)
+	}
+	return b.String()
+}
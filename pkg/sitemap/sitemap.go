@@ -0,0 +1,75 @@
+// Package sitemap writes sitemaps and sitemap indexes following the
+// sitemaps.org protocol.
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const (
+	// MaxURLs is the most URLs the protocol allows in a single sitemap.
+	MaxURLs = 50000
+
+	ContentType = "application/xml; charset=utf-8"
+
+	namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// URL is a page, or a sitemap when listed in an index. LastMod is left out
+// when zero.
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	NS      string     `xml:"xmlns,attr"`
+	URLs    []location `xml:"url"`
+}
+
+type index struct {
+	XMLName  xml.Name   `xml:"sitemapindex"`
+	NS       string     `xml:"xmlns,attr"`
+	Sitemaps []location `xml:"sitemap"`
+}
+
+type location struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+func WriteURLSet(w io.Writer, urls []URL) error {
+	return write(w, urlSet{NS: namespace, URLs: locations(urls)})
+}
+
+func WriteIndex(w io.Writer, sitemaps []URL) error {
+	return write(w, index{NS: namespace, Sitemaps: locations(sitemaps)})
+}
+
+func locations(urls []URL) []location {
+	locs := make([]location, len(urls))
+	for i, u := range urls {
+		locs[i].Loc = u.Loc
+		if !u.LastMod.IsZero() {
+			locs[i].LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+	}
+	return locs
+}
+
+func write(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,43 @@
+package sitemap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteURLSet(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteURLSet(&buf, []URL{
+		{Loc: "https://conduit.example/article/fish-&-chips", LastMod: time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("", 3600))},
+		{Loc: "https://conduit.example/profile/jake"},
+	}))
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://conduit.example/article/fish-&amp;-chips</loc>
+    <lastmod>2024-03-01T09:00:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://conduit.example/profile/jake</loc>
+  </url>
+</urlset>
+`, buf.String())
+}
+
+func TestWriteIndex(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteIndex(&buf, []URL{{Loc: "https://conduit.example/sitemaps/1.xml"}}))
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>https://conduit.example/sitemaps/1.xml</loc>
+  </sitemap>
+</sitemapindex>
+`, buf.String())
+}
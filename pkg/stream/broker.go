@@ -0,0 +1,168 @@
+// Package stream fans events out to Server-Sent Events clients.
+package stream
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Event struct {
+	ID   string
+	Type string
+	Data []byte
+}
+
+// Broker delivers the events published on a topic to its subscribers.
+//
+// Memory serves a single process. A broker shared by several instances, for
+// example over Postgres LISTEN/NOTIFY, must give events IDs that every
+// instance understands, so a client can resume on any of them.
+type Broker interface {
+	Publish(ctx context.Context, topic, eventType string, data []byte) error
+	// Subscribe first delivers the buffered events published after
+	// lastEventID, then every new one, until ctx is done. The channel is
+	// closed when ctx is done or when the subscriber falls too far behind;
+	// clients are expected to reconnect with the ID of the last event they
+	// got.
+	Subscribe(ctx context.Context, topic, lastEventID string) (<-chan Event, error)
+}
+
+const subscriberBuffer = 64
+
+// Memory keeps the last history events of each topic so clients can resume
+// after a short disconnection. IDs are "<epoch>-<sequence>": after a restart
+// the epoch changes and clients get the whole buffer again rather than
+// nothing.
+type Memory struct {
+	history   int
+	maxTopics int
+	epoch     string
+
+	mu     sync.Mutex
+	seq    uint64
+	topics map[string]*topic
+}
+
+type topic struct {
+	events      []Event
+	subscribers map[chan Event]struct{}
+	lastPublish time.Time
+}
+
+// NewMemory keeps history events for at most maxTopics topics; topics
+// nobody listens to are forgotten first.
+func NewMemory(history, maxTopics int) *Memory {
+	return &Memory{
+		history:   history,
+		maxTopics: maxTopics,
+		epoch:     strconv.FormatInt(time.Now().UnixNano(), 36),
+		topics:    map[string]*topic{},
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, name, eventType string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seq++
+	event := Event{ID: fmt.Sprintf("%s-%d", m.epoch, m.seq), Type: eventType, Data: data}
+
+	t := m.topic(name)
+	t.lastPublish = time.Now()
+	t.events = append(t.events, event)
+	if len(t.events) > m.history {
+		t.events = t.events[len(t.events)-m.history:]
+	}
+
+	for ch := range t.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, name, lastEventID string) (<-chan Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.topic(name)
+	replay := m.since(t.events, lastEventID)
+
+	ch := make(chan Event, len(replay)+subscriberBuffer)
+	for _, event := range replay {
+		ch <- event
+	}
+	t.subscribers[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := t.subscribers[ch]; ok {
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}()
+
+	return ch, nil
+}
+
+// topic returns the topic called name, creating it and making room for it
+// if needed. m.mu must be held.
+func (m *Memory) topic(name string) *topic {
+	if t, ok := m.topics[name]; ok {
+		return t
+	}
+
+	if len(m.topics) >= m.maxTopics {
+		var oldest string
+		for n, t := range m.topics {
+			if len(t.subscribers) > 0 {
+				continue
+			}
+			if oldest == "" || t.lastPublish.Before(m.topics[oldest].lastPublish) {
+				oldest = n
+			}
+		}
+		if oldest != "" {
+			delete(m.topics, oldest)
+		}
+	}
+
+	t := &topic{subscribers: map[chan Event]struct{}{}}
+	m.topics[name] = t
+	return t
+}
+
+// since returns the events published after lastEventID: none when it is
+// empty, all of them when it comes from another epoch.
+func (m *Memory) since(events []Event, lastEventID string) []Event {
+	if lastEventID == "" {
+		return nil
+	}
+
+	epoch, seq, ok := strings.Cut(lastEventID, "-")
+	last, err := strconv.ParseUint(seq, 10, 64)
+	if !ok || err != nil || epoch != m.epoch {
+		return events
+	}
+
+	for i, event := range events {
+		_, s, _ := strings.Cut(event.ID, "-")
+		if n, _ := strconv.ParseUint(s, 10, 64); n > last {
+			return events[i:]
+		}
+	}
+	return nil
+}
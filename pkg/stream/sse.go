@@ -0,0 +1,67 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// retryMs is how long browsers wait before reconnecting a closed stream.
+const retryMs = 3000
+
+// Serve streams the events of topic to the client as Server-Sent Events,
+// resuming after the Last-Event-ID header, and writes a comment every
+// heartbeat so proxies keep the connection open. It returns when the client
+// goes away or the broker drops the subscription.
+func Serve(w http.ResponseWriter, r *http.Request, broker Broker, topic string, heartbeat time.Duration) {
+	rc := http.NewResponseController(w)
+
+	events, err := broker.Subscribe(r.Context(), topic, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", retryMs)
+	if err := rc.Flush(); err != nil {
+		slog.WarnContext(r.Context(), "streaming not supported", slog.String("error", err.Error()))
+		return
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+		case <-ticker.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event Event) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\n", event.ID, event.Type)
+	for _, line := range bytes.Split(event.Data, []byte("\n")) {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
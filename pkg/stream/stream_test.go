@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, events <-chan Event, n int) []Event {
+	t.Helper()
+
+	var got []Event
+	for len(got) < n {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "channel closed after %d events", len(got))
+			got = append(got, event)
+		case <-time.After(time.Second):
+			t.Fatalf("got %d events, want %d", len(got), n)
+		}
+	}
+	return got
+}
+
+func TestMemory_PublishSubscribe(t *testing.T) {
+	b := NewMemory(10, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := b.Subscribe(ctx, "article:1", "")
+	require.NoError(t, err)
+
+	b.Publish(ctx, "article:2", "comment-created", []byte(`{"id":"other"}`))
+	b.Publish(ctx, "article:1", "comment-created", []byte(`{"id":"a"}`))
+
+	got := receive(t, events, 1)
+	assert.Equal(t, "comment-created", got[0].Type)
+	assert.Equal(t, `{"id":"a"}`, string(got[0].Data))
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, ok := <-events
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMemory_Resume(t *testing.T) {
+	b := NewMemory(3, 10)
+	ctx := context.Background()
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		b.Publish(ctx, "article:1", "comment-created", []byte(id))
+	}
+
+	events, _ := b.Subscribe(ctx, "article:1", "")
+	assert.Empty(t, events, "no replay without Last-Event-ID")
+
+	all, _ := b.Subscribe(ctx, "article:1", "restarted-1")
+	got := receive(t, all, 3)
+	assert.Equal(t, "2", string(got[0].Data), "only the last 3 events are kept")
+
+	resumed, _ := b.Subscribe(ctx, "article:1", got[1].ID)
+	got = receive(t, resumed, 1)
+	assert.Equal(t, "4", string(got[0].Data))
+	assert.Empty(t, resumed)
+}
+
+func TestMemory_DropsSlowSubscribers(t *testing.T) {
+	b := NewMemory(1, 10)
+	ctx := context.Background()
+
+	events, _ := b.Subscribe(ctx, "article:1", "")
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(ctx, "article:1", "comment-created", nil)
+	}
+
+	receive(t, events, subscriberBuffer)
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestMemory_EvictsIdleTopics(t *testing.T) {
+	b := NewMemory(10, 2)
+	ctx := context.Background()
+
+	b.Subscribe(ctx, "busy", "")
+	b.Publish(ctx, "old", "comment-created", nil)
+	b.Publish(ctx, "new", "comment-created", nil)
+
+	assert.Len(t, b.topics, 2)
+	assert.Contains(t, b.topics, "busy")
+	assert.Contains(t, b.topics, "new")
+}
+
+func TestServe(t *testing.T) {
+	b := NewMemory(10, 10)
+	b.Publish(context.Background(), "article:1", "comment-deleted", []byte(`{"id":"old"}`))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Serve(w, r, b, "article:1", 20*time.Millisecond)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "restarted-1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(time.Second):
+			t.Fatal("stream stalled")
+			return ""
+		}
+	}
+
+	assert.Equal(t, "retry: 3000", next())
+	assert.Equal(t, "", next())
+	assert.True(t, strings.HasPrefix(next(), "id: "))
+	assert.Equal(t, "event: comment-deleted", next())
+	assert.Equal(t, `data: {"id":"old"}`, next())
+	assert.Equal(t, "", next())
+
+	for line := next(); line != ": heartbeat"; line = next() {
+	}
+
+	b.Publish(context.Background(), "article:1", "comment-created", []byte("{\n}"))
+	for line := next(); line != "event: comment-created"; line = next() {
+	}
+	assert.Equal(t, "data: {", next())
+	assert.Equal(t, "data: }", next())
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenDB opens a database whose statements are recorded as client spans by
+// otelsql. Only statements run under an existing span are traced, so startup
+// migrations and pool maintenance do not start traces of their own.
+func OpenDB(driverName, dsn string, system attribute.KeyValue) (*sql.DB, error) {
+	return otelsql.Open(driverName, dsn,
+		otelsql.WithAttributes(system),
+		otelsql.WithSpanNameFormatter(statementName),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitConnectorConnect: true,
+			OmitRows:             true,
+			SpanFilter:           underSpan,
+		}),
+	)
+}
+
+func underSpan(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// statementName names a span after the SQL verb of its statement, e.g.
+// SELECT, falling back to the driver method for prepares and transactions.
+func statementName(_ context.Context, method otelsql.Method, query string) string {
+	if method == otelsql.MethodConnPrepare {
+		return "PREPARE"
+	}
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return string(method)
+}
@@ -0,0 +1,119 @@
+// Package tracing configures the OpenTelemetry SDK for the server and wraps
+// the bits of it the rest of the code uses: spans around application code,
+// the HTTP middleware and the traced database handle. Trace context is
+// propagated with the W3C traceparent and tracestate headers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the application's own spans.
+const instrumentationName = "github.com/sallescosta/conduit-api"
+
+// Config selects where spans go. Exporter is otlp, stdout or file; tracing
+// is off when it is empty or "none".
+type Config struct {
+	Exporter    string
+	Endpoint    string
+	File        string
+	ServiceName string
+	SampleRatio float64
+}
+
+// Setup builds the tracer provider selected by config and installs it, with
+// the W3C propagator, as the global one. It returns nil when tracing is off;
+// otherwise the caller must Shutdown the provider to flush queued spans.
+func Setup(ctx context.Context, config Config) (*sdktrace.TracerProvider, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch config.Exporter {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpointURL(strings.TrimRight(config.Endpoint, "/")+"/v1/traces"))
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		var f io.Writer
+		f, err = os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err == nil {
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		}
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+		resource.WithAttributes(semconv.ServiceName(config.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(sdktrace.NewBatchSpanProcessor(exporter), res, config.SampleRatio)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	return provider, nil
+}
+
+// NewProvider returns a tracer provider keeping a fraction ratio of root
+// traces. Child spans follow the decision of their parent, so every service
+// in a trace agrees on it.
+func NewProvider(processor sdktrace.SpanProcessor, res *resource.Resource, ratio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+}
+
+// Start begins an internal span under the current span of ctx using the
+// global tracer provider. It is a no-op span when tracing is off.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// SetError records err on span and marks it as failed. A nil err does
+// nothing.
+func SetError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Observe has the shape of a repository observer: it wraps each call in a
+// span named after the method and records its error.
+func Observe(ctx context.Context, method string) (context.Context, func(error)) {
+	ctx, span := Start(ctx, method)
+	return ctx, func(err error) {
+		SetError(span, err)
+		span.End()
+	}
+}
@@ -0,0 +1,208 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// newRecorder installs a global provider sampling ratio of root traces that
+// keeps every ended span in memory.
+func newRecorder(t *testing.T, ratio float64) *tracetest.SpanRecorder {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(NewProvider(rec, resource.Empty(), ratio))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+	return rec
+}
+
+func byName(rec *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, s := range rec.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestStart_ChildSpans(t *testing.T) {
+	rec := newRecorder(t, 1)
+
+	ctx, root := Start(context.Background(), "root")
+	_, done := Observe(ctx, "ArticleDB.GetArticleBySlug")
+	done(errors.New("not found"))
+	root.End()
+
+	require.Len(t, rec.Ended(), 2)
+
+	child := byName(rec, "ArticleDB.GetArticleBySlug")
+	require.NotNil(t, child)
+	parent := byName(rec, "root")
+	require.NotNil(t, parent)
+
+	assert.Equal(t, parent.SpanContext().TraceID(), child.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.False(t, parent.Parent().IsValid())
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "not found"}, child.Status())
+	assert.Equal(t, trace.SpanKindInternal, child.SpanKind())
+}
+
+func TestStart_Disabled(t *testing.T) {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	_, span := Start(context.Background(), "noop")
+	assert.False(t, span.SpanContext().IsValid())
+
+	span.SetAttributes(attribute.String("k", "v"))
+	SetError(span, errors.New("ignored"))
+	span.End()
+}
+
+func TestSampling(t *testing.T) {
+	rec := newRecorder(t, 0)
+
+	_, span := Start(context.Background(), "dropped")
+	span.End()
+
+	traceID := trace.TraceID{1}
+	sampledParent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled, Remote: true,
+	})
+	_, span = Start(trace.ContextWithRemoteSpanContext(context.Background(), sampledParent), "kept")
+	span.End()
+
+	require.Len(t, rec.Ended(), 1)
+	assert.Equal(t, "kept", rec.Ended()[0].Name())
+	assert.Equal(t, traceID, rec.Ended()[0].SpanContext().TraceID())
+}
+
+func TestSetup(t *testing.T) {
+	provider, err := Setup(context.Background(), Config{Exporter: "none"})
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+
+	_, err = Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.EqualError(t, err, `unknown traces exporter "zipkin"`)
+
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	provider, err = Setup(context.Background(), Config{Exporter: "file", File: file, ServiceName: "conduit-api", SampleRatio: 1})
+	require.NoError(t, err)
+
+	_, span := Start(context.Background(), "work")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"work"`)
+	assert.Contains(t, string(data), `"Value":"conduit-api"`)
+}
+
+func TestMiddleware_NamesSpanByRoute(t *testing.T) {
+	rec := newRecorder(t, 1)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/api/articles/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "work")
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/articles/hello", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	server := byName(rec, "GET /api/articles/{slug}")
+	require.NotNil(t, server)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, server.Status().Code)
+	assert.Contains(t, server.Attributes(), semconv.HTTPRoute("/api/articles/{slug}"))
+	assert.Contains(t, server.Attributes(), semconv.HTTPResponseStatusCode(500))
+
+	work := byName(rec, "work")
+	require.NotNil(t, work)
+	assert.Equal(t, server.SpanContext().SpanID(), work.Parent().SpanID())
+}
+
+func TestOpenDB_StatementSpans(t *testing.T) {
+	sql.Register("tracing-fake", fakeDriver{})
+	rec := newRecorder(t, 1)
+
+	db, err := OpenDB("tracing-fake", "", semconv.DBSystemNamePostgreSQL)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(context.Background(), "CREATE TABLE untraced (id INT)")
+	require.NoError(t, err)
+
+	ctx, root := Start(context.Background(), "request")
+	_, err = db.ExecContext(ctx, "update articles\n  set title = $1", "x")
+	require.NoError(t, err)
+	rows, err := db.QueryContext(ctx, "SELECT id FROM articles")
+	require.NoError(t, err)
+	rows.Close()
+	root.End()
+
+	var names []string
+	for _, s := range rec.Ended() {
+		names = append(names, s.Name())
+	}
+	assert.ElementsMatch(t, []string{"PREPARE", "UPDATE", "PREPARE", "SELECT", "request"}, names)
+
+	update := byName(rec, "UPDATE")
+	assert.Equal(t, trace.SpanKindClient, update.SpanKind())
+	assert.Equal(t, root.SpanContext().SpanID(), update.Parent().SpanID())
+	assert.Contains(t, update.Attributes(), semconv.DBSystemNamePostgreSQL)
+}
+
+// fakeDriver accepts every statement and returns no rows.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("unsupported") }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                                    { return nil }
+func (fakeStmt) NumInput() int                                   { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error)  { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+const maxReceivedBody = 1 << 20
+
+// Receiver is a webhook endpoint for local testing: it checks the signature
+// of every request against secret, answers 401 when it does not match and
+// otherwise prints the event and its indented payload to out.
+func Receiver(secret string, out io.Writer) http.Handler {
+	var mu sync.Mutex
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxReceivedBody))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if !Verify(secret, body, r.Header.Get(HeaderSignature)) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err != nil {
+			pretty.Reset()
+			pretty.Write(body)
+		}
+
+		mu.Lock()
+		fmt.Fprintf(out, "%s delivery=%s\n%s\n\n", r.Header.Get(HeaderEvent), r.Header.Get(HeaderDelivery), pretty.Bytes())
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,120 @@
+// Package webhook signs, sends and verifies webhook requests.
+//
+// Every request is a JSON POST whose body is signed with HMAC-SHA256 using
+// the secret of the webhook. The signature is sent hex encoded, prefixed
+// with "sha256=", in the X-Conduit-Signature-256 header.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Conduit-Event"
+	HeaderDelivery  = "X-Conduit-Delivery"
+	HeaderSignature = "X-Conduit-Signature-256"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the signature of body with secret, as sent in HeaderSignature.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify tells whether signature is the signature of body with secret.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// ErrPrivateAddress is returned when a webhook URL resolves to an address
+// of the local network and the Sender does not allow them.
+var ErrPrivateAddress = errors.New("webhook url resolves to a private address")
+
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender sends requests that time out after timeout. Unless allowPrivate
+// is set, it refuses to connect to loopback, private and link-local
+// addresses, so webhooks cannot be used to reach internal services.
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &Sender{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// Send posts req and returns the response status. Redirects are not
+// followed; any status outside 2xx is returned with an error.
+func (s *Sender) Send(ctx context.Context, req Request) (int, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "Conduit-Webhook/1.0")
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, req.DeliveryID)
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, req.Body))
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
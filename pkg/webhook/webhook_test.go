@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"article.published"}`)
+
+	// echo -n '{"event":"article.published"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=4b8530b4f3bf86212260afc235018d1f0e37d9110ce70221c47366c37d1a6ae4", Sign("secret", body))
+
+	assert.True(t, Verify("secret", body, Sign("secret", body)))
+	assert.False(t, Verify("other", body, Sign("secret", body)))
+	assert.False(t, Verify("secret", []byte(`{}`), Sign("secret", body)))
+	assert.False(t, Verify("secret", body, ""))
+}
+
+func TestSender_SendToReceiver(t *testing.T) {
+	var out bytes.Buffer
+	srv := httptest.NewServer(Receiver("secret", &out))
+	defer srv.Close()
+
+	sender := NewSender(time.Second, true)
+	status, err := sender.Send(context.Background(), Request{
+		URL: srv.URL, Secret: "secret", Event: "comment.created", DeliveryID: "d1", Body: []byte(`{"a":1}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Equal(t, "comment.created delivery=d1\n{\n  \"a\": 1\n}\n\n", out.String())
+
+	status, err = sender.Send(context.Background(), Request{URL: srv.URL, Secret: "wrong", Body: []byte(`{}`)})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestSender_RefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := NewSender(time.Second, false).Send(context.Background(), Request{URL: srv.URL, Body: []byte(`{}`)})
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
+
+func TestSender_DoesNotFollowRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.RedirectHandler("http://169.254.169.254/", http.StatusFound))
+	defer srv.Close()
+
+	status, err := NewSender(time.Second, true).Send(context.Background(), Request{URL: srv.URL, Body: []byte(`{}`)})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusFound, status)
+}